@@ -2,7 +2,6 @@ module github.com/sumup-oss/go-pkgs
 
 require (
 	github.com/elliotchance/orderedmap v1.2.0
-	github.com/golang/protobuf v1.3.2 // indirect
 	github.com/hashicorp/go-syslog v1.0.0
 	github.com/hashicorp/vault/api v1.0.1
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
@@ -10,6 +9,7 @@ require (
 	github.com/mattes/go-expand-tilde v0.0.0-20150330173918-cb884138e64c
 	github.com/mitchellh/go-homedir v1.1.0 // indirect
 	github.com/palantir/stacktrace v0.0.0-20161112013806-78658fd2d177
+	github.com/prometheus/client_golang v1.7.1
 	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/cobra v0.0.3
 	github.com/spf13/pflag v1.0.3 // indirect
@@ -19,7 +19,6 @@ require (
 	github.com/sumup-oss/go-pkgs/errors v0.0.0-20210806131309-dd59fc2fd123
 	go.uber.org/zap v1.19.0
 	golang.org/x/crypto v0.0.0-20190510104115-cbcb75029529
-	golang.org/x/sys v0.0.0-20200122134326-e047566fdf82 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 )
 
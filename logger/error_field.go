@@ -23,7 +23,7 @@ import (
 	"github.com/sumup-oss/go-pkgs/errors"
 )
 
-// ErrorField creates a zap.Field for the corresponding error.
+// ErrorField creates a Field for the corresponding error.
 //
 // By default it creates zapcore.ErrorType field the same way zap.Error() does it.
 //
@@ -31,10 +31,11 @@ import (
 // it will add a `trace` field in the log with the error stack trace.
 //
 // The Location interface looks like this:
-//   interface {
-//		Location() (function, file string, line int)
-//	}
-func ErrorField(err error) zap.Field {
+//
+//	  interface {
+//			Location() (function, file string, line int)
+//		}
+func ErrorField(err error) Field {
 	if err == nil {
 		return zap.Skip()
 	}
@@ -0,0 +1,94 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Field is a structured context of a log entry, see StructuredLogger.
+//
+// Create the fields with the constructors of this package, so the callers don't depend on the logging backend.
+// NOTE: Field is an alias of zap.Field, so the fields created with zap are accepted as well.
+type Field = zap.Field
+
+// String creates a Field with a string value.
+func String(key, value string) Field {
+	return zap.String(key, value)
+}
+
+// Bytes creates a Field with an UTF-8 encoded text value, e.g. a message body.
+func Bytes(key string, value []byte) Field {
+	return zap.ByteString(key, value)
+}
+
+// Bool creates a Field with a bool value.
+func Bool(key string, value bool) Field {
+	return zap.Bool(key, value)
+}
+
+// Int creates a Field with an int value.
+func Int(key string, value int) Field {
+	return zap.Int(key, value)
+}
+
+// Int64 creates a Field with an int64 value.
+func Int64(key string, value int64) Field {
+	return zap.Int64(key, value)
+}
+
+// Uint64 creates a Field with an uint64 value.
+func Uint64(key string, value uint64) Field {
+	return zap.Uint64(key, value)
+}
+
+// Float64 creates a Field with a float64 value.
+func Float64(key string, value float64) Field {
+	return zap.Float64(key, value)
+}
+
+// Duration creates a Field with a time.Duration value.
+func Duration(key string, value time.Duration) Field {
+	return zap.Duration(key, value)
+}
+
+// Time creates a Field with a time.Time value.
+func Time(key string, value time.Time) Field {
+	return zap.Time(key, value)
+}
+
+// Any creates a Field with an arbitrary value, choosing the best way to represent it.
+func Any(key string, value interface{}) Field {
+	return zap.Any(key, value)
+}
+
+// NamedError creates a Field with an error under the key, see ErrorField() for the "error" key.
+//
+// A nil error creates a Skip() field.
+func NamedError(key string, err error) Field {
+	return zap.NamedError(key, err)
+}
+
+// Stack creates a Field with the stack trace of the current goroutine.
+func Stack(key string) Field {
+	return zap.Stack(key)
+}
+
+// Skip creates a Field which is ignored, e.g. for the optional fields.
+func Skip() Field {
+	return zap.Skip()
+}
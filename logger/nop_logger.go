@@ -0,0 +1,46 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logger
+
+import "go.uber.org/zap/zapcore"
+
+// Ensure that NopLogger implements the StructuredLogger interface.
+var _ StructuredLogger = (*NopLogger)(nil)
+
+// NopLogger is a StructuredLogger which discards everything, e.g. for tests or libraries
+// which must be silenced.
+//
+// Unlike StructuredNopLogger, its Panic and Fatal methods do not panic or exit either.
+type NopLogger struct{}
+
+// NewNop returns a NopLogger.
+func NewNop() *NopLogger {
+	return &NopLogger{}
+}
+
+func (l *NopLogger) Panic(msg string, fields ...Field) {}
+func (l *NopLogger) Fatal(msg string, fields ...Field) {}
+func (l *NopLogger) Error(msg string, fields ...Field) {}
+func (l *NopLogger) Info(msg string, fields ...Field)  {}
+func (l *NopLogger) Warn(msg string, fields ...Field)  {}
+func (l *NopLogger) Debug(msg string, fields ...Field) {}
+
+// With returns the logger itself, since it discards the fields anyway.
+func (l *NopLogger) With(fields ...Field) StructuredLogger {
+	return l
+}
+
+func (l *NopLogger) GetLevel() zapcore.Level { return zapcore.InfoLevel }
+func (l *NopLogger) Sync() error             { return nil }
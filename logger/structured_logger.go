@@ -20,16 +20,16 @@ import (
 )
 
 type StructuredLogger interface {
-	Panic(msg string, fields ...zap.Field)
-	Fatal(msg string, fields ...zap.Field)
-	Error(msg string, fields ...zap.Field)
-	Info(msg string, fields ...zap.Field)
-	Warn(msg string, fields ...zap.Field)
-	Debug(msg string, fields ...zap.Field)
+	Panic(msg string, fields ...Field)
+	Fatal(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Debug(msg string, fields ...Field)
 
 	// With creates a child logger and adds structured context to it. Fields added
 	// to the child don't affect the parent, and vice versa.
-	With(fields ...zap.Field) StructuredLogger
+	With(fields ...Field) StructuredLogger
 
 	GetLevel() zapcore.Level
 	Sync() error
@@ -65,7 +65,7 @@ func (z *StructuredNopLogger) GetLevel() zapcore.Level {
 
 // With creates a child logger and adds structured context to it. Fields added
 // to the child don't affect the parent, and vice versa.
-func (z *StructuredNopLogger) With(fields ...zap.Field) StructuredLogger {
+func (z *StructuredNopLogger) With(fields ...Field) StructuredLogger {
 	return &StructuredNopLogger{
 		Logger: z.Logger.With(fields...),
 		level:  z.level,
@@ -0,0 +1,138 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// WithAsyncAck makes the consumer acknowledge the processed deliveries from a dedicated goroutine,
+// so the handler does not wait for the round trip of the acknowledgements to the broker.
+//
+// The acknowledgements are queued to the acker goroutine, which performs them in order,
+// on their own or in batches of multiple acks as configured with WithBatchAck().
+// The queue holds up to queueSize acknowledgements, when it is full the processing of the next delivery
+// waits until the acker catches up. The queued acknowledgements are flushed when the consumer stops,
+// before the channel is closed. When the channel closes with queued acknowledgements,
+// the broker redelivers their deliveries.
+// The audit records and the processing reports of the deliveries are recorded by the acker goroutine,
+// once their acknowledgement is performed. When an acknowledgement fails and the handler must stop
+// on its error, the consumer stops processing the next delivery.
+// A queueSize <= 0 acknowledges the deliveries synchronously, which is the default.
+func WithAsyncAck(queueSize int) ConsumerOption {
+	return func(c *Consumer) {
+		c.asyncAck.queueSize = queueSize
+	}
+}
+
+// asyncAcks is the queue of the acker goroutine, see WithAsyncAck().
+type asyncAcks struct {
+	queueSize int
+
+	// mu protects the queue from being closed while the acknowledgements are queued
+	mu      sync.RWMutex
+	queue   chan queuedAck
+	stopped chan struct{}
+
+	errMu sync.Mutex
+	// err is the first acknowledgement error the consumer must stop on
+	err error
+}
+
+// queuedAck is an acknowledgement queued to the acker goroutine.
+type queuedAck struct {
+	// d is copied, since e.g. the batches of WithBatch() reuse the deliveries
+	d               amqp.Delivery
+	acknowledgement HandlerAcknowledgement
+	handlerDuration time.Duration
+}
+
+// startAsyncAcks starts the acker goroutine of the current channel, if configured.
+func (c *Consumer) startAsyncAcks() {
+	if c.asyncAck.queueSize <= 0 || c.handler.QueueAutoAck() {
+		return
+	}
+
+	queue := make(chan queuedAck, c.asyncAck.queueSize)
+	stopped := make(chan struct{})
+
+	c.asyncAck.mu.Lock()
+	c.asyncAck.queue = queue
+	c.asyncAck.stopped = stopped
+	c.asyncAck.mu.Unlock()
+
+	c.asyncAck.errMu.Lock()
+	c.asyncAck.err = nil
+	c.asyncAck.errMu.Unlock()
+
+	go func() {
+		defer close(stopped)
+
+		for ack := range queue {
+			ack := ack
+
+			err := c.sendAcknowledgement(&ack.d, ack.acknowledgement, ack.handlerDuration)
+			if err != nil {
+				c.asyncAck.errMu.Lock()
+				if c.asyncAck.err == nil {
+					c.asyncAck.err = err
+				}
+				c.asyncAck.errMu.Unlock()
+			}
+		}
+	}()
+}
+
+// stopAsyncAcks performs the queued acknowledgements and stops the acker goroutine, if it is running.
+func (c *Consumer) stopAsyncAcks() {
+	c.asyncAck.mu.Lock()
+	queue, stopped := c.asyncAck.queue, c.asyncAck.stopped
+	c.asyncAck.queue = nil
+	c.asyncAck.mu.Unlock()
+
+	if queue == nil {
+		return
+	}
+
+	close(queue)
+	<-stopped
+}
+
+// queueAcknowledgement queues the acknowledgement of the delivery to the acker goroutine.
+// Returns false if the acker is not running, so the delivery must be acknowledged synchronously,
+// and the error of a previous acknowledgement the consumer must stop on, if any.
+func (c *Consumer) queueAcknowledgement(
+	d *amqp.Delivery,
+	acknowledgement HandlerAcknowledgement,
+	handlerDuration time.Duration,
+) (bool, error) {
+	c.asyncAck.mu.RLock()
+	defer c.asyncAck.mu.RUnlock()
+
+	if c.asyncAck.queue == nil {
+		return false, nil
+	}
+
+	// NOTE: The acker does not take mu, so it keeps draining the queue while it is full.
+	c.asyncAck.queue <- queuedAck{d: *d, acknowledgement: acknowledgement, handlerDuration: handlerDuration}
+
+	c.asyncAck.errMu.Lock()
+	defer c.asyncAck.errMu.Unlock()
+
+	return true, c.asyncAck.err
+}
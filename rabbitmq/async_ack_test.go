@@ -0,0 +1,112 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingAcknowledger blocks the acks until released.
+type blockingAcknowledger struct {
+	*fakeAcknowledger
+	release chan struct{}
+}
+
+func (a *blockingAcknowledger) Ack(tag uint64, multiple bool) error {
+	<-a.release
+
+	return a.fakeAcknowledger.Ack(tag, multiple)
+}
+
+func TestWithAsyncAck(t *testing.T) {
+	t.Run("the handler proceeds while the acks are pending, and they are flushed on stop", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(3)
+		ack := &blockingAcknowledger{fakeAcknowledger: &fakeAcknowledger{}, release: make(chan struct{})}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		processed := make(chan struct{}, 3)
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			processed <- struct{}{}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithAsyncAck(3))
+
+		for tag := uint64(1); tag <= 3; tag++ {
+			channel.deliver(ack, tag, "foo")
+		}
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		// NOTE: The acker is blocked on the first ack, while the handler processes all the deliveries.
+		for i := 0; i < 3; i++ {
+			<-processed
+		}
+		assert.Empty(t, ack.Acks())
+
+		cancel()
+		close(ack.release)
+		assert.Error(t, <-runErr)
+		assert.Equal(t, []uint64{1, 2, 3}, ack.Acks())
+		assert.Equal(t, uint64(3), consumer.Stats().Acked)
+		assert.True(t, channel.IsClosed())
+	})
+
+	t.Run("with WithBatchAck, the acker acks the batches with multiple=true", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(5)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		processed := make(chan struct{}, 5)
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			processed <- struct{}{}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithAsyncAck(10), WithBatchAck(3, 0))
+
+		for tag := uint64(1); tag <= 5; tag++ {
+			channel.deliver(ack, tag, "foo")
+		}
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		for i := 0; i < 5; i++ {
+			<-processed
+		}
+
+		cancel()
+		assert.Error(t, <-runErr)
+		assert.Equal(t, []uint64{3, 5}, ack.MultipleAcks())
+		assert.Equal(t, uint64(5), consumer.Stats().Acked)
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// AuditLogMessage is the message of the audit log records, see WithAuditLog().
+const AuditLogMessage = "RMQ message acknowledgement audit"
+
+// WithAuditLog logs an audit record of the acknowledgement decision of every delivery to auditLogger at level.
+//
+// The audit records are separate from the debug logging of the consumer, and contain the fields:
+//
+//	message_id - the message ID of the delivery
+//	routing_key - the routing key of the delivery
+//	processing_id - the ID of the attempt to process the delivery, see ProcessingIDFromContext()
+//	decision - "ack", "nack" or "reject"
+//	requeue - whether the delivery is requeued
+//	handler_duration - how long the handler took to process the delivery
+//	ack_error - the error of acknowledging the delivery to RMQ, only when it failed
+//
+// The records are logged once the delivery is acknowledged to RMQ, so they contain its outcome.
+// The decision is the final one, e.g. the retry ladder acks the deliveries it re-publishes.
+// The deliveries consumed with auto-ack are audited as acked.
+func WithAuditLog(auditLogger logger.StructuredLogger, level zapcore.Level) ConsumerOption {
+	return func(c *Consumer) {
+		c.auditLogger = auditLogger
+		c.auditLevel = level
+	}
+}
+
+func (c *Consumer) audit(
+	d *amqp.Delivery,
+	acknowledgement HandlerAcknowledgement,
+	handlerDuration time.Duration,
+	ackErr error,
+) {
+	if c.auditLogger == nil {
+		return
+	}
+
+	fields := []logger.Field{
+		logger.String("message_id", d.MessageId),
+		logger.String("routing_key", d.RoutingKey),
+		logger.String("decision", acknowledgement.Acknowledgement.String()),
+		logger.Bool("requeue", acknowledgement.Requeue),
+		logger.Duration("handler_duration", handlerDuration),
+		tracingField(d.CorrelationId),
+		c.processingIDField(d),
+	}
+	if ackErr != nil {
+		fields = append(fields, logger.NamedError("ack_error", ackErr))
+	}
+
+	switch c.auditLevel {
+	case zapcore.DebugLevel:
+		c.auditLogger.Debug(AuditLogMessage, fields...)
+	case zapcore.WarnLevel:
+		c.auditLogger.Warn(AuditLogMessage, fields...)
+	case zapcore.ErrorLevel:
+		c.auditLogger.Error(AuditLogMessage, fields...)
+	default:
+		c.auditLogger.Info(AuditLogMessage, fields...)
+	}
+}
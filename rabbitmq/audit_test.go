@@ -0,0 +1,159 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// capturingLogger is a StructuredLogger recording the logged entries.
+type capturingLogger struct {
+	*zap.Logger
+	logs *observer.ObservedLogs
+}
+
+func newCapturingLogger() *capturingLogger {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	return &capturingLogger{
+		Logger: zap.New(core),
+		logs:   logs,
+	}
+}
+
+func (l *capturingLogger) With(fields ...zap.Field) logger.StructuredLogger {
+	return &capturingLogger{
+		Logger: l.Logger.With(fields...),
+		logs:   l.logs,
+	}
+}
+
+func (l *capturingLogger) GetLevel() zapcore.Level {
+	return zapcore.DebugLevel
+}
+
+func TestWithAuditLog(t *testing.T) {
+	t.Run("it logs one record per delivery with the acknowledgement decision", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+		auditLogger := newCapturingLogger()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			switch string(msg.Body) {
+			case "nack":
+				return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+			case "reject":
+				cancel()
+
+				return HandlerAcknowledgement{Acknowledgement: Reject}, nil
+			default:
+				return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+			}
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithAuditLog(auditLogger, zapcore.WarnLevel),
+		)
+
+		for i, body := range []string{"ack", "nack", "reject"} {
+			channel.deliveries <- amqp.Delivery{
+				Acknowledger: ack,
+				DeliveryTag:  uint64(i + 1),
+				MessageId:    "id-" + body,
+				RoutingKey:   "key." + body,
+				Body:         []byte(body),
+			}
+		}
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		records := auditLogger.logs.FilterMessage(AuditLogMessage).All()
+		require.Len(t, records, 3)
+
+		expected := []struct {
+			decision string
+			requeue  bool
+		}{
+			{decision: "ack"},
+			{decision: "nack", requeue: true},
+			{decision: "reject"},
+		}
+		for i, record := range records {
+			fields := record.ContextMap()
+
+			assert.Equal(t, zapcore.WarnLevel, record.Level)
+			assert.Equal(t, "id-"+expected[i].decision, fields["message_id"])
+			assert.Equal(t, "key."+expected[i].decision, fields["routing_key"])
+			assert.Equal(t, expected[i].decision, fields["decision"])
+			assert.Equal(t, expected[i].requeue, fields["requeue"])
+			assert.Contains(t, fields, "handler_duration")
+			assert.NotContains(t, fields, "ack_error")
+		}
+	})
+
+	t.Run("it logs the error of the failed acknowledgement", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{err: amqp.ErrClosed}
+		auditLogger := newCapturingLogger()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithAuditLog(auditLogger, zapcore.InfoLevel),
+		)
+
+		channel.deliver(ack, 1, "ack")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		records := auditLogger.logs.FilterMessage(AuditLogMessage).All()
+		require.Len(t, records, 1)
+
+		fields := records[0].ContextMap()
+		assert.Equal(t, "ack", fields["decision"])
+		assert.Equal(t, amqp.ErrClosed.Error(), fields["ack_error"])
+	})
+}
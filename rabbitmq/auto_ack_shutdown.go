@@ -0,0 +1,167 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// AutoAckShutdownPolicy is what the consumer of an auto-ack queue does with the deliveries
+// which are received but not processed yet when it stops.
+//
+// The broker considers such deliveries acknowledged as soon as it sends them,
+// so they are lost unless they are processed before the consumer returns.
+type AutoAckShutdownPolicy int
+
+const (
+	// AutoAckDropOnShutdown drops the deliveries which are not processed, logging a warning with their count.
+	// It is the default.
+	AutoAckDropOnShutdown AutoAckShutdownPolicy = iota
+	// AutoAckProcessOnShutdown processes the deliveries which are already received before the consumer returns,
+	// bounded by the drain timeout, see WithDrainTimeout().
+	AutoAckProcessOnShutdown
+)
+
+// WithAutoAckShutdownPolicy sets what the consumer does with the received deliveries which are not processed
+// when it stops, see Handler.QueueAutoAck().
+//
+// With AutoAckProcessOnShutdown the deliveries are passed to the handler with a new context,
+// since the one of the consumer is already canceled. The context is canceled once the drain timeout passes,
+// and the deliveries which are not processed by then are dropped.
+// The channel is closed, and the final checkpoint runs, only once the deliveries are processed,
+// see WithFinalCheckpoint().
+//
+// NOTE: The policy is not applied to the batches consumed with WithBatch().
+func WithAutoAckShutdownPolicy(policy AutoAckShutdownPolicy) ConsumerOption {
+	return func(c *Consumer) {
+		c.autoAckShutdownPolicy = policy
+	}
+}
+
+// drainsAutoAcked reports whether the consumer processes the received auto-acked deliveries when it stops.
+func (c *Consumer) drainsAutoAcked() bool {
+	return c.handler.QueueAutoAck() && c.autoAckShutdownPolicy == AutoAckProcessOnShutdown
+}
+
+// drainAutoAcked applies the auto-ack shutdown policy to the deliveries which are already received.
+func (c *Consumer) drainAutoAcked(deliveries <-chan amqp.Delivery) {
+	if !c.handler.QueueAutoAck() {
+		return
+	}
+
+	if c.autoAckShutdownPolicy != AutoAckProcessOnShutdown {
+		c.warnDroppedAutoAcked(countBuffered(deliveries))
+
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if c.drainTimeout > 0 {
+		timer := c.clock.NewTimer(c.drainTimeout)
+		defer timer.Stop()
+
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-timer.C():
+				cancel()
+			}
+		}()
+	}
+
+	processed := 0
+	for ctx.Err() == nil {
+		select {
+		case d, hasMore := <-deliveries:
+			if !hasMore {
+				c.logShutdownProcessed(processed)
+
+				return
+			}
+
+			err := c.handleSingleDelivery(ctx, &d)
+			if err != nil {
+				c.logger.Error(
+					"failed to process an auto-acked RMQ delivery while stopping",
+					logger.ErrorField(err),
+					tracingField(d.CorrelationId),
+				)
+				c.warnDroppedAutoAcked(countBuffered(deliveries))
+
+				return
+			}
+
+			processed++
+		default:
+			c.logShutdownProcessed(processed)
+
+			return
+		}
+	}
+
+	c.logger.Warn(
+		"RMQ consumer drain timeout exceeded while processing the auto-acked deliveries",
+		logger.String("queue", c.handler.GetQueueName()),
+		logger.Duration("drain_timeout", c.drainTimeout),
+	)
+	c.warnDroppedAutoAcked(countBuffered(deliveries))
+}
+
+func (c *Consumer) logShutdownProcessed(processed int) {
+	if processed == 0 {
+		return
+	}
+
+	c.logger.Info(
+		"RMQ consumer processed the auto-acked deliveries while stopping",
+		logger.String("queue", c.handler.GetQueueName()),
+		logger.Int("deliveries", processed),
+	)
+}
+
+func (c *Consumer) warnDroppedAutoAcked(dropped int) {
+	if dropped == 0 {
+		return
+	}
+
+	c.logger.Warn(
+		"RMQ consumer dropped the auto-acked deliveries which were not processed",
+		logger.String("queue", c.handler.GetQueueName()),
+		logger.Int("deliveries", dropped),
+	)
+}
+
+// countBuffered discards the deliveries which are already received, returning how many there were.
+func countBuffered(deliveries <-chan amqp.Delivery) int {
+	count := 0
+	for {
+		select {
+		case _, hasMore := <-deliveries:
+			if !hasMore {
+				return count
+			}
+
+			count++
+		default:
+			return count
+		}
+	}
+}
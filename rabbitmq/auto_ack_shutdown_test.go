@@ -0,0 +1,191 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAutoAckShutdownPolicy(t *testing.T) {
+	t.Run("it processes the buffered auto-acked deliveries before returning", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		var processed []string
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			mu.Lock()
+			processed = append(processed, string(msg.Body))
+			mu.Unlock()
+
+			if string(msg.Body) == "foo" {
+				cancel()
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		handler.autoAck = true
+
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithAutoAckShutdownPolicy(AutoAckProcessOnShutdown))
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+		channel.deliver(ack, 3, "baz")
+
+		err := consumer.Run(ctx)
+		assert.Error(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{"foo", "bar", "baz"}, processed)
+		assert.Empty(t, ack.Acks())
+	})
+
+	t.Run("it closes the channel and runs the final checkpoint once the auto-acked deliveries are processed", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		var processed []string
+		var closedWhileProcessing bool
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			if string(msg.Body) == "foo" {
+				cancel()
+			} else {
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			processed = append(processed, string(msg.Body))
+			closedWhileProcessing = closedWhileProcessing || channel.IsClosed()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		handler.autoAck = true
+
+		var checkpointed []string
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithAutoAckShutdownPolicy(AutoAckProcessOnShutdown),
+			WithFinalCheckpoint(func(ctx context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				checkpointed = append([]string(nil), processed...)
+
+				return nil
+			}),
+		)
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+		channel.deliver(ack, 3, "baz")
+
+		err := consumer.Run(ctx)
+		assert.Error(t, err)
+
+		<-channel.Closed()
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.False(t, closedWhileProcessing)
+		assert.Equal(t, []string{"foo", "bar", "baz"}, checkpointed)
+	})
+
+	t.Run("it stops processing the auto-acked deliveries once the drain timeout passes", func(t *testing.T) {
+		t.Parallel()
+
+		var processed []string
+		var blockedErr error
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			processed = append(processed, string(msg.Body))
+
+			if string(msg.Body) == "block" {
+				<-ctx.Done()
+				blockedErr = ctx.Err()
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		handler.autoAck = true
+
+		consumer, _ := newTestConsumer(
+			handler,
+			newFakeChannel(0),
+			ConsumerConfig{},
+			WithAutoAckShutdownPolicy(AutoAckProcessOnShutdown),
+			WithDrainTimeout(10*time.Millisecond),
+		)
+
+		deliveries := make(chan amqp.Delivery, 3)
+		deliveries <- amqp.Delivery{Body: []byte("foo")}
+		deliveries <- amqp.Delivery{Body: []byte("block")}
+		deliveries <- amqp.Delivery{Body: []byte("bar")}
+
+		consumer.drainAutoAcked(deliveries)
+
+		assert.Equal(t, []string{"foo", "block"}, processed)
+		assert.Equal(t, context.Canceled, blockedErr)
+		assert.Empty(t, deliveries)
+	})
+
+	t.Run("by default, it drops the buffered auto-acked deliveries", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			t.Error("the handler must not be called")
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		handler.autoAck = true
+
+		consumer, _ := newTestConsumer(handler, newFakeChannel(0), ConsumerConfig{})
+		capturingLog := newCapturingLogger()
+		consumer.logger = capturingLog
+
+		deliveries := make(chan amqp.Delivery, 2)
+		deliveries <- amqp.Delivery{Body: []byte("foo")}
+		deliveries <- amqp.Delivery{Body: []byte("bar")}
+
+		consumer.drainAutoAcked(deliveries)
+
+		assert.Empty(t, deliveries)
+
+		entries := capturingLog.logs.FilterMessage("RMQ consumer dropped the auto-acked deliveries which were not processed").All()
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, int64(2), entries[0].ContextMap()["deliveries"])
+		}
+	})
+}
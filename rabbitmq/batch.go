@@ -0,0 +1,196 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// BatchHandler is implemented by handlers which process the deliveries in batches,
+// e.g. to store them in a DB with a single bulk insert.
+//
+// ReceiveBatch must return one acknowledgement per delivery, in the same order as the deliveries.
+// The consumer acknowledges every delivery according to its own acknowledgement, so a batch can
+// partially fail by returning Nack or Reject only for the failed deliveries.
+// When ReceiveBatch returns an error, none of the deliveries is acknowledged and the consumer stops.
+type BatchHandler interface {
+	ReceiveBatch(ctx context.Context, deliveries []amqp.Delivery) ([]HandlerAcknowledgement, error)
+}
+
+// WithBatch makes the consumer pass the deliveries to the handler in batches.
+//
+// The consumer accumulates up to size deliveries, or the deliveries received within maxInterval
+// since the first delivery of the batch, whatever comes first, and passes them to the
+// BatchHandler.ReceiveBatch() method of the handler.
+// Make sure the PrefetchCount of the consumer is not lower than size, otherwise the batches
+// will be flushed only by maxInterval.
+//
+// NOTE: Delivery tags are valid only on the channel they come from, so when the channel closes
+// the pending batch is discarded without being acknowledged, and the broker redelivers
+// its deliveries on the next channel.
+//
+// The handler must implement the BatchHandler interface.
+func WithBatch(size int, maxInterval time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.batchSize = size
+		c.batchMaxInterval = maxInterval
+	}
+}
+
+func (c *Consumer) handleBatchDeliveries(
+	ctx context.Context,
+	deliveries <-chan amqp.Delivery,
+) error {
+	batch := make([]amqp.Delivery, 0, c.batchSize)
+
+	var flushTimer task.Timer
+	var flushCh <-chan time.Time
+
+	flush := func() error {
+		if flushTimer != nil {
+			flushTimer.Stop()
+			flushTimer = nil
+			flushCh = nil
+		}
+
+		inflight := c.startInflight()
+		if inflight == nil {
+			// NOTE: The consumer is stopping, so the broker redelivers the batch once the channel closes.
+			batch = batch[:0]
+
+			return ctx.Err()
+		}
+
+		err := c.handleBatch(ctx, batch)
+		inflight.done()
+
+		batch = batch[:0]
+
+		return err
+	}
+
+	idle := c.newIdleWatch()
+	defer idle.stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Warn("RMQ handler stopping")
+
+			if flushTimer != nil {
+				flushTimer.Stop()
+			}
+
+			return ctx.Err()
+		case <-idle.C():
+			idle.fired()
+			c.onIdle()
+		case <-flushCh:
+			err := flush()
+			if err != nil {
+				return stacktrace.Propagate(err, "failed to process RMQ deliveries batch")
+			}
+		case d, hasMore := <-deliveries:
+			if !hasMore {
+				if ctx.Err() != nil {
+					// NOTE: The consumer closed the channel while stopping.
+					return ctx.Err()
+				}
+
+				c.logger.Warn("RMQ handler deliveries channel closed.")
+
+				return c.deliveriesClosedError()
+			}
+
+			idle.reset()
+
+			batch = append(batch, d)
+			if len(batch) == 1 && c.batchMaxInterval > 0 {
+				flushTimer = c.clock.NewTimer(c.batchMaxInterval)
+				flushCh = flushTimer.C()
+			}
+
+			if len(batch) < c.batchSize {
+				continue
+			}
+
+			err := flush()
+			if err != nil {
+				return stacktrace.Propagate(err, "failed to process RMQ deliveries batch")
+			}
+		}
+	}
+}
+
+func (c *Consumer) handleBatch(ctx context.Context, batch []amqp.Delivery) error {
+	for i := range batch {
+		c.startProcessing(&batch[i])
+		defer c.finishProcessing(&batch[i])
+
+		c.observeDelivery(&batch[i])
+		c.trackUnacked(&batch[i])
+	}
+
+	batch, err := c.rejectContentTypes(batch)
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+
+	batch, err = c.skipGatedOutBatch(batch)
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+
+	c.stats.addInflight(len(batch))
+	defer c.stats.addInflight(-len(batch))
+
+	startedAt := c.clock.Now()
+
+	acknowledgements, err := c.handler.(BatchHandler).ReceiveBatch(ctx, batch)
+
+	handlerDuration := c.clock.Now().Sub(startedAt)
+	c.observeHandlerLatency(handlerDuration)
+
+	if err != nil {
+		for i := range batch {
+			c.reportProcessing(&batch[i], HandlerAcknowledgement{}, handlerDuration, err, nil)
+		}
+
+		return stacktrace.Propagate(err, "batch handler returned error")
+	}
+
+	if len(acknowledgements) != len(batch) {
+		return stacktrace.NewError(
+			"batch handler returned %d acknowledgements for %d deliveries",
+			len(acknowledgements),
+			len(batch),
+		)
+	}
+
+	for i := range batch {
+		err := c.acknowledge(&batch[i], acknowledgements[i], handlerDuration)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// WithBatchAck makes the consumer ack the processed deliveries in batches, e.g. for the high-throughput queues
+// where acking every delivery on its own is a bottleneck.
+//
+// The deliveries acked by the handler are not acked right away, but once size of them are pending,
+// and every interval, with a single ack of the highest delivery tag with multiple=true.
+// The nacks and rejects are not batched. The pending acks are flushed when the consumer stops, e.g. because
+// the handler failed, before the channel is closed. When the channel closes with pending acks,
+// the broker redelivers their deliveries.
+// The audit records and the processing reports of the deliveries are recorded once their ack is batched,
+// while a failed batch ack is logged with the number of the deliveries it acks.
+// A size < 2 acks every delivery on its own, which is the default. An interval <= 0 flushes the pending
+// acks only once size of them are pending, or when the consumer stops.
+//
+// NOTE: An ack with multiple=true acks all the outstanding deliveries up to its tag, so the acks are not batched
+// when the deliveries are processed concurrently with WithWorkers(), nor for the auto-ack queues.
+func WithBatchAck(size int, interval time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.batchAck.size = size
+		c.batchAck.interval = interval
+	}
+}
+
+// batchAcks holds the acks pending to be flushed, see WithBatchAck().
+type batchAcks struct {
+	size     int
+	interval time.Duration
+
+	mu sync.Mutex
+	// last is the delivery with the highest tag of the batch, copied since e.g. the batches
+	// of WithBatch() reuse the deliveries
+	last    amqp.Delivery
+	pending int
+}
+
+// batchesAcks reports whether the acks are batched, see WithBatchAck().
+func (c *Consumer) batchesAcks() bool {
+	return c.batchAck.size > 1 && c.workerCount() <= 1 && !c.handler.QueueAutoAck()
+}
+
+// resetBatchAcks forgets the pending acks, since the delivery tags are valid only on the channel they come from.
+func (c *Consumer) resetBatchAcks() {
+	c.batchAck.mu.Lock()
+	defer c.batchAck.mu.Unlock()
+
+	c.batchAck.last = amqp.Delivery{}
+	c.batchAck.pending = 0
+}
+
+// addBatchAck adds the ack of the delivery to the batch, flushing it once it is full.
+// Returns the error of the batch ack, if it is flushed.
+func (c *Consumer) addBatchAck(d *amqp.Delivery) error {
+	c.batchAck.mu.Lock()
+	defer c.batchAck.mu.Unlock()
+
+	c.batchAck.last = *d
+	c.batchAck.pending++
+
+	if c.batchAck.pending < c.batchAck.size {
+		return nil
+	}
+
+	return c.flushBatchAcksLocked()
+}
+
+// batchAcknowledgement adds the ack of the delivery to the batch, see WithBatchAck().
+func (c *Consumer) batchAcknowledgement(
+	d *amqp.Delivery,
+	acknowledgement HandlerAcknowledgement,
+	handlerDuration time.Duration,
+) error {
+	c.audit(d, acknowledgement, handlerDuration, nil)
+	c.reportProcessing(d, acknowledgement, handlerDuration, nil, nil)
+
+	err := c.addBatchAck(d)
+	if err != nil && c.handler.MustStopOnAckError() {
+		return stacktrace.Propagate(err, "stop consuming due to batch ack error")
+	}
+
+	return nil
+}
+
+// flushBatchAcks acks the pending deliveries, if any.
+func (c *Consumer) flushBatchAcks() {
+	c.batchAck.mu.Lock()
+	defer c.batchAck.mu.Unlock()
+
+	_ = c.flushBatchAcksLocked()
+}
+
+func (c *Consumer) flushBatchAcksLocked() error {
+	last, pending := c.batchAck.last, c.batchAck.pending
+	if pending == 0 {
+		return nil
+	}
+
+	c.batchAck.last = amqp.Delivery{}
+	c.batchAck.pending = 0
+
+	if c.isChannelClosed() {
+		c.logger.Warn(
+			"RMQ channel closed with pending batched acks, the broker redelivers their deliveries",
+			logger.Int("deliveries", pending),
+			logger.Uint64("delivery_tag", last.DeliveryTag),
+		)
+
+		return nil
+	}
+
+	err := last.Ack(true)
+	for i := 0; i < pending; i++ {
+		c.metric.ObserveAck(err == nil)
+	}
+
+	if err != nil {
+		c.logger.Error(
+			"failed to ack the batch of messages",
+			logger.ErrorField(err),
+			logger.Int("deliveries", pending),
+			logger.Uint64("delivery_tag", last.DeliveryTag),
+		)
+
+		return err
+	}
+
+	atomic.AddInt64(&c.stats.acked, int64(pending))
+
+	return nil
+}
+
+// watchBatchAcks flushes the pending acks every interval in the background, until ctx is done.
+func (c *Consumer) watchBatchAcks(ctx context.Context) {
+	if !c.batchesAcks() || c.batchAck.interval <= 0 {
+		return
+	}
+
+	ticker := c.clock.NewTicker(c.batchAck.interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				c.flushBatchAcks()
+			}
+		}
+	}()
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithBatchAck(t *testing.T) {
+	t.Run("it acks the batches with multiple=true and flushes the pending acks on stop", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(5)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		processed := make(chan struct{}, 5)
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			processed <- struct{}{}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithBatchAck(3, 0))
+
+		for tag := uint64(1); tag <= 5; tag++ {
+			channel.deliver(ack, tag, "foo")
+		}
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		for i := 0; i < 5; i++ {
+			<-processed
+		}
+		assert.Equal(t, []uint64{3}, ack.MultipleAcks())
+
+		cancel()
+		assert.Error(t, <-runErr)
+		assert.Equal(t, []uint64{3, 5}, ack.Acks())
+		assert.Equal(t, []uint64{3, 5}, ack.MultipleAcks())
+		assert.Equal(t, uint64(5), consumer.Stats().Acked)
+	})
+
+	t.Run("it flushes the pending acks every interval", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		processed := make(chan struct{}, 2)
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			processed <- struct{}{}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithClock(clock),
+			WithBatchAck(10, time.Second),
+		)
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		<-processed
+		<-processed
+		assert.Empty(t, ack.Acks())
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		assert.Eventually(t, func() bool {
+			return len(ack.MultipleAcks()) == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, []uint64{2}, ack.MultipleAcks())
+
+		cancel()
+		assert.Error(t, <-runErr)
+		assert.Equal(t, []uint64{2}, ack.Acks())
+	})
+
+	t.Run("it does not batch the nacks", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		processed := make(chan struct{}, 3)
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			defer func() {
+				processed <- struct{}{}
+			}()
+
+			if string(msg.Body) == "bad" {
+				return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithBatchAck(2, 0))
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bad")
+		channel.deliver(ack, 3, "bar")
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		for i := 0; i < 3; i++ {
+			<-processed
+		}
+
+		cancel()
+		assert.Error(t, <-runErr)
+		assert.Equal(t, []uint64{2}, ack.Nacks())
+		assert.Equal(t, []uint64{3}, ack.MultipleAcks())
+	})
+}
@@ -0,0 +1,217 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBatchHandler struct {
+	*fakeHandler
+
+	mu           sync.Mutex
+	batches      [][]string
+	receiveBatch func(ctx context.Context, deliveries []amqp.Delivery) ([]HandlerAcknowledgement, error)
+}
+
+func (h *fakeBatchHandler) ReceiveBatch(
+	ctx context.Context,
+	deliveries []amqp.Delivery,
+) ([]HandlerAcknowledgement, error) {
+	bodies := make([]string, 0, len(deliveries))
+	for _, d := range deliveries {
+		bodies = append(bodies, string(d.Body))
+	}
+
+	h.mu.Lock()
+	h.batches = append(h.batches, bodies)
+	h.mu.Unlock()
+
+	return h.receiveBatch(ctx, deliveries)
+}
+
+func (h *fakeBatchHandler) Batches() [][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([][]string(nil), h.batches...)
+}
+
+func TestWithBatch(t *testing.T) {
+	t.Run("it acknowledges every delivery of the batch according to its own outcome", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := &fakeBatchHandler{
+			fakeHandler: newFakeHandler(ackAll),
+			receiveBatch: func(ctx context.Context, deliveries []amqp.Delivery) ([]HandlerAcknowledgement, error) {
+				cancel()
+
+				return []HandlerAcknowledgement{
+					{Acknowledgement: Ack},
+					{Acknowledgement: Nack, Requeue: true},
+					{Acknowledgement: Ack},
+				}, nil
+			},
+		}
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{PrefetchCount: 3}, WithBatch(3, time.Hour))
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+		channel.deliver(ack, 3, "baz")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, [][]string{{"foo", "bar", "baz"}}, handler.Batches())
+		assert.Equal(t, []uint64{1, 3}, ack.Acks())
+		assert.Equal(t, []uint64{2}, ack.Nacks())
+	})
+
+	t.Run("it flushes an incomplete batch after the max interval", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := &fakeBatchHandler{
+			fakeHandler: newFakeHandler(ackAll),
+			receiveBatch: func(ctx context.Context, deliveries []amqp.Delivery) ([]HandlerAcknowledgement, error) {
+				cancel()
+
+				return []HandlerAcknowledgement{{Acknowledgement: Ack}, {Acknowledgement: Ack}}, nil
+			},
+		}
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithBatch(10, 10*time.Millisecond))
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, [][]string{{"foo", "bar"}}, handler.Batches())
+		assert.Equal(t, []uint64{1, 2}, ack.Acks())
+	})
+
+	t.Run("when the handler returns less acknowledgements than deliveries, it stops with an error", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+		handler := &fakeBatchHandler{
+			fakeHandler: newFakeHandler(ackAll),
+			receiveBatch: func(ctx context.Context, deliveries []amqp.Delivery) ([]HandlerAcknowledgement, error) {
+				return []HandlerAcknowledgement{{Acknowledgement: Ack}}, nil
+			},
+		}
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithBatch(2, time.Hour))
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+
+		err := consumer.Run(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "batch handler returned 1 acknowledgements for 2 deliveries")
+		assert.Empty(t, ack.Acks())
+	})
+
+	t.Run("when the handler does not implement BatchHandler, it returns an error", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(0)
+		consumer, _ := newTestConsumer(newFakeHandler(ackAll), channel, ConsumerConfig{}, WithBatch(2, time.Hour))
+
+		err := consumer.Run(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, 0, channel.ConsumeCalls())
+	})
+}
+
+func TestWithBatch_Reconnect(t *testing.T) {
+	t.Run("it discards the pending batch instead of acknowledging stale delivery tags", func(t *testing.T) {
+		t.Parallel()
+
+		staleChannel := newFakeChannel(2)
+		staleAck := &fakeAcknowledger{}
+
+		handler := &fakeBatchHandler{
+			fakeHandler: newFakeHandler(ackAll),
+		}
+		consumer, _ := newTestConsumer(handler, staleChannel, ConsumerConfig{PrefetchCount: 3}, WithBatch(3, time.Hour))
+
+		staleChannel.deliver(staleAck, 1, "foo")
+		staleChannel.deliver(staleAck, 2, "bar")
+		staleChannel.closeDeliveries()
+
+		err := consumer.Run(context.Background())
+		assert.Error(t, err)
+
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+		consumer.createChannel = func(ctx context.Context) (amqpChannel, error) {
+			return channel, nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler.receiveBatch = func(ctx context.Context, deliveries []amqp.Delivery) ([]HandlerAcknowledgement, error) {
+			cancel()
+
+			acknowledgements := make([]HandlerAcknowledgement, len(deliveries))
+			for i := range acknowledgements {
+				acknowledgements[i] = HandlerAcknowledgement{Acknowledgement: Ack}
+			}
+
+			return acknowledgements, nil
+		}
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+		channel.deliver(ack, 3, "baz")
+
+		err = consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, [][]string{{"foo", "bar", "baz"}}, handler.Batches())
+
+		staleAck.mu.Lock()
+		assert.Empty(t, staleAck.acks)
+		assert.Empty(t, staleAck.nacks)
+		assert.Empty(t, staleAck.rejects)
+		staleAck.mu.Unlock()
+
+		ack.mu.Lock()
+		defer ack.mu.Unlock()
+		assert.Equal(t, []uint64{1, 2, 3}, ack.acks)
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+// amqpChannel is the subset of *amqp.Channel used by the Consumer.
+//
+// It exists so that the consumer can be tested without a running RabbitMQ.
+type amqpChannel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Consume(
+		queue,
+		consumer string,
+		autoAck,
+		exclusive,
+		noLocal,
+		noWait bool,
+		args amqp.Table,
+	) (<-chan amqp.Delivery, error)
+	Get(queue string, autoAck bool) (msg amqp.Delivery, ok bool, err error)
+	Cancel(consumer string, noWait bool) error
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	NotifyClose(c chan *amqp.Error) chan *amqp.Error
+	NotifyCancel(c chan string) chan string
+	Close() error
+}
+
+// topologyChannel is the subset of *amqp.Channel used to declare a Topology.
+type topologyChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+}
+
+func newChannelFactory(client RabbitMQClientInterface) func(ctx context.Context) (amqpChannel, error) {
+	return func(ctx context.Context) (amqpChannel, error) {
+		channel, err := client.CreateChannel(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return channel, nil
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// ErrChannelClosed is the acknowledgement error recorded for the deliveries processed while the channel closed,
+// e.g. in the ProcessingReport, since they can no longer be acknowledged and the broker redelivers them.
+var ErrChannelClosed = errors.New("RMQ channel closed, the delivery is redelivered by the broker")
+
+// markChannelClosed makes the consumer skip the acknowledgements of the deliveries still being processed,
+// once the channel closes, or is about to be closed by the stopping consumer.
+func (c *Consumer) markChannelClosed() {
+	atomic.StoreInt32(&c.channelClosed, 1)
+}
+
+func (c *Consumer) isChannelClosed() bool {
+	return atomic.LoadInt32(&c.channelClosed) == 1
+}
+
+// skipClosedChannel records the acknowledgement of a delivery processed while the channel closed as skipped.
+func (c *Consumer) skipClosedChannel(d *amqp.Delivery, acknowledgement HandlerAcknowledgement, handlerDuration time.Duration) {
+	c.logger.Warn(
+		"RMQ channel closed while processing the delivery, skipping its acknowledgement, the broker redelivers it",
+		logger.String("acknowledgement", acknowledgement.Acknowledgement.String()),
+		tracingField(d.CorrelationId),
+		c.processingIDField(d),
+	)
+
+	c.audit(d, acknowledgement, handlerDuration, ErrChannelClosed)
+	c.reportProcessing(d, acknowledgement, handlerDuration, nil, ErrChannelClosed)
+}
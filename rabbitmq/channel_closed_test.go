@@ -0,0 +1,86 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_ChannelClosedWhileProcessing(t *testing.T) {
+	t.Run("it does not acknowledge the delivery on the closed channel", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		received := make(chan struct{})
+		release := make(chan struct{})
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			close(received)
+			<-release
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+
+		reports := make(chan ProcessingReport, 1)
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithProcessingReport(func(report ProcessingReport) {
+				reports <- report
+			}),
+		)
+		capturingLog := newCapturingLogger()
+		consumer.logger = capturingLog
+
+		channel.deliver(ack, 1, "foo")
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(context.Background())
+		}()
+
+		<-received
+		rmqErr := &amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED"}
+		channel.closeWithError(rmqErr)
+		assert.Eventually(t, consumer.isChannelClosed, time.Second, time.Millisecond)
+
+		close(release)
+		err := <-runErr
+		assert.Equal(t, rmqErr, stacktrace.RootCause(err))
+
+		assert.Empty(t, ack.Acks())
+		assert.Empty(t, ack.Nacks())
+		assert.Empty(t, ack.Rejects())
+
+		report := <-reports
+		assert.False(t, report.Acknowledged)
+		assert.Equal(t, ErrChannelClosed, report.AckErr)
+
+		entries := capturingLog.logs.FilterMessage(
+			"RMQ channel closed while processing the delivery, skipping its acknowledgement, the broker redelivers it",
+		).All()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "ack", entries[0].ContextMap()["acknowledgement"])
+	})
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// WithFinalCheckpoint registers a function called once the consumer stops, after the in-flight deliveries
+// are acknowledged and before the channel is closed, e.g. to make the offset of an external store durable.
+//
+// The checkpoint is called with a new context, since the one of the consumer is already canceled.
+// It is not called when the channel is closed by the broker, since the deliveries can no longer be acknowledged.
+// The error returned by the checkpoint is logged.
+//
+// NOTE: The in-flight deliveries are awaited only if Handler.WaitToConsumeInflight() is true,
+// and at most for the drain timeout, see WithDrainTimeout().
+func WithFinalCheckpoint(checkpoint func(ctx context.Context) error) ConsumerOption {
+	return func(c *Consumer) {
+		c.finalCheckpoint = checkpoint
+	}
+}
+
+func (c *Consumer) runFinalCheckpoint() {
+	if c.finalCheckpoint == nil {
+		return
+	}
+
+	err := c.finalCheckpoint(context.Background())
+	if err != nil {
+		c.logger.Error(
+			"RMQ consumer final checkpoint failed",
+			logger.String("queue", c.handler.GetQueueName()),
+			logger.ErrorField(err),
+		)
+	}
+}
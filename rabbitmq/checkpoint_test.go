@@ -0,0 +1,113 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFinalCheckpoint(t *testing.T) {
+	t.Run("it runs the checkpoint once after the in-flight deliveries are acked and before the channel is closed", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := make(chan struct{})
+		release := make(chan struct{})
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			close(received)
+			<-release
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+
+		checkpoints := 0
+		var acksAtCheckpoint []uint64
+		var closedAtCheckpoint bool
+		var checkpointErr error
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithFinalCheckpoint(func(ctx context.Context) error {
+				checkpoints++
+				acksAtCheckpoint = ack.Acks()
+				closedAtCheckpoint = channel.IsClosed()
+				checkpointErr = ctx.Err()
+
+				return nil
+			}),
+		)
+
+		channel.deliver(ack, 1, "slow")
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		<-received
+		cancel()
+		close(release)
+
+		assert.Error(t, <-runErr)
+		<-channel.Closed()
+
+		assert.Equal(t, 1, checkpoints)
+		assert.Equal(t, []uint64{1}, acksAtCheckpoint)
+		assert.False(t, closedAtCheckpoint)
+		assert.NoError(t, checkpointErr)
+	})
+
+	t.Run("it closes the channel even when the checkpoint fails", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		checkpointed := make(chan struct{})
+		consumer, _ := newTestConsumer(
+			newFakeHandler(ackAll),
+			channel,
+			ConsumerConfig{},
+			WithFinalCheckpoint(func(ctx context.Context) error {
+				close(checkpointed)
+
+				return errors.New("store unavailable")
+			}),
+		)
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		cancel()
+
+		assert.Error(t, <-runErr)
+		<-checkpointed
+		<-channel.Closed()
+	})
+}
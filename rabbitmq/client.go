@@ -16,6 +16,8 @@ package rabbitmq
 
 import (
 	"context"
+	"crypto/tls"
+	"sync"
 	"time"
 
 	"github.com/sumup-oss/go-pkgs/task"
@@ -41,18 +43,47 @@ type ClientConfig struct {
 	ConnectRetryAttempts int
 	// InitialReconnectDelay delay between each attempt
 	InitialReconnectDelay time.Duration
+	// TLSConfig is used to connect over TLS, e.g. with a client certificate to a managed broker requiring it.
+	// The ConnectionURI must use the `amqps://` scheme then. The `amqps://` URIs are dialed over TLS
+	// with the default configuration when it is nil.
+	TLSConfig *tls.Config
+}
+
+// amqpConnection is the subset of *amqp.Connection used by the RabbitMQClient.
+type amqpConnection interface {
+	Channel() (*amqp.Channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
 }
 
 // A simple client that tries to connect to rabbitmq and create a channel.
 //
 // Does not attempt to reconnect if the connection drops.
+//
+// The client can be shared by multiple consumers and producers, each of them holding a reference
+// to the client, see RabbitMQClient.Retain(). The connection is closed only once the last reference
+// is released by RabbitMQClient.Close(), so e.g. a stopping consumer does not close the connection
+// of a producer still flushing its messages.
 type RabbitMQClient struct {
 	amqpURI               string
-	conn                  *amqp.Connection
+	conn                  amqpConnection
 	metric                Metric
 	connectRetryAttempts  int
 	initialReconnectDelay time.Duration
 	cfg                   *ClientConfig
+
+	// mu protects the reference counting properties
+	mu sync.Mutex
+	// refs is the number of the references to the client, including the one of its creator
+	refs            int
+	creatorReleased bool
+	closed          bool
+}
+
+// retainer is implemented by the clients which count the references to them, see RabbitMQClient.Retain().
+type retainer interface {
+	Retain()
+	Release() error
 }
 
 func NewRabbitMQClient(ctx context.Context, cfg *ClientConfig) (RabbitMQClientInterface, error) {
@@ -62,11 +93,21 @@ func NewRabbitMQClient(ctx context.Context, cfg *ClientConfig) (RabbitMQClientIn
 		connectRetryAttempts:  cfg.ConnectRetryAttempts,
 		initialReconnectDelay: cfg.InitialReconnectDelay,
 		cfg:                   cfg,
+		refs:                  1,
 	}
 
-	err := task.RetryUntil(cfg.ConnectRetryAttempts, cfg.InitialReconnectDelay, func(c context.Context) error {
-		conn, dialErr := amqp.Dial(client.amqpURI)
+	err := validateTLS(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = task.RetryUntil(cfg.ConnectRetryAttempts, cfg.InitialReconnectDelay, func(c context.Context) error {
+		conn, dialErr := dial(client.amqpURI, cfg.TLSConfig)
 		if dialErr != nil {
+			if !IsRetryable(dialErr) {
+				return dialErr
+			}
+
 			cfg.Metric.ObserveRabbitMQConnectionRetry()
 
 			return task.NewRetryableError(dialErr)
@@ -87,6 +128,36 @@ func NewRabbitMQClient(ctx context.Context, cfg *ClientConfig) (RabbitMQClientIn
 	return client, nil
 }
 
+// validateTLS fails when the TLS configuration is provided for a plain AMQP URI,
+// since the connection would silently not use it.
+func validateTLS(cfg *ClientConfig) error {
+	if cfg.TLSConfig == nil {
+		return nil
+	}
+
+	uri, err := amqp.ParseURI(cfg.ConnectionURI)
+	if err != nil {
+		return stacktrace.Propagate(err, "invalid RMQ connection URI")
+	}
+
+	if uri.Scheme != "amqps" {
+		return stacktrace.NewError(
+			"RMQ connection URI must use the amqps:// scheme with a TLS config, got %s://",
+			uri.Scheme,
+		)
+	}
+
+	return nil
+}
+
+func dial(uri string, tlsConfig *tls.Config) (*amqp.Connection, error) {
+	if tlsConfig == nil {
+		return amqp.Dial(uri)
+	}
+
+	return amqp.DialTLS(uri, tlsConfig)
+}
+
 func (c *RabbitMQClient) CreateChannel(ctx context.Context) (*amqp.Channel, error) {
 	var channel *amqp.Channel
 
@@ -95,6 +166,10 @@ func (c *RabbitMQClient) CreateChannel(ctx context.Context) (*amqp.Channel, erro
 
 		channel, channelErr = c.conn.Channel()
 		if channelErr != nil {
+			if !IsRetryable(channelErr) {
+				return channelErr
+			}
+
 			c.metric.ObserveRabbitMQChanelConnectionRetry()
 
 			return task.NewRetryableError(channelErr)
@@ -119,36 +194,106 @@ func (c *RabbitMQClient) Setup(ctx context.Context, setup *Setup) error {
 	if err != nil {
 		return stacktrace.Propagate(err, "failed to create a RMQ channel")
 	}
+	// NOTE: The broker closes the channel on a failed declaration already, so the error is not relevant.
+	defer channel.Close()
 
-	for _, e := range setup.Exchanges {
-		err := channel.ExchangeDeclare(e.Name, e.Kind, e.Durable, e.AutoDelete, e.Internal, e.NoWait, e.Args)
-		if err != nil {
-			return stacktrace.Propagate(err, "could not declare exchange")
-		}
-	}
+	return declareTopology(channel, setup)
+}
 
-	for _, q := range setup.Queues {
-		_, err := channel.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, q.NoWait, q.Args)
-		if err != nil {
-			return stacktrace.Propagate(err, "could not declare queue")
-		}
+// DeclareTopology declares the exchanges, then the queues, then the bindings of the topology,
+// e.g. to declare the whole messaging setup of a service on startup.
+//
+// The declarations are idempotent, so it is safe to declare the same topology again, as long as
+// the existing entities are declared with the same options and arguments.
+// It fails fast with an error telling which entity failed to be declared, since the broker closes the channel
+// once a declaration fails, e.g. when the entity already exists with different options.
+func (c *RabbitMQClient) DeclareTopology(ctx context.Context, topology Topology) error {
+	return c.Setup(ctx, &topology)
+}
+
+// NotifyClose returns a channel receiving the error the connection is closed with,
+// e.g. to fail a readiness probe once the connection to RMQ drops.
+//
+// The channel receives at most once, and is closed once the connection closes. It is closed without receiving
+// when the connection is closed gracefully with RabbitMQClient.Close(), or right away if the connection is closed already.
+// It can be called at any time, e.g. before the consumers using the client run, and every call returns a new channel.
+// The channel is buffered, so the connection does not block closing while the error is not received.
+func (c *RabbitMQClient) NotifyClose() <-chan *amqp.Error {
+	return c.conn.NotifyClose(make(chan *amqp.Error, 1))
+}
+
+// Retain adds a reference to the client, which must be released with RabbitMQClient.Release().
+//
+// The creator of the client holds a reference, released with RabbitMQClient.Close(). Consumer.Run() retains
+// the client while it runs, and NewProducer() retains it until the producer is closed.
+func (c *RabbitMQClient) Retain() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.refs++
+}
+
+// Release releases a reference to the client, and closes the connection once there are no more references.
+// It is a no-op once the connection is closed.
+func (c *RabbitMQClient) Release() error {
+	c.mu.Lock()
+	if c.closed || c.refs == 0 {
+		c.mu.Unlock()
+
+		return nil
 	}
 
-	for _, b := range setup.QueueBindings {
-		err := channel.QueueBind(b.Name, b.Key, b.Exchange, b.NoWait, b.Args)
-		if err != nil {
-			return stacktrace.Propagate(
-				err,
-				"could not bind queue %s to exchange %s", b.Name, b.Exchange,
-			)
-		}
+	c.refs--
+	if c.refs > 0 {
+		c.mu.Unlock()
+
+		return nil
 	}
 
-	return nil
-}
+	c.closed = true
+	c.mu.Unlock()
 
-func (c *RabbitMQClient) Close() error {
 	err := c.conn.Close()
 
 	return stacktrace.Propagate(err, "RMQ connection close")
 }
+
+// Close releases the reference of the creator of the client, see RabbitMQClient.Retain().
+//
+// The connection is closed once the consumers and producers using the client are done with it.
+// Calling Close again is a no-op.
+func (c *RabbitMQClient) Close() error {
+	c.mu.Lock()
+	released := c.creatorReleased
+	c.creatorReleased = true
+	c.mu.Unlock()
+
+	if released {
+		return nil
+	}
+
+	return c.Release()
+}
+
+// retain adds a reference to the client, and returns the function releasing it.
+//
+// The release function releases the reference only the first time it is called.
+// The clients which do not count their references are closed on release instead.
+func retain(client RabbitMQClientInterface) func() error {
+	release := client.Close
+	if r, ok := client.(retainer); ok {
+		r.Retain()
+		release = r.Release
+	}
+
+	var once sync.Once
+
+	return func() error {
+		var err error
+		once.Do(func() {
+			err = release()
+		})
+
+		return err
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// WithClock sets the clock used by the time-based features of the consumer, e.g. the batch interval,
+// the pause cooldown, the drain timeout and the backoff of the RetryableConsumer.
+//
+// The real clock is used by default. A fake clock, such as tasktest.FakeClock, can be set
+// to test these features deterministically.
+func WithClock(clock task.Clock) ConsumerOption {
+	return func(c *Consumer) {
+		c.clock = clock
+	}
+}
+
+// clockFromOptions returns the clock set by the options, or the real clock if none is set.
+func clockFromOptions(opts []ConsumerOption) task.Clock {
+	c := &Consumer{clock: task.RealClock()}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c.clock
+}
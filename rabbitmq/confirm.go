@@ -0,0 +1,88 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+)
+
+// WithPublisherConfirms puts the consumer channel in confirm mode, so the messages the consumer re-publishes,
+// e.g. to the retry ladder, are confirmed by the broker before the original deliveries are acked.
+//
+// A re-publish which is nacked by the broker, or not confirmed before the channel closes,
+// fails the same way a failed publish does, so the original delivery is not lost.
+// When the consumer stops, it waits for the pending confirmations before closing the channel,
+// bounded by the drain timeout, see WithDrainTimeout().
+func WithPublisherConfirms() ConsumerOption {
+	return func(c *Consumer) {
+		c.publisherConfirms = true
+	}
+}
+
+// enableConfirms puts the channel in confirm mode if the publisher confirms are enabled.
+func (c *Consumer) enableConfirms(channel amqpChannel) error {
+	if !c.publisherConfirms {
+		return nil
+	}
+
+	err := channel.Confirm(false)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to put the RMQ channel in confirm mode")
+	}
+
+	c.confirmMu.Lock()
+	c.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	c.confirmMu.Unlock()
+
+	return nil
+}
+
+// republish publishes msg on the consumer channel, and waits for its confirmation
+// if the publisher confirms are enabled.
+func (c *Consumer) republish(exchange, key string, msg amqp.Publishing) error {
+	if !c.publisherConfirms {
+		return c.currentChannel().Publish(exchange, key, false, false, msg)
+	}
+
+	c.republishes.add()
+	defer c.republishes.done()
+
+	c.confirmMu.Lock()
+	defer c.confirmMu.Unlock()
+
+	err := c.currentChannel().Publish(exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: Wait for the confirmation regardless of the consumer context, since it is canceled
+	// as soon as the consumer starts stopping, and the channel is kept open until this returns.
+	confirmation, ok := <-c.confirms
+	if !ok {
+		return stacktrace.NewError("RMQ channel closed before confirming the re-published message")
+	}
+
+	if !confirmation.Ack {
+		return stacktrace.NewError("RMQ broker nacked the re-published message")
+	}
+
+	return nil
+}
+
+// waitRepublishes waits until the pending re-publishes are confirmed, or the drain timeout passes.
+func (c *Consumer) waitRepublishes() {
+	c.waitDrained(&c.republishes, "RMQ consumer drain timeout exceeded, closing the channel with unconfirmed re-publishes")
+}
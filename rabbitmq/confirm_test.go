@@ -0,0 +1,115 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPublisherConfirms(t *testing.T) {
+	t.Run("it confirms the re-publish in flight at shutdown before closing the channel", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithRetryLadder([]time.Duration{5 * time.Second}),
+			WithPublisherConfirms(),
+			WithDrainTimeout(time.Minute),
+		)
+
+		channel.deliver(ack, 1, "retried")
+
+		ran := make(chan error)
+		go func() {
+			ran <- consumer.Run(ctx)
+		}()
+
+		<-channel.publishedCh
+
+		select {
+		case <-channel.Closed():
+			t.Fatal("the channel must stay open until the re-publish is confirmed")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		channel.confirm(true)
+
+		// NOTE: Canceling the consumer closes the deliveries, so Run returns either of the errors.
+		assert.Error(t, <-ran)
+
+		<-channel.Closed()
+		ack.mu.Lock()
+		defer ack.mu.Unlock()
+		assert.Equal(t, []uint64{1}, ack.acks)
+		assert.Empty(t, ack.nacks)
+	})
+
+	t.Run("it requeues the delivery when the re-publish is nacked", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithRetryLadder([]time.Duration{5 * time.Second}),
+			WithPublisherConfirms(),
+		)
+
+		channel.deliver(ack, 1, "retried")
+
+		go func() {
+			<-channel.publishedCh
+			channel.confirm(false)
+		}()
+
+		err := consumer.Run(ctx)
+		assert.Error(t, err)
+
+		require.Len(t, channel.Published(), 1)
+		ack.mu.Lock()
+		defer ack.mu.Unlock()
+		assert.Empty(t, ack.acks)
+		assert.Equal(t, []uint64{1}, ack.nacks)
+	})
+}
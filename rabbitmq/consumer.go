@@ -16,13 +16,18 @@ package rabbitmq
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/palantir/stacktrace"
 
-	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
+	"github.com/sumup-oss/go-pkgs/backoff"
 	"github.com/sumup-oss/go-pkgs/logger"
+	"github.com/sumup-oss/go-pkgs/task"
 
 	"github.com/streadway/amqp"
 )
@@ -34,6 +39,10 @@ type ConsumerConfig struct {
 	// with too many deliveries in flight which results into badly distributed work load and high memory footprint
 	// of the consumers.
 	PrefetchCount int
+	// PrefetchGlobal applies the PrefetchCount to all the consumers of the channel together,
+	// instead of to each consumer of the channel separately.
+	// ref: https://www.rabbitmq.com/consumer-prefetch.html#sharing-the-limit
+	PrefetchGlobal bool
 }
 
 type Consumer struct {
@@ -42,7 +51,107 @@ type Consumer struct {
 	logger  logger.StructuredLogger
 	metric  Metric
 	cfg     ConsumerConfig
-	stopWg  sync.WaitGroup
+
+	// inflightMu protects the inflightStopped and inflight properties, and the start of the in-flight
+	// deliveries tracked by the inflight, see startInflight()
+	inflightMu      sync.Mutex
+	inflightStopped bool
+	inflight        *drainGroup
+
+	createChannel func(ctx context.Context) (amqpChannel, error)
+
+	// channelMu protects the channel and the cfg.PrefetchCount properties
+	channelMu sync.RWMutex
+	channel   amqpChannel
+	// channelClosed is 1 once the channel is closed, see markChannelClosed()
+	channelClosed int32
+
+	retryLadder []time.Duration
+
+	deadLetterMaxAttempts int
+	deadLetterDelay       time.Duration
+
+	batchSize        int
+	batchMaxInterval time.Duration
+
+	onChannelError func(err *amqp.Error)
+
+	deliveryContextFuncs []DeliveryContextFunc
+	deliveryInterceptors []DeliveryInterceptor
+
+	onUnmarshalError UnmarshalErrorHandler
+
+	errorRate     *errorRateWindow
+	pauseCooldown time.Duration
+
+	metricLabels *MetricLabels
+
+	clock        task.Clock
+	drainTimeout time.Duration
+
+	autoAckShutdownPolicy AutoAckShutdownPolicy
+	finalCheckpoint       func(ctx context.Context) error
+
+	maxUnackedAge time.Duration
+
+	idleTimeout time.Duration
+	onIdle      func()
+	unacked     *unackedTracker
+
+	stats *consumerStats
+
+	frameCodec FrameCodec
+	pipeline   Pipeline
+
+	auditLogger logger.StructuredLogger
+	auditLevel  zapcore.Level
+
+	onProcessingReport func(report ProcessingReport)
+	debugRing          *deliveryRing
+
+	priorityOrdering bool
+
+	jsonArrayFanOut        bool
+	jsonArrayPartialFailed HandlerAcknowledgement
+
+	expectedContentType string
+
+	featureGate     func(d *amqp.Delivery) bool
+	featureGatedOut HandlerAcknowledgement
+
+	workers int
+
+	batchAck batchAcks
+	asyncAck asyncAcks
+
+	pollBackoff *backoff.Config
+
+	// processingIDs holds the processing ID of every delivery being processed, see ProcessingIDFromContext()
+	processingIDs sync.Map
+
+	queueDeletedPolicy QueueDeletedPolicy
+	serverCancelCh     chan string
+
+	publisherConfirms bool
+	// confirmMu serializes the re-publishes, so the next confirmation is the one of the pending re-publish
+	confirmMu   sync.Mutex
+	confirms    chan amqp.Confirmation
+	republishes drainGroup
+}
+
+// ConsumerOption configures optional behavior of a Consumer.
+type ConsumerOption func(c *Consumer)
+
+// WithOnChannelError registers a callback invoked as soon as the RMQ channel used by the consumer
+// is closed with an error, e.g. because the connection dropped or because of a protocol error.
+//
+// It allows the callers to react immediately, e.g. to alert or to fail a readiness probe,
+// instead of finding out once the consumer's Run method returns.
+// The callback is invoked from the consumer's goroutine watching the channel, so it must not block.
+func WithOnChannelError(fn func(err *amqp.Error)) ConsumerOption {
+	return func(c *Consumer) {
+		c.onChannelError = fn
+	}
 }
 
 func NewConsumer(
@@ -51,58 +160,165 @@ func NewConsumer(
 	logger logger.StructuredLogger,
 	metric Metric,
 	cfg ConsumerConfig,
+	opts ...ConsumerOption,
 ) *Consumer {
-	return &Consumer{
+	consumer := &Consumer{
 		client:  client,
 		handler: handler,
 		logger:  logger,
 		metric:  metric,
 		cfg:     cfg,
-		stopWg:  sync.WaitGroup{},
+
+		inflight:      &drainGroup{},
+		createChannel: newChannelFactory(client),
+		clock:         task.RealClock(),
+		stats:         &consumerStats{},
+		unacked:       newUnackedTracker(),
+	}
+
+	for _, opt := range opts {
+		opt(consumer)
 	}
+
+	consumer.labelMetric()
+
+	return consumer
 }
 
-func (c *Consumer) Run(ctx context.Context) error {
-	channel, err := c.client.CreateChannel(ctx)
+func (c *Consumer) Run(ctx context.Context) (err error) {
+	// NOTE: The client is retained while the consumer runs, and released by the goroutine
+	// stopping the consumer once it is started, see RabbitMQClient.Retain().
+	releaseClient := retain(c.client)
+	stopping := false
+	defer c.observeState(ConsumerDown)
+	defer func() {
+		if !stopping {
+			_ = releaseClient()
+		}
+	}()
+
+	channel, err := c.createChannel(ctx)
 	if err != nil {
 		return stacktrace.Propagate(err, "failed to create a RMQ channel")
 	}
 
+	if c.batchSize > 0 {
+		if _, ok := c.handler.(BatchHandler); !ok {
+			return stacktrace.NewError("RMQ handler must implement BatchHandler to consume in batches")
+		}
+	}
+
+	c.setChannel(channel)
+	c.observeState(ConsumerConnected)
+	atomic.StoreInt32(&c.channelClosed, 0)
+	c.unacked.reset()
+	c.resetInflight()
+	c.resetBatchAcks()
+	c.startAsyncAcks()
+
+	// NOTE: Return only once the consumer stopped and closed the channel, so the next Run of the consumer
+	// does not overlap with the stopping of this one.
+	stopped := make(chan struct{})
+	defer func() {
+		if stopping {
+			<-stopped
+		}
+	}()
+
+	parentCtx := ctx
 	ctx, cancelFunc := context.WithCancel(ctx)
 	defer cancelFunc()
 
 	closeCh := channel.NotifyClose(make(chan *amqp.Error))
+	// closedErr receives the error the channel is closed with, before the consumer cancels itself
+	closedErr := make(chan error, 1)
+
+	defer func() {
+		// NOTE: When the channel closes, the consumer stops with the error of its own canceled context.
+		// Return the error of the channel instead, so the callers can tell it apart from a stop e.g. to reconnect.
+		if err == nil || parentCtx.Err() != nil {
+			return
+		}
+
+		select {
+		case err = <-closedErr:
+		default:
+		}
+	}()
+
+	if c.drainsAutoAcked() {
+		// NOTE: Track the handling of the deliveries, so the channel is closed only once the auto-acked
+		// deliveries are drained, otherwise amqp drops the buffered ones when the channel closes.
+		inflight := c.currentInflight()
+		inflight.add()
+		defer inflight.done()
+	}
+
+	stopping = true
 
 	go func() {
+		defer close(stopped)
+
 		select {
 		case rmqErr := <-closeCh:
+			c.markChannelClosed()
+			c.stopAsyncAcks()
+			c.flushBatchAcks()
+
+			if rmqErr == nil {
+				closedErr <- stacktrace.NewError("RMQ closed the channel without an error")
+			} else {
+				closedErr <- stacktrace.Propagate(rmqErr, "RMQ closed the channel")
+			}
+
 			cancelFunc()
+			_ = releaseClient()
+
+			if rmqErr == nil {
+				c.logger.Warn("RMQ closed the connection without an error")
+
+				return
+			}
+
 			c.logger.Warn(
 				"RMQ closed the connection",
-				zap.String("reason", rmqErr.Reason),
-				zap.Int("code", rmqErr.Code),
-				zap.Bool("recover", rmqErr.Recover),
-				zap.Bool("server", rmqErr.Server),
+				logger.String("reason", rmqErr.Reason),
+				logger.Int("code", rmqErr.Code),
+				logger.Bool("recover", rmqErr.Recover),
+				logger.Bool("server", rmqErr.Server),
 			)
 
+			if c.onChannelError != nil {
+				c.onChannelError(rmqErr)
+			}
+
 			return
 		case <-ctx.Done():
 			c.logger.Info("Received context cancel. Going to close RMQ connections.")
-			err = channel.Cancel(c.handler.GetConsumerTag(), false)
-			if err != nil {
-				c.logger.Warn("failed to cancel the RMQ channel while stopping handler", logger.ErrorField(err))
+			c.stopInflight()
+
+			cancelErr := channel.Cancel(c.handler.GetConsumerTag(), false)
+			if cancelErr != nil {
+				c.logger.Warn("failed to cancel the RMQ channel while stopping handler", logger.ErrorField(cancelErr))
 			}
 
 			// NOTE: We must process the events before we close the channel
 			// otherwise we cant ACK/NACK.
-			if c.handler.WaitToConsumeInflight() {
-				c.stopWg.Wait()
+			if c.handler.WaitToConsumeInflight() || c.drainsAutoAcked() {
+				c.waitInflight()
+			} else {
+				c.waitRepublishes()
 			}
 
+			c.runFinalCheckpoint()
+
+			c.stopAsyncAcks()
+			c.flushBatchAcks()
+			c.markChannelClosed()
 			_ = channel.Close()
 
 			c.logger.Info("RMQ consumer stopped.")
-			_ = c.client.Close()
+			_ = releaseClient()
 		}
 	}()
 
@@ -110,9 +326,45 @@ func (c *Consumer) Run(ctx context.Context) error {
 		return stacktrace.Propagate(ctx.Err(), "context canceled")
 	}
 
-	err = channel.Qos(c.cfg.PrefetchCount, 0, false)
+	prefetchCount := c.prefetchCount()
+	err = channel.Qos(prefetchCount, 0, c.cfg.PrefetchGlobal)
 	if err != nil {
-		return stacktrace.Propagate(err, "failed to set RMQ channel's QoS prefetch count to: %d", c.cfg.PrefetchCount)
+		return stacktrace.Propagate(err, "failed to set RMQ channel's QoS prefetch count to: %d", prefetchCount)
+	}
+
+	err = c.enableConfirms(channel)
+	if err != nil {
+		return err
+	}
+
+	if warmupHandler, ok := c.handler.(WarmupHandler); ok {
+		err = warmupHandler.Warmup(ctx)
+		if err != nil {
+			return stacktrace.Propagate(err, "RMQ handler warm-up failed")
+		}
+	}
+
+	c.watchServerCancel(channel)
+	c.watchUnackedAge(ctx)
+	c.watchBatchAcks(ctx)
+
+	for {
+		err = c.consume(ctx, channel)
+		if !c.mustRedeclare(err) {
+			return err
+		}
+
+		err = c.redeclareQueue(ctx)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// consume handles the deliveries of the channel until it fails or stops.
+func (c *Consumer) consume(ctx context.Context, channel amqpChannel) error {
+	if c.pollBackoff != nil {
+		return stacktrace.Propagate(c.pull(ctx, channel), "failed/stopped pulling RMQ deliveries")
 	}
 
 	deliveries, err := channel.Consume(
@@ -128,7 +380,16 @@ func (c *Consumer) Run(ctx context.Context) error {
 		return stacktrace.Propagate(err, "couldn't start consuming from RMQ channel")
 	}
 
-	err = c.handleDeliveries(ctx, deliveries)
+	c.setConsuming(true)
+	defer c.setConsuming(false)
+
+	deliveries = c.prioritize(ctx, deliveries)
+
+	if c.batchSize > 0 {
+		err = c.handleBatchDeliveries(ctx, deliveries)
+	} else {
+		err = c.handleDeliveries(ctx, deliveries)
+	}
 
 	return stacktrace.Propagate(err, "failed/stopped handling RMQ consumer deliveries")
 }
@@ -137,120 +398,295 @@ func (c *Consumer) handleDeliveries(
 	ctx context.Context,
 	deliveries <-chan amqp.Delivery,
 ) error {
+	if workers := c.workerCount(); workers > 1 {
+		return c.handleConcurrentDeliveries(ctx, deliveries, workers)
+	}
+
+	idle := c.newIdleWatch()
+	defer idle.stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			c.logger.Warn("RMQ handler stopping")
+			c.drainAutoAcked(deliveries)
 
 			return ctx.Err()
+		case <-idle.C():
+			idle.fired()
+			c.onIdle()
 		case d, hasMore := <-deliveries:
 			if !hasMore {
+				if ctx.Err() != nil {
+					// NOTE: The consumer closed the channel while stopping.
+					return ctx.Err()
+				}
+
 				c.logger.Warn("RMQ handler deliveries channel closed.")
 
-				return stacktrace.NewError("RMQ handler deliveries channel closed.")
+				return c.deliveriesClosedError()
+			}
+
+			inflight := c.startInflight()
+			if inflight == nil {
+				return ctx.Err()
 			}
 
-			// TODO: Add option to parallelize processing
-			c.stopWg.Add(1)
 			err := c.handleSingleDelivery(ctx, &d)
-			c.stopWg.Done()
+			inflight.done()
 			if err != nil {
 				return stacktrace.Propagate(err, "failed to process RMQ delivery")
 			}
+
+			err = c.pauseOnErrorRate(ctx)
+			if err != nil {
+				return err
+			}
+
+			idle.reset()
 		}
 	}
 }
 
 func (c *Consumer) handleSingleDelivery(ctx context.Context, d *amqp.Delivery) error {
-	c.metric.ObserveMsgDelivered()
+	c.observeDelivery(d)
+	c.trackUnacked(d)
 
-	acknowledgement, err := c.handler.ReceiveMessage(ctx, &Message{
-		Body:          d.Body,
-		CorrelationID: d.CorrelationId,
+	c.stats.addInflight(1)
+	defer c.stats.addInflight(-1)
+
+	processingID := c.startProcessing(d)
+	defer c.finishProcessing(d)
+
+	ctx = withProcessingID(c.deliveryContext(ctx, d), processingID)
+	startedAt := c.clock.Now()
+
+	acknowledgement, err := c.intercept(ctx, d, func(ctx context.Context) (HandlerAcknowledgement, error) {
+		if !c.hasExpectedContentType(d) {
+			return c.rejectContentType(d), nil
+		}
+
+		if !c.passesFeatureGate(d) {
+			return c.skipGatedOut(d), nil
+		}
+
+		if c.frameCodec != nil {
+			return c.receiveFrames(ctx, d)
+		}
+
+		return c.receive(ctx, d, d.Body)
 	})
+
+	handlerDuration := c.clock.Now().Sub(startedAt)
+	c.observeHandlerLatency(handlerDuration)
+
 	if err != nil {
+		c.reportProcessing(d, HandlerAcknowledgement{}, handlerDuration, err, nil)
+
 		return stacktrace.Propagate(err, "handler returned error")
 	}
 
+	c.recordDeliveryOutcome(acknowledgement)
+
+	return c.acknowledge(d, acknowledgement, handlerDuration)
+}
+
+// receive passes the body of the delivery to the handler, once it is processed by the pipeline, see WithPipeline().
+//
+// A panic of the handler is recovered, see recoverHandlerPanic().
+func (c *Consumer) receive(
+	ctx context.Context,
+	d *amqp.Delivery,
+	body []byte,
+) (acknowledgement HandlerAcknowledgement, err error) {
+	defer c.recoverHandlerPanic(d, &acknowledgement, &err)
+
+	body, ok := c.runPipeline(ctx, d, body)
+	if !ok {
+		return HandlerAcknowledgement{Acknowledgement: Reject, Requeue: false}, nil
+	}
+
+	msg := &Message{
+		Body:          body,
+		CorrelationID: d.CorrelationId,
+		DeliveryCount: DeliveryCount(d),
+	}
+
+	if jsonHandler, ok := c.handler.(JSONHandler); ok {
+		return c.receiveJSON(ctx, jsonHandler, d, msg)
+	}
+
+	return c.handler.ReceiveMessage(ctx, msg)
+}
+
+// acknowledge acks, nacks or rejects the delivery as requested by the handler,
+// which took handlerDuration to process it.
+func (c *Consumer) acknowledge(
+	d *amqp.Delivery,
+	acknowledgement HandlerAcknowledgement,
+	handlerDuration time.Duration,
+) error {
 	if c.handler.QueueAutoAck() {
+		c.audit(d, HandlerAcknowledgement{Acknowledgement: Ack}, handlerDuration, nil)
+		c.reportProcessing(d, HandlerAcknowledgement{Acknowledgement: Ack}, handlerDuration, nil, nil)
 		c.metric.ObserveAck(true)
+		atomic.AddInt64(&c.stats.acked, 1)
 
 		return nil
 	}
 
-	switch acknowledgement.Acknowledgement {
-	case Ack:
-		err := d.Ack(false)
-		if err != nil {
-			c.metric.ObserveAck(false)
-			c.logger.Error(
-				"failed to ack message",
-				zap.Error(err),
-				tracingField(d.CorrelationId),
-			)
+	if !c.unacked.settle(d) {
+		c.logger.Warn(
+			"RMQ delivery already nacked for exceeding the max unacked age, ignoring its acknowledgement",
+			logger.String("acknowledgement", acknowledgement.Acknowledgement.String()),
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
 
-			if c.handler.MustStopOnAckError() {
-				return stacktrace.Propagate(err, "stop consuming due to ack error")
-			}
+		return nil
+	}
 
-			return nil
+	if c.asyncAck.queueSize > 0 {
+		queued, err := c.queueAcknowledgement(d, acknowledgement, handlerDuration)
+		if queued {
+			return err
 		}
+	}
 
-		c.metric.ObserveAck(true)
-		c.logger.Info(
-			"successful ack message",
-			tracingField(d.CorrelationId),
-		)
+	return c.sendAcknowledgement(d, acknowledgement, handlerDuration)
+}
+
+// sendAcknowledgement performs the acknowledgement of the delivery on the broker.
+func (c *Consumer) sendAcknowledgement(
+	d *amqp.Delivery,
+	acknowledgement HandlerAcknowledgement,
+	handlerDuration time.Duration,
+) error {
+	if c.isChannelClosed() {
+		c.skipClosedChannel(d, acknowledgement, handlerDuration)
 
 		return nil
+	}
+
+	switch {
+	case c.deadLetterMaxAttempts > 0 && acknowledgement.Acknowledgement != Ack:
+		acknowledgement = c.retryOrDeadLetter(d, acknowledgement)
+	case len(c.retryLadder) > 0 && acknowledgement.Acknowledgement != Ack && acknowledgement.Requeue:
+		acknowledgement = c.retryLater(d)
+	}
+
+	if acknowledgement.Acknowledgement == Ack && c.batchesAcks() {
+		return c.batchAcknowledgement(d, acknowledgement, handlerDuration)
+	}
+
+	var ackErr error
+	var mustStop bool
+
+	switch acknowledgement.Acknowledgement {
+	case Ack:
+		ackErr = d.Ack(false)
+		c.metric.ObserveAck(ackErr == nil)
+		mustStop = c.handler.MustStopOnAckError()
 	case Nack:
-		err := d.Nack(false, acknowledgement.Requeue)
-		if err != nil {
-			c.metric.ObserveNack(false)
-			c.logger.Error(
-				"failed to nack message",
-				zap.Error(err),
-				tracingField(d.CorrelationId),
-			)
+		ackErr = d.Nack(false, acknowledgement.Requeue)
+		c.metric.ObserveNack(ackErr == nil)
+		mustStop = c.handler.MustStopOnNAckError()
+	case Reject:
+		ackErr = d.Reject(acknowledgement.Requeue)
+		c.metric.ObserveReject(ackErr == nil)
+		mustStop = c.handler.MustStopOnRejectError()
+	default:
+		return stacktrace.NewError("acknowledgement type not in predefined")
+	}
 
-			if c.handler.MustStopOnNAckError() {
-				return stacktrace.Propagate(err, "stop consuming due to nack error")
-			}
+	// NOTE: Audit and report the acknowledgement once the broker call is made, so they record its outcome.
+	c.audit(d, acknowledgement, handlerDuration, ackErr)
+	c.reportProcessing(d, acknowledgement, handlerDuration, nil, ackErr)
 
-			return nil
+	if ackErr != nil {
+		c.logger.Error(
+			fmt.Sprintf("failed to %s message", acknowledgement.Acknowledgement),
+			logger.ErrorField(ackErr),
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
+
+		if mustStop {
+			return stacktrace.Propagate(ackErr, "stop consuming due to %s error", acknowledgement.Acknowledgement)
 		}
 
-		c.metric.ObserveNack(true)
+		return nil
+	}
+
+	switch acknowledgement.Acknowledgement {
+	case Ack:
+		atomic.AddInt64(&c.stats.acked, 1)
+		c.logger.Info(
+			"successful ack message",
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
+	case Nack:
+		atomic.AddInt64(&c.stats.nacked, 1)
 		c.logger.Info(
 			"successful nack message",
 			tracingField(d.CorrelationId),
+			c.processingIDField(d),
 		)
-
-		return nil
 	case Reject:
-		err := d.Reject(acknowledgement.Requeue)
-		if err != nil {
-			c.metric.ObserveReject(false)
-			c.logger.Error(
-				"failed to reject message",
-				zap.Error(err),
-				tracingField(d.CorrelationId),
-			)
-
-			if c.handler.MustStopOnRejectError() {
-				return stacktrace.Propagate(err, "stop consuming due to reject error")
-			}
-
-			return nil
-		}
-		c.metric.ObserveReject(true)
+		atomic.AddInt64(&c.stats.rejected, 1)
 		c.logger.Info(
 			"successful rejected message",
 			tracingField(d.CorrelationId),
+			c.processingIDField(d),
 		)
+	}
 
-		return nil
-	default:
-		return stacktrace.NewError("acknowledgement type not in predefined")
+	return nil
+}
+
+func (c *Consumer) setChannel(channel amqpChannel) {
+	c.channelMu.Lock()
+	defer c.channelMu.Unlock()
+
+	c.channel = channel
+}
+
+func (c *Consumer) currentChannel() amqpChannel {
+	c.channelMu.RLock()
+	defer c.channelMu.RUnlock()
+
+	return c.channel
+}
+
+// SetPrefetch changes the prefetch count of the consumer at runtime, without restarting it.
+//
+// The new prefetch count is applied to the channel the consumer is currently consuming from, if any,
+// and it is used for every channel the consumer creates from now on.
+// It is safe to call SetPrefetch while the consumer processes deliveries.
+func (c *Consumer) SetPrefetch(count int) error {
+	if count < 0 {
+		return stacktrace.NewError("invalid RMQ prefetch count: %d", count)
+	}
+
+	c.channelMu.Lock()
+	defer c.channelMu.Unlock()
+
+	if c.channel != nil {
+		err := c.channel.Qos(count, 0, c.cfg.PrefetchGlobal)
+		if err != nil {
+			return stacktrace.Propagate(err, "failed to set RMQ channel's QoS prefetch count to: %d", count)
+		}
 	}
+
+	c.cfg.PrefetchCount = count
+
+	return nil
+}
+
+func (c *Consumer) prefetchCount() int {
+	c.channelMu.RLock()
+	defer c.channelMu.RUnlock()
+
+	return c.cfg.PrefetchCount
 }
@@ -0,0 +1,82 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+// ConsumerState is the lifecycle state of a consumer, see ConsumerStateMetric.
+type ConsumerState int
+
+const (
+	// ConsumerDown is the state of a consumer which does not run, or has no channel.
+	ConsumerDown ConsumerState = iota
+	// ConsumerConnected is the state of a consumer which has a channel, but does not receive deliveries.
+	ConsumerConnected
+	// ConsumerConsuming is the state of a consumer receiving deliveries.
+	ConsumerConsuming
+	// ConsumerPaused is the state of a consumer paused by WithPauseOnErrorRate().
+	ConsumerPaused
+)
+
+// String returns the name of the state: "down", "connected", "consuming" or "paused".
+func (s ConsumerState) String() string {
+	switch s {
+	case ConsumerDown:
+		return "down"
+	case ConsumerConnected:
+		return "connected"
+	case ConsumerConsuming:
+		return "consuming"
+	case ConsumerPaused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+// ConsumerStateMetric is an optional interface implemented by metrics observing the lifecycle state
+// of the consumers, e.g. as a gauge to alert on a consumer which is connected but not consuming.
+//
+// The consumer observes its state on every transition, the value of the state being
+// 0 for ConsumerDown, 1 for ConsumerConnected, 2 for ConsumerConsuming and 3 for ConsumerPaused.
+type ConsumerStateMetric interface {
+	ObserveConsumerState(state ConsumerState)
+}
+
+func (c *Consumer) observeState(state ConsumerState) {
+	if metric, ok := c.metric.(ConsumerStateMetric); ok {
+		metric.ObserveConsumerState(state)
+	}
+}
+
+// setConsuming records whether the consumer receives deliveries.
+func (c *Consumer) setConsuming(consuming bool) {
+	c.stats.setConsuming(consuming)
+
+	if consuming {
+		c.observeState(ConsumerConsuming)
+	} else {
+		c.observeState(ConsumerConnected)
+	}
+}
+
+// setPaused records whether the consumer is paused, see WithPauseOnErrorRate().
+func (c *Consumer) setPaused(paused bool) {
+	c.stats.setPaused(paused)
+
+	if paused {
+		c.observeState(ConsumerPaused)
+	} else {
+		c.observeState(ConsumerConsuming)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+type consumerStateMetric struct {
+	NullMetric
+
+	mu     sync.Mutex
+	states []ConsumerState
+}
+
+func (m *consumerStateMetric) ObserveConsumerState(state ConsumerState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.states = append(m.states, state)
+}
+
+func (m *consumerStateMetric) States() []ConsumerState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]ConsumerState(nil), m.states...)
+}
+
+func TestConsumer_ObserveConsumerState(t *testing.T) {
+	t.Run("it observes the transitions through connect, consume and pause", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(pauseMinDeliveries + 1)
+		ack := &fakeAcknowledger{}
+		metric := &consumerStateMetric{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			if string(msg.Body) == "ok" {
+				cancel()
+
+				return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: false}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithPauseOnErrorRate(0.5, time.Minute, time.Millisecond),
+		)
+		consumer.metric = metric
+
+		for i := 1; i <= pauseMinDeliveries; i++ {
+			channel.deliver(ack, uint64(i), "fail")
+		}
+		channel.deliver(ack, pauseMinDeliveries+1, "ok")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(
+			t,
+			[]ConsumerState{
+				ConsumerConnected,
+				ConsumerConsuming,
+				ConsumerPaused,
+				ConsumerConsuming,
+				ConsumerConnected,
+				ConsumerDown,
+			},
+			metric.States(),
+		)
+	})
+
+	t.Run("it observes the consumer down when it cannot create a channel", func(t *testing.T) {
+		t.Parallel()
+
+		metric := &consumerStateMetric{}
+		consumer, _ := newTestConsumer(newFakeHandler(ackAll), newFakeChannel(0), ConsumerConfig{})
+		consumer.metric = metric
+		consumer.createChannel = func(ctx context.Context) (amqpChannel, error) {
+			return nil, stacktrace.NewError("connection refused")
+		}
+
+		err := consumer.Run(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, []ConsumerState{ConsumerDown}, metric.States())
+	})
+}
+
+func TestConsumerState_String(t *testing.T) {
+	assert.Equal(t, "down", ConsumerDown.String())
+	assert.Equal(t, "connected", ConsumerConnected.String())
+	assert.Equal(t, "consuming", ConsumerConsuming.String())
+	assert.Equal(t, "paused", ConsumerPaused.String())
+	assert.Equal(t, "unknown", ConsumerState(42).String())
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_Run(t *testing.T) {
+	t.Run("it processes the deliveries until the context is canceled", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+		var bodies []string
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			bodies = append(bodies, string(msg.Body))
+			if len(bodies) == 2 {
+				cancel()
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{PrefetchCount: 2})
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"foo", "bar"}, bodies)
+		assert.Equal(t, []uint64{1, 2}, ack.Acks())
+	})
+
+	t.Run("when the context is canceled before it consumes, it returns once the consumer is stopped", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		consumer, client := newTestConsumer(newFakeHandler(ackAll), channel, ConsumerConfig{})
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, 0, channel.ConsumeCalls())
+		assert.True(t, channel.IsClosed())
+		assert.Equal(t, 1, client.CloseCount())
+	})
+
+	t.Run("when the handler fails, it returns once the consumer is stopped", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			return HandlerAcknowledgement{}, assert.AnError
+		})
+		consumer, client := newTestConsumer(handler, channel, ConsumerConfig{})
+
+		channel.deliver(ack, 1, "foo")
+
+		err := consumer.Run(context.Background())
+		assert.Equal(t, assert.AnError, stacktrace.RootCause(err))
+		assert.True(t, channel.IsClosed())
+		assert.Equal(t, 1, client.CloseCount())
+	})
+
+	t.Run("when the handler warm-up fails, it does not consume and returns the warm-up error", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(0)
+		handler := &fakeWarmupHandler{
+			fakeHandler: newFakeHandler(ackAll),
+			warmupErr:   assert.AnError,
+		}
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{})
+
+		err := consumer.Run(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, assert.AnError, stacktrace.RootCause(err))
+		assert.Equal(t, 1, handler.warmupCount)
+		assert.Equal(t, 0, channel.ConsumeCalls())
+	})
+
+	t.Run("it warms up the handler before it starts consuming", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := &fakeWarmupHandler{}
+		handler.fakeHandler = newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			assert.Equal(t, 1, handler.warmupCount)
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{})
+
+		channel.deliver(ack, 1, "foo")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+}
+
+func TestWithOnChannelError(t *testing.T) {
+	t.Run("when the channel is closed with an error, it invokes the callback and stops with the error", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(0)
+		notified := make(chan *amqp.Error, 1)
+		consumer, _ := newTestConsumer(
+			newFakeHandler(ackAll),
+			channel,
+			ConsumerConfig{},
+			WithOnChannelError(func(err *amqp.Error) {
+				notified <- err
+			}),
+		)
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(context.Background())
+		}()
+
+		for channel.ConsumeCalls() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		closeErr := &amqp.Error{Code: amqp.ConnectionForced, Reason: "shutdown", Server: true}
+		channel.closeWithError(closeErr)
+
+		assert.Equal(t, closeErr, <-notified)
+		assert.Equal(t, closeErr, stacktrace.RootCause(<-runErr))
+	})
+}
+
+func TestConsumer_SetPrefetch(t *testing.T) {
+	t.Run("it applies the new prefetch count to the live channel", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(0)
+		consumer, _ := newTestConsumer(newFakeHandler(ackAll), channel, ConsumerConfig{PrefetchCount: 10})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		for channel.ConsumeCalls() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		err := consumer.SetPrefetch(25)
+		require.NoError(t, err)
+		assert.Equal(t, []int{10, 25}, channel.QosCalls())
+
+		cancel()
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(<-runErr))
+	})
+
+	t.Run("before the consumer runs, it stores the new prefetch count for the channel", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(0)
+		consumer, _ := newTestConsumer(newFakeHandler(ackAll), channel, ConsumerConfig{PrefetchCount: 10})
+
+		err := consumer.SetPrefetch(5)
+		require.NoError(t, err)
+
+		assert.Empty(t, channel.QosCalls())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		for channel.ConsumeCalls() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		assert.Equal(t, []int{5}, channel.QosCalls())
+
+		cancel()
+		<-runErr
+	})
+
+	t.Run("with PrefetchGlobal, it applies the prefetch count to the whole channel", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(0)
+		consumer, _ := newTestConsumer(
+			newFakeHandler(ackAll),
+			channel,
+			ConsumerConfig{PrefetchCount: 10, PrefetchGlobal: true},
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		for channel.ConsumeCalls() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		err := consumer.SetPrefetch(25)
+		require.NoError(t, err)
+		assert.Equal(t, []int{10, 25}, channel.QosCalls())
+		assert.Equal(t, []bool{true, true}, channel.QosGlobalCalls())
+
+		cancel()
+		<-runErr
+	})
+
+	t.Run("it rejects a negative prefetch count", func(t *testing.T) {
+		t.Parallel()
+
+		consumer, _ := newTestConsumer(newFakeHandler(ackAll), newFakeChannel(0), ConsumerConfig{})
+
+		err := consumer.SetPrefetch(-1)
+		assert.Error(t, err)
+	})
+}
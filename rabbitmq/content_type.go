@@ -0,0 +1,73 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// WithExpectedContentType makes the consumer reject without requeue the deliveries whose ContentType
+// property is not contentType, before they are passed to the handler, e.g. to guard against mis-routed messages.
+//
+// The rejected deliveries are logged with their content type.
+// With WithBatch(), they are rejected before the batch is passed to the handler, so the handler receives
+// only the deliveries with the expected content type.
+func WithExpectedContentType(contentType string) ConsumerOption {
+	return func(c *Consumer) {
+		c.expectedContentType = contentType
+	}
+}
+
+func (c *Consumer) hasExpectedContentType(d *amqp.Delivery) bool {
+	return c.expectedContentType == "" || d.ContentType == c.expectedContentType
+}
+
+func (c *Consumer) rejectContentType(d *amqp.Delivery) HandlerAcknowledgement {
+	c.logger.Warn(
+		"RMQ delivery has unexpected content type, rejecting it",
+		logger.String("content_type", d.ContentType),
+		logger.String("expected_content_type", c.expectedContentType),
+		tracingField(d.CorrelationId),
+		c.processingIDField(d),
+	)
+
+	return HandlerAcknowledgement{Acknowledgement: Reject}
+}
+
+// rejectContentTypes rejects the deliveries of the batch with unexpected content type,
+// and returns the rest of them.
+func (c *Consumer) rejectContentTypes(batch []amqp.Delivery) ([]amqp.Delivery, error) {
+	if c.expectedContentType == "" {
+		return batch, nil
+	}
+
+	accepted := make([]amqp.Delivery, 0, len(batch))
+	for i := range batch {
+		if c.hasExpectedContentType(&batch[i]) {
+			accepted = append(accepted, batch[i])
+
+			continue
+		}
+
+		err := c.acknowledge(&batch[i], c.rejectContentType(&batch[i]), 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return accepted, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithExpectedContentType(t *testing.T) {
+	t.Run("it rejects the deliveries with unexpected content type before the handler runs", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var received []string
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			received = append(received, string(msg.Body))
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithExpectedContentType("application/json"))
+		capturingLog := newCapturingLogger()
+		consumer.logger = capturingLog
+
+		channel.deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, ContentType: "text/plain", Body: []byte("foo")}
+		channel.deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 2, ContentType: "application/json", Body: []byte("{}")}
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, []string{"{}"}, received)
+		assert.Equal(t, []uint64{1}, ack.Rejects())
+		assert.Empty(t, ack.Requeued())
+		assert.Equal(t, []uint64{2}, ack.Acks())
+
+		entries := capturingLog.logs.FilterMessage("RMQ delivery has unexpected content type, rejecting it").All()
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, "text/plain", entries[0].ContextMap()["content_type"])
+		}
+	})
+
+	t.Run("it passes only the deliveries with the expected content type to the batch handler", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := &fakeBatchHandler{
+			fakeHandler: newFakeHandler(ackAll),
+			receiveBatch: func(ctx context.Context, deliveries []amqp.Delivery) ([]HandlerAcknowledgement, error) {
+				cancel()
+
+				return []HandlerAcknowledgement{{Acknowledgement: Ack}}, nil
+			},
+		}
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{PrefetchCount: 2},
+			WithBatch(2, time.Hour),
+			WithExpectedContentType("application/json"),
+		)
+
+		channel.deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, ContentType: "application/json", Body: []byte("{}")}
+		channel.deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 2, Body: []byte("bar")}
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, [][]string{{"{}"}}, handler.Batches())
+		assert.Equal(t, []uint64{2}, ack.Rejects())
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// DeathHeader is the message header in which the broker records the dead-lettering of a message.
+const DeathHeader = "x-death"
+
+// WithDeadLetterRetry enables the retries of the failed messages through the dead letter exchange
+// of the queue, before they are dead-lettered to the dead letter queue.
+//
+// When the handler returns Nack or Reject with Requeue, the consumer nacks the message without requeue,
+// so the broker dead-letters it to the retry queue, which dead-letters it back to the queue once it
+// waited there for delay. The attempts are counted by the broker in the DeathHeader header.
+// Once the message failed maxAttempts times, or when the handler returns Nack or Reject without Requeue,
+// the consumer re-publishes the message to the dead letter queue and acks the original one.
+//
+// The delay is the same for all the attempts, since it is the message TTL of the retry queue.
+// Use WithRetryLadder() instead for delays growing with the attempts.
+//
+// The queue, the retry queue and the dead letter queue must be declared in advance, see DeadLetterRetrySetup().
+func WithDeadLetterRetry(maxAttempts int, delay time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.deadLetterMaxAttempts = maxAttempts
+		c.deadLetterDelay = delay
+	}
+}
+
+// DeadLetterRetryQueueName returns the name of the retry queue used for the provided queue by WithDeadLetterRetry().
+func DeadLetterRetryQueueName(queue string) string {
+	return fmt.Sprintf("%s.retry", queue)
+}
+
+// DeadLetterQueueName returns the name of the dead letter queue used for the provided queue by WithDeadLetterRetry().
+func DeadLetterQueueName(queue string) string {
+	return fmt.Sprintf("%s.dead", queue)
+}
+
+// DeadLetterRetrySetup returns the setup declaring the queues used by WithDeadLetterRetry().
+//
+// The queue dead-letters the messages nacked without requeue to the retry queue through the default exchange.
+// The retry queue has a message TTL equal to delay and dead-letters the expired messages back to the queue.
+// The dead letter queue holds the messages once their attempts are exhausted.
+func DeadLetterRetrySetup(queue string, delay time.Duration) *Setup {
+	return &Setup{
+		Queues: []QueueConfig{
+			{
+				Name:    queue,
+				Durable: true,
+				Args: amqp.Table{
+					"x-dead-letter-exchange":    "",
+					"x-dead-letter-routing-key": DeadLetterRetryQueueName(queue),
+				},
+			},
+			{
+				Name:    DeadLetterRetryQueueName(queue),
+				Durable: true,
+				Args: amqp.Table{
+					"x-message-ttl":             delay.Milliseconds(),
+					"x-dead-letter-exchange":    "",
+					"x-dead-letter-routing-key": queue,
+				},
+			},
+			{
+				Name:    DeadLetterQueueName(queue),
+				Durable: true,
+			},
+		},
+	}
+}
+
+// deathCount returns how many times the delivery was dead-lettered by the broker from the queue.
+func deathCount(d *amqp.Delivery, queue string) int {
+	deaths, _ := d.Headers[DeathHeader].([]interface{})
+	for _, death := range deaths {
+		table, ok := death.(amqp.Table)
+		if !ok || table["queue"] != queue || table["reason"] != "rejected" {
+			continue
+		}
+
+		return intHeader(table, "count")
+	}
+
+	return 0
+}
+
+// retryOrDeadLetter returns how the failed delivery must be acknowledged to be retried through
+// the retry queue, and re-publishes it to the dead letter queue once it must not be retried anymore.
+func (c *Consumer) retryOrDeadLetter(d *amqp.Delivery, acknowledgement HandlerAcknowledgement) HandlerAcknowledgement {
+	queue := c.handler.GetQueueName()
+	attempt := deathCount(d, queue) + 1
+
+	if acknowledgement.Requeue && attempt < c.deadLetterMaxAttempts {
+		c.logger.Info(
+			"RMQ message failed, retrying it through the retry queue",
+			logger.Int("attempt", attempt),
+			logger.Duration("delay", c.deadLetterDelay),
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
+
+		return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: false}
+	}
+
+	c.logger.Warn(
+		"RMQ message failed, dead-lettering it",
+		logger.Int("attempt", attempt),
+		tracingField(d.CorrelationId),
+		c.processingIDField(d),
+	)
+
+	err := c.republish("", DeadLetterQueueName(queue), republishing(d, d.Headers))
+	c.metric.ObserveMsgPublish(err == nil)
+
+	if err != nil {
+		c.logger.Error(
+			"failed to re-publish RMQ message to the dead letter queue, requeueing it",
+			logger.ErrorField(err),
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
+
+		return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}
+	}
+
+	return HandlerAcknowledgement{Acknowledgement: Ack}
+}
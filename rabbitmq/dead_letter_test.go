@@ -0,0 +1,140 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterRetrySetup(t *testing.T) {
+	t.Run("it declares the queue dead-lettering to the retry queue dead-lettering back to it", func(t *testing.T) {
+		t.Parallel()
+
+		setup := DeadLetterRetrySetup("orders", 5*time.Second)
+
+		require.Len(t, setup.Queues, 3)
+
+		assert.Equal(t, "orders", setup.Queues[0].Name)
+		assert.Equal(
+			t,
+			amqp.Table{
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": "orders.retry",
+			},
+			setup.Queues[0].Args,
+		)
+
+		assert.Equal(t, "orders.retry", setup.Queues[1].Name)
+		assert.Equal(
+			t,
+			amqp.Table{
+				"x-message-ttl":             int64(5000),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": "orders",
+			},
+			setup.Queues[1].Args,
+		)
+
+		assert.Equal(t, "orders.dead", setup.Queues[2].Name)
+	})
+}
+
+func TestWithDeadLetterRetry(t *testing.T) {
+	t.Run("it retries the failed messages until the attempts are exhausted, then dead-letters them", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := 0
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			received++
+			if received == 3 {
+				cancel()
+
+				return HandlerAcknowledgement{Acknowledgement: Reject, Requeue: false}, nil
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithDeadLetterRetry(3, 5*time.Second))
+
+		deaths := func(count int64) amqp.Table {
+			return amqp.Table{
+				DeathHeader: []interface{}{
+					amqp.Table{"queue": "test-queue.retry", "reason": "expired", "count": count},
+					amqp.Table{"queue": "test-queue", "reason": "rejected", "count": count},
+				},
+			}
+		}
+
+		channel.deliverWithHeaders(ack, 1, "first", deaths(1))
+		channel.deliverWithHeaders(ack, 2, "exhausted", deaths(2))
+		channel.deliverWithHeaders(ack, 3, "rejected", nil)
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, []uint64{1}, ack.Nacks())
+		assert.Empty(t, ack.Requeued())
+		assert.Equal(t, []uint64{2, 3}, ack.Acks())
+
+		published := channel.Published()
+		require.Len(t, published, 2)
+
+		assert.Equal(t, "", published[0].exchange)
+		assert.Equal(t, "test-queue.dead", published[0].key)
+		assert.Equal(t, "exhausted", string(published[0].msg.Body))
+		assert.Equal(t, deaths(2), published[0].msg.Headers)
+
+		assert.Equal(t, "test-queue.dead", published[1].key)
+		assert.Equal(t, "rejected", string(published[1].msg.Body))
+	})
+
+	t.Run("when the re-publish fails, it requeues the message", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		channel.publishErr = amqp.ErrClosed
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Reject}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithDeadLetterRetry(3, 5*time.Second))
+
+		channel.deliver(ack, 1, "foo")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []uint64{1}, ack.Nacks())
+		assert.Equal(t, []uint64{1}, ack.Requeued())
+	})
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"sync"
+	"time"
+)
+
+// DeliveryRecord is the record of a processed delivery, see WithDebugRingBuffer().
+type DeliveryRecord struct {
+	ProcessingReport
+	// ProcessedAt is the time when the delivery was processed.
+	ProcessedAt time.Time
+}
+
+// WithDebugRingBuffer keeps the records of the last n processed deliveries in memory,
+// see Consumer.RecentDeliveries(), e.g. to debug post-mortem without logging every delivery.
+//
+// The records hold the same metadata and outcome as the processing reports, see WithProcessingReport().
+func WithDebugRingBuffer(n int) ConsumerOption {
+	return func(c *Consumer) {
+		if n > 0 {
+			c.debugRing = &deliveryRing{records: make([]DeliveryRecord, n)}
+		}
+	}
+}
+
+// RecentDeliveries returns the records of the last processed deliveries, from the oldest to the newest.
+// It is empty unless the consumer is created with WithDebugRingBuffer().
+func (c *Consumer) RecentDeliveries() []DeliveryRecord {
+	if c.debugRing == nil {
+		return nil
+	}
+
+	return c.debugRing.all()
+}
+
+// deliveryRing keeps the records of the last processed deliveries.
+type deliveryRing struct {
+	// mu protects the properties of the ring
+	mu      sync.Mutex
+	records []DeliveryRecord
+	next    int
+	full    bool
+}
+
+func (r *deliveryRing) add(record DeliveryRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[r.next] = record
+
+	r.next++
+	if r.next == len(r.records) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+func (r *deliveryRing) all() []DeliveryRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]DeliveryRecord(nil), r.records[:r.next]...)
+	}
+
+	records := make([]DeliveryRecord, 0, len(r.records))
+	records = append(records, r.records[r.next:]...)
+
+	return append(records, r.records[:r.next]...)
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDebugRingBuffer(t *testing.T) {
+	t.Run("it keeps only the records of the last n processed deliveries", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(5)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := 0
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			received++
+			if received == 5 {
+				cancel()
+
+				return HandlerAcknowledgement{Acknowledgement: Reject}, nil
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithDebugRingBuffer(3))
+
+		assert.Empty(t, consumer.RecentDeliveries())
+
+		for tag := uint64(1); tag <= 5; tag++ {
+			channel.deliver(ack, tag, "foo")
+		}
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		records := consumer.RecentDeliveries()
+		require.Len(t, records, 3)
+
+		for i, record := range records {
+			assert.Equal(t, uint64(i+3), record.DeliveryTag)
+			assert.True(t, record.Acknowledged)
+			assert.False(t, record.ProcessedAt.IsZero())
+		}
+
+		assert.Equal(t, Ack, records[1].Acknowledgement.Acknowledgement)
+		assert.Equal(t, Reject, records[2].Acknowledgement.Acknowledgement)
+	})
+
+	t.Run("it keeps fewer records until the buffer is full", func(t *testing.T) {
+		t.Parallel()
+
+		ring := &deliveryRing{records: make([]DeliveryRecord, 3)}
+		ring.add(DeliveryRecord{ProcessingReport: ProcessingReport{DeliveryTag: 1}})
+		ring.add(DeliveryRecord{ProcessingReport: ProcessingReport{DeliveryTag: 2}})
+
+		records := ring.all()
+		require.Len(t, records, 2)
+		assert.Equal(t, uint64(1), records[0].DeliveryTag)
+		assert.Equal(t, uint64(2), records[1].DeliveryTag)
+	})
+
+	t.Run("without the option, it keeps no records", func(t *testing.T) {
+		t.Parallel()
+
+		consumer, _ := newTestConsumer(newFakeHandler(ackAll), newFakeChannel(0), ConsumerConfig{})
+		assert.Nil(t, consumer.RecentDeliveries())
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+// DeliveryContextFunc derives the context passed to the handler from a delivery,
+// e.g. to extract the tracing information or other metadata from the delivery headers.
+type DeliveryContextFunc func(ctx context.Context, d *amqp.Delivery) context.Context
+
+// WithDeliveryContext registers functions deriving the context passed to the handler for every delivery.
+// The functions are applied in the order they are provided.
+//
+// NOTE: The functions are not applied to the batches consumed with WithBatch(),
+// since a batch is not bound to a single delivery.
+func WithDeliveryContext(fns ...DeliveryContextFunc) ConsumerOption {
+	return func(c *Consumer) {
+		c.deliveryContextFuncs = append(c.deliveryContextFuncs, fns...)
+	}
+}
+
+func (c *Consumer) deliveryContext(ctx context.Context, d *amqp.Delivery) context.Context {
+	for _, fn := range c.deliveryContextFuncs {
+		ctx = fn(ctx, d)
+	}
+
+	return ctx
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+type deliveryContextKey string
+
+func TestWithDeliveryContext(t *testing.T) {
+	t.Run("it passes the context derived from the delivery to the handler", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var tenant, source interface{}
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			tenant = ctx.Value(deliveryContextKey("tenant"))
+			source = ctx.Value(deliveryContextKey("source"))
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithDeliveryContext(
+				func(ctx context.Context, d *amqp.Delivery) context.Context {
+					return context.WithValue(ctx, deliveryContextKey("tenant"), d.Headers["tenant"])
+				},
+				func(ctx context.Context, d *amqp.Delivery) context.Context {
+					return context.WithValue(ctx, deliveryContextKey("source"), "rmq")
+				},
+			),
+		)
+
+		channel.deliverWithHeaders(ack, 1, "foo", amqp.Table{"tenant": "acme"})
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, "acme", tenant)
+		assert.Equal(t, "rmq", source)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+}
@@ -0,0 +1,64 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"sync/atomic"
+
+	"github.com/streadway/amqp"
+)
+
+// DeliveryCountHeader is the header in which quorum queues report how many times a message was delivered before.
+//
+// Once it reaches the x-delivery-limit of the queue, the broker dead-letters the message
+// instead of delivering it again.
+const DeliveryCountHeader = "x-delivery-count"
+
+// DeliveryCountMetric is an optional interface implemented by metrics observing the delivery count
+// of the consumed messages, see DeliveryCountHeader.
+type DeliveryCountMetric interface {
+	ObserveDeliveryCount(count int)
+}
+
+// DeliveryCount returns how many times the delivery was delivered before, as reported by quorum queues.
+// It is 0 for the first delivery, and for the queues which do not report the delivery count.
+func DeliveryCount(d *amqp.Delivery) int {
+	return intHeader(d.Headers, DeliveryCountHeader)
+}
+
+func (c *Consumer) observeDelivery(d *amqp.Delivery) {
+	c.metric.ObserveMsgDelivered()
+	atomic.AddInt64(&c.stats.consumed, 1)
+
+	if metric, ok := c.metric.(DeliveryCountMetric); ok {
+		metric.ObserveDeliveryCount(DeliveryCount(d))
+	}
+}
+
+// intHeader returns the value of an integer header, or 0 if there is no such header.
+func intHeader(headers amqp.Table, name string) int {
+	switch value := headers[name].(type) {
+	case int:
+		return value
+	case int16:
+		return int(value)
+	case int32:
+		return int(value)
+	case int64:
+		return int(value)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+type deliveryCountMetric struct {
+	NullMetric
+
+	mu     sync.Mutex
+	counts []int
+}
+
+func (m *deliveryCountMetric) ObserveDeliveryCount(count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.counts = append(m.counts, count)
+}
+
+func (m *deliveryCountMetric) Counts() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]int(nil), m.counts...)
+}
+
+func TestDeliveryCount(t *testing.T) {
+	t.Run("it passes the delivery count to the handler and the metric", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+		metric := &deliveryCountMetric{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var deliveryCounts []int
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			deliveryCounts = append(deliveryCounts, msg.DeliveryCount)
+			if len(deliveryCounts) == 2 {
+				cancel()
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{})
+		consumer.metric = metric
+
+		channel.deliverWithHeaders(ack, 1, "first", nil)
+		channel.deliverWithHeaders(ack, 2, "redelivered", amqp.Table{DeliveryCountHeader: int64(3)})
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []int{0, 3}, deliveryCounts)
+		assert.Equal(t, []int{0, 3}, metric.Counts())
+	})
+}
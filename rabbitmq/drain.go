@@ -0,0 +1,156 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// WithDrainTimeout bounds how long the consumer waits for the in-flight deliveries to be processed
+// when it stops, see Handler.WaitToConsumeInflight().
+//
+// Once the timeout passes, the consumer closes the channel anyway, so the deliveries still being processed
+// can no longer be acknowledged and they are redelivered by the broker.
+// It also bounds the wait for the re-publishes pending a confirmation, see WithPublisherConfirms().
+// And the processing of the auto-acked deliveries when the consumer stops, see WithAutoAckShutdownPolicy().
+// A timeout <= 0 means the consumer waits indefinitely, which is the default.
+func WithDrainTimeout(timeout time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.drainTimeout = timeout
+	}
+}
+
+// drainGroup counts the pending operations, e.g. the in-flight deliveries, so the consumer can wait until
+// they are drained.
+//
+// Unlike sync.WaitGroup, it is waited for with a channel, so the wait can be abandoned once the drain timeout
+// passes without leaking a goroutine, and it is safe to reuse at any time.
+type drainGroup struct {
+	mu      sync.Mutex
+	pending int
+	// drained is closed once no operation is pending, nil if none was added yet
+	drained chan struct{}
+}
+
+func (d *drainGroup) add() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.pending == 0 {
+		d.drained = make(chan struct{})
+	}
+	d.pending++
+}
+
+func (d *drainGroup) done() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pending--
+	if d.pending == 0 {
+		close(d.drained)
+	}
+}
+
+// wait returns a channel closed once no operation is pending.
+func (d *drainGroup) wait() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.drained == nil {
+		d.drained = make(chan struct{})
+		close(d.drained)
+	}
+
+	return d.drained
+}
+
+// startInflight registers a received delivery as in-flight, so the consumer waits for it to be
+// acknowledged before closing the channel when it stops, see Handler.WaitToConsumeInflight().
+// The delivery is done once the returned drainGroup is.
+//
+// It returns nil once the consumer is stopping, and then the delivery must not be processed,
+// since it may be acknowledged only after the channel closes. The broker redelivers it instead.
+// The auto-acked deliveries are always processed, see WithAutoAckShutdownPolicy().
+func (c *Consumer) startInflight() *drainGroup {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	if c.inflightStopped && !c.handler.QueueAutoAck() {
+		return nil
+	}
+
+	c.inflight.add()
+
+	return c.inflight
+}
+
+// stopInflight makes the consumer stop processing the received deliveries, see startInflight().
+func (c *Consumer) stopInflight() {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	c.inflightStopped = true
+}
+
+// resetInflight lets the consumer process the deliveries again, once it runs again after stopping.
+//
+// NOTE: Every run tracks its own in-flight deliveries, so the ones of a previous run abandoned
+// after the drain timeout do not delay the stop of this one.
+func (c *Consumer) resetInflight() {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	c.inflightStopped = false
+	c.inflight = &drainGroup{}
+}
+
+// currentInflight returns the in-flight deliveries of the current run.
+func (c *Consumer) currentInflight() *drainGroup {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+
+	return c.inflight
+}
+
+// waitInflight waits until the in-flight deliveries are processed, or the drain timeout passes.
+func (c *Consumer) waitInflight() {
+	c.waitDrained(c.currentInflight(), "RMQ consumer drain timeout exceeded, closing the channel with in-flight deliveries")
+}
+
+// waitDrained waits for the group to drain, or until the drain timeout passes, logging timeoutMsg in the latter case.
+func (c *Consumer) waitDrained(group *drainGroup, timeoutMsg string) {
+	if c.drainTimeout <= 0 {
+		<-group.wait()
+
+		return
+	}
+
+	timer := c.clock.NewTimer(c.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-group.wait():
+	case <-timer.C():
+		c.logger.Warn(
+			timeoutMsg,
+			logger.String("queue", c.handler.GetQueueName()),
+			logger.Duration("drain_timeout", c.drainTimeout),
+		)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithDrainTimeout(t *testing.T) {
+	t.Run("it closes the channel once the drain timeout passes", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := make(chan struct{})
+		release := make(chan struct{})
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			close(received)
+			<-release
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithClock(clock),
+			WithDrainTimeout(time.Minute),
+		)
+
+		channel.deliver(ack, 1, "slow")
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		<-received
+		cancel()
+
+		clock.BlockUntil(1)
+		select {
+		case <-channel.Closed():
+			t.Fatal("the channel must not be closed before the drain timeout")
+		default:
+		}
+
+		clock.Advance(time.Minute)
+		<-channel.Closed()
+
+		close(release)
+		assert.Error(t, <-runErr)
+	})
+
+	t.Run("it closes the channel as soon as the in-flight deliveries are processed", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithClock(clock),
+			WithDrainTimeout(time.Minute),
+		)
+
+		channel.deliver(ack, 1, "fast")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		<-channel.Closed()
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+}
+
+func TestDrainGroup(t *testing.T) {
+	t.Run("it is drained once every added operation is done", func(t *testing.T) {
+		t.Parallel()
+
+		group := &drainGroup{}
+
+		select {
+		case <-group.wait():
+		default:
+			t.Fatal("the group without operations must be drained")
+		}
+
+		group.add()
+		group.add()
+		group.done()
+
+		drained := group.wait()
+		select {
+		case <-drained:
+			t.Fatal("the group must not be drained while an operation is pending")
+		default:
+		}
+
+		group.done()
+		<-drained
+	})
+
+	t.Run("it can be reused once it is drained", func(t *testing.T) {
+		t.Parallel()
+
+		group := &drainGroup{}
+
+		group.add()
+		group.done()
+		<-group.wait()
+
+		group.add()
+		select {
+		case <-group.wait():
+			t.Fatal("the reused group must not be drained while an operation is pending")
+		default:
+		}
+
+		group.done()
+		<-group.wait()
+	})
+}
+
+// closeCheckingAcknowledger records whether the channel is closed when a delivery is acked.
+type closeCheckingAcknowledger struct {
+	*fakeAcknowledger
+	channel *fakeChannel
+
+	mu            sync.Mutex
+	ackedOnClosed bool
+}
+
+func (a *closeCheckingAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	a.ackedOnClosed = a.ackedOnClosed || a.channel.IsClosed()
+	a.mu.Unlock()
+
+	return a.fakeAcknowledger.Ack(tag, multiple)
+}
+
+func TestConsumer_Shutdown(t *testing.T) {
+	t.Run("it acks the delivery of a slow handler before closing the channel", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &closeCheckingAcknowledger{fakeAcknowledger: &fakeAcknowledger{}, channel: channel}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := make(chan struct{})
+		release := make(chan struct{})
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			close(received)
+			<-release
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{})
+
+		channel.deliver(ack, 1, "slow")
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		<-received
+		cancel()
+
+		assert.Never(t, channel.IsClosed, 50*time.Millisecond, time.Millisecond)
+
+		close(release)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(<-runErr))
+		assert.True(t, channel.IsClosed())
+		assert.Equal(t, []uint64{1}, ack.Acks())
+		assert.False(t, ack.ackedOnClosed)
+	})
+
+	t.Run("it does not process the deliveries received once it is stopping", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		var called bool
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			called = true
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{})
+		consumer.stopInflight()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		channel.deliver(ack, 1, "late")
+
+		err := consumer.handleDeliveries(ctx, channel.deliveries)
+		assert.Equal(t, context.Canceled, err)
+		assert.False(t, called)
+		assert.Empty(t, ack.Acks())
+		assert.Empty(t, ack.Nacks())
+	})
+}
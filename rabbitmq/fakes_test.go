@@ -0,0 +1,508 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+type fakeClient struct {
+	mu         sync.Mutex
+	closeCount int
+	setups     []*Setup
+}
+
+func (c *fakeClient) CreateChannel(ctx context.Context) (*amqp.Channel, error) {
+	panic("the consumer tests must use a fakeChannel")
+}
+
+func (c *fakeClient) Setup(ctx context.Context, setup *Setup) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setups = append(c.setups, setup)
+
+	return nil
+}
+
+func (c *fakeClient) Setups() []*Setup {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]*Setup(nil), c.setups...)
+}
+
+func (c *fakeClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeCount++
+
+	return nil
+}
+
+func (c *fakeClient) CloseCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closeCount
+}
+
+type fakeChannel struct {
+	mu         sync.Mutex
+	deliveries chan amqp.Delivery
+	// deliveriesClosed is true once the current deliveries are closed
+	deliveriesClosed bool
+	notifyCancel     []chan string
+	notifyClose      []chan *amqp.Error
+	qosErr           error
+	consumeErr       error
+	qosCalls         []int
+	qosGlobalCalls   []bool
+	consumeCalls     int
+	getCalls         int
+	cancelCalls      int
+	closed           bool
+	closedCh         chan struct{}
+	publishErr       error
+	published        []fakePublishing
+	publishedCh      chan struct{}
+	confirmMode      bool
+	notifyPub        []chan amqp.Confirmation
+	publishTag       uint64
+}
+
+type fakePublishing struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+func newFakeChannel(buffer int) *fakeChannel {
+	return &fakeChannel{
+		deliveries:  make(chan amqp.Delivery, buffer),
+		closedCh:    make(chan struct{}),
+		publishedCh: make(chan struct{}, buffer),
+	}
+}
+
+func (ch *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.qosCalls = append(ch.qosCalls, prefetchCount)
+	ch.qosGlobalCalls = append(ch.qosGlobalCalls, global)
+
+	return ch.qosErr
+}
+
+func (ch *fakeChannel) QosCalls() []int {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return append([]int(nil), ch.qosCalls...)
+}
+
+func (ch *fakeChannel) QosGlobalCalls() []bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return append([]bool(nil), ch.qosGlobalCalls...)
+}
+
+func (ch *fakeChannel) Consume(
+	queue,
+	consumer string,
+	autoAck,
+	exclusive,
+	noLocal,
+	noWait bool,
+	args amqp.Table,
+) (<-chan amqp.Delivery, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.consumeCalls++
+	if ch.consumeErr != nil {
+		return nil, ch.consumeErr
+	}
+
+	return ch.deliveries, nil
+}
+
+// Get returns the next delivery of the fake channel, if any, the same way amqp does once the queue is empty.
+func (ch *fakeChannel) Get(queue string, autoAck bool) (amqp.Delivery, bool, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.getCalls++
+
+	select {
+	case d, ok := <-ch.deliveries:
+		return d, ok, nil
+	default:
+		return amqp.Delivery{}, false, nil
+	}
+}
+
+func (ch *fakeChannel) GetCalls() int {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return ch.getCalls
+}
+
+func (ch *fakeChannel) Cancel(consumer string, noWait bool) error {
+	ch.mu.Lock()
+	ch.cancelCalls++
+	ch.mu.Unlock()
+
+	ch.closeDeliveries()
+
+	return nil
+}
+
+func (ch *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.publishErr != nil {
+		return ch.publishErr
+	}
+
+	ch.published = append(ch.published, fakePublishing{exchange: exchange, key: key, msg: msg})
+	ch.publishTag++
+
+	select {
+	case ch.publishedCh <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (ch *fakeChannel) Confirm(noWait bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.confirmMode = true
+
+	return nil
+}
+
+func (ch *fakeChannel) NotifyPublish(c chan amqp.Confirmation) chan amqp.Confirmation {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.notifyPub = append(ch.notifyPub, c)
+
+	return c
+}
+
+// confirm notifies the NotifyPublish listeners about the confirmation of the last publishing.
+func (ch *fakeChannel) confirm(ack bool) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	for _, listener := range ch.notifyPub {
+		listener <- amqp.Confirmation{DeliveryTag: ch.publishTag, Ack: ack}
+	}
+}
+
+func (ch *fakeChannel) Published() []fakePublishing {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return append([]fakePublishing(nil), ch.published...)
+}
+
+func (ch *fakeChannel) NotifyClose(c chan *amqp.Error) chan *amqp.Error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.notifyClose = append(ch.notifyClose, c)
+
+	return c
+}
+
+// closeWithError notifies the NotifyClose listeners, the same way amqp does when the server closes the channel.
+func (ch *fakeChannel) closeWithError(err *amqp.Error) {
+	ch.mu.Lock()
+	listeners := ch.notifyClose
+	ch.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener <- err
+	}
+}
+
+func (ch *fakeChannel) Close() error {
+	ch.mu.Lock()
+	if !ch.closed {
+		ch.closed = true
+		close(ch.closedCh)
+
+		for _, listener := range ch.notifyPub {
+			close(listener)
+		}
+	}
+	ch.mu.Unlock()
+
+	ch.closeDeliveries()
+
+	return nil
+}
+
+func (ch *fakeChannel) IsClosed() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return ch.closed
+}
+
+// Closed returns a channel closed once the fake channel is closed.
+func (ch *fakeChannel) Closed() <-chan struct{} {
+	return ch.closedCh
+}
+
+func (ch *fakeChannel) ConsumeCalls() int {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return ch.consumeCalls
+}
+
+func (ch *fakeChannel) closeDeliveries() {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if !ch.deliveriesClosed {
+		ch.deliveriesClosed = true
+		close(ch.deliveries)
+	}
+}
+
+func (ch *fakeChannel) NotifyCancel(c chan string) chan string {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.notifyCancel = append(ch.notifyCancel, c)
+
+	return c
+}
+
+// cancelByServer cancels the consumer the same way the broker does when its queue is deleted.
+//
+// It notifies the NotifyCancel listeners and closes the deliveries, so the next Consume call gets
+// new deliveries, which are returned.
+func (ch *fakeChannel) cancelByServer(consumerTag string) chan amqp.Delivery {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	for _, listener := range ch.notifyCancel {
+		listener <- consumerTag
+	}
+
+	if !ch.deliveriesClosed {
+		close(ch.deliveries)
+	}
+
+	ch.deliveries = make(chan amqp.Delivery, cap(ch.deliveries))
+	ch.deliveriesClosed = false
+
+	return ch.deliveries
+}
+
+// deliver enqueues a delivery acknowledged through the provided acknowledger.
+func (ch *fakeChannel) deliver(ack amqp.Acknowledger, tag uint64, body string) {
+	ch.mu.Lock()
+	deliveries := ch.deliveries
+	ch.mu.Unlock()
+
+	deliveries <- amqp.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  tag,
+		Body:         []byte(body),
+	}
+}
+
+// deliverWithHeaders enqueues a delivery with headers acknowledged through the provided acknowledger.
+func (ch *fakeChannel) deliverWithHeaders(ack amqp.Acknowledger, tag uint64, body string, headers amqp.Table) {
+	ch.mu.Lock()
+	deliveries := ch.deliveries
+	ch.mu.Unlock()
+
+	deliveries <- amqp.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  tag,
+		Body:         []byte(body),
+		Headers:      headers,
+	}
+}
+
+type fakeAcknowledger struct {
+	mu      sync.Mutex
+	acks    []uint64
+	nacks   []uint64
+	rejects []uint64
+	// multipleAcks records the tags of the deliveries acked with multiple=true
+	multipleAcks []uint64
+	// requeued records the tags of the deliveries nacked or rejected with requeue
+	requeued []uint64
+	err      error
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.acks = append(a.acks, tag)
+	if multiple {
+		a.multipleAcks = append(a.multipleAcks, tag)
+	}
+
+	return a.err
+}
+
+func (a *fakeAcknowledger) MultipleAcks() []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]uint64(nil), a.multipleAcks...)
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.nacks = append(a.nacks, tag)
+	if requeue {
+		a.requeued = append(a.requeued, tag)
+	}
+
+	return a.err
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rejects = append(a.rejects, tag)
+	if requeue {
+		a.requeued = append(a.requeued, tag)
+	}
+
+	return a.err
+}
+
+func (a *fakeAcknowledger) Acks() []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]uint64(nil), a.acks...)
+}
+
+func (a *fakeAcknowledger) Nacks() []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]uint64(nil), a.nacks...)
+}
+
+func (a *fakeAcknowledger) Rejects() []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]uint64(nil), a.rejects...)
+}
+
+func (a *fakeAcknowledger) Requeued() []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]uint64(nil), a.requeued...)
+}
+
+// Ensure that the fake handlers implement the handler interfaces used by the consumer.
+var (
+	_ Handler       = (*fakeHandler)(nil)
+	_ WarmupHandler = (*fakeWarmupHandler)(nil)
+	_ BatchHandler  = (*fakeBatchHandler)(nil)
+	_ JSONHandler   = (*fakeJSONHandler)(nil)
+)
+
+type fakeHandler struct {
+	queueName   string
+	consumerTag string
+	autoAck     bool
+	receive     func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error)
+}
+
+func newFakeHandler(receive func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error)) *fakeHandler {
+	return &fakeHandler{
+		queueName:   "test-queue",
+		consumerTag: "test-consumer",
+		receive:     receive,
+	}
+}
+
+func (h *fakeHandler) GetQueueName() string        { return h.queueName }
+func (h *fakeHandler) GetConsumerTag() string      { return h.consumerTag }
+func (h *fakeHandler) QueueAutoAck() bool          { return h.autoAck }
+func (h *fakeHandler) ExclusiveConsumer() bool     { return false }
+func (h *fakeHandler) MustStopOnAckError() bool    { return false }
+func (h *fakeHandler) MustStopOnNAckError() bool   { return false }
+func (h *fakeHandler) MustStopOnRejectError() bool { return false }
+func (h *fakeHandler) WaitToConsumeInflight() bool { return true }
+func (h *fakeHandler) ReceiveMessage(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+	return h.receive(ctx, msg)
+}
+
+type fakeWarmupHandler struct {
+	*fakeHandler
+	warmupErr   error
+	warmupCount int
+}
+
+func (h *fakeWarmupHandler) Warmup(ctx context.Context) error {
+	h.warmupCount++
+
+	return h.warmupErr
+}
+
+func ackAll(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+	return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+}
+
+// newTestConsumer creates a consumer that uses the provided fake channel.
+func newTestConsumer(
+	handler Handler,
+	channel *fakeChannel,
+	cfg ConsumerConfig,
+	opts ...ConsumerOption,
+) (*Consumer, *fakeClient) {
+	client := &fakeClient{}
+	consumer := NewConsumer(client, handler, logger.NewStructuredNopLogger("info"), &NullMetric{}, cfg, opts...)
+	consumer.createChannel = func(ctx context.Context) (amqpChannel, error) {
+		return channel, nil
+	}
+
+	return consumer, client
+}
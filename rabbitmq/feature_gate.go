@@ -0,0 +1,81 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// WithFeatureGate passes to the handler only the deliveries for which gate returns true,
+// e.g. to roll out a handler gradually based on a header or a percentage of the messages.
+//
+// The gated-out deliveries are acked without being handled, unless another acknowledgement is set
+// with WithFeatureGateAcknowledgement().
+// With WithBatch(), they are acknowledged before the batch is passed to the handler, so the handler receives
+// only the deliveries passing the gate.
+func WithFeatureGate(gate func(d *amqp.Delivery) bool) ConsumerOption {
+	return func(c *Consumer) {
+		c.featureGate = gate
+	}
+}
+
+// WithFeatureGateAcknowledgement sets how the deliveries gated out by WithFeatureGate() are acknowledged,
+// e.g. nacked with requeue so another consumer instance handles them.
+func WithFeatureGateAcknowledgement(acknowledgement HandlerAcknowledgement) ConsumerOption {
+	return func(c *Consumer) {
+		c.featureGatedOut = acknowledgement
+	}
+}
+
+func (c *Consumer) passesFeatureGate(d *amqp.Delivery) bool {
+	return c.featureGate == nil || c.featureGate(d)
+}
+
+func (c *Consumer) skipGatedOut(d *amqp.Delivery) HandlerAcknowledgement {
+	c.logger.Debug(
+		"RMQ delivery gated out, skipping the handler",
+		logger.String("acknowledgement", c.featureGatedOut.Acknowledgement.String()),
+		tracingField(d.CorrelationId),
+		c.processingIDField(d),
+	)
+
+	return c.featureGatedOut
+}
+
+// skipGatedOutBatch acknowledges the deliveries of the batch gated out by the feature gate,
+// and returns the rest of them.
+func (c *Consumer) skipGatedOutBatch(batch []amqp.Delivery) ([]amqp.Delivery, error) {
+	if c.featureGate == nil {
+		return batch, nil
+	}
+
+	passed := make([]amqp.Delivery, 0, len(batch))
+	for i := range batch {
+		if c.passesFeatureGate(&batch[i]) {
+			passed = append(passed, batch[i])
+
+			continue
+		}
+
+		err := c.acknowledge(&batch[i], c.skipGatedOut(&batch[i]), 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return passed, nil
+}
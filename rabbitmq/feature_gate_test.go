@@ -0,0 +1,147 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func gateOnHeader(d *amqp.Delivery) bool {
+	return d.Headers["x-rollout"] == "enabled"
+}
+
+func TestWithFeatureGate(t *testing.T) {
+	t.Run("it acks the gated-out deliveries without passing them to the handler", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var received []string
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			received = append(received, string(msg.Body))
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Reject}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithFeatureGate(gateOnHeader))
+
+		channel.deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, Body: []byte("foo")}
+		channel.deliveries <- amqp.Delivery{
+			Acknowledger: ack,
+			DeliveryTag:  2,
+			Headers:      amqp.Table{"x-rollout": "enabled"},
+			Body:         []byte("bar"),
+		}
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, []string{"bar"}, received)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+		assert.Equal(t, []uint64{2}, ack.Rejects())
+	})
+
+	t.Run("it applies the acknowledgement set for the gated-out deliveries", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var received []string
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			received = append(received, string(msg.Body))
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithFeatureGate(gateOnHeader),
+			WithFeatureGateAcknowledgement(HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}),
+		)
+
+		channel.deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, Body: []byte("foo")}
+		channel.deliveries <- amqp.Delivery{
+			Acknowledger: ack,
+			DeliveryTag:  2,
+			Headers:      amqp.Table{"x-rollout": "enabled"},
+			Body:         []byte("bar"),
+		}
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, []string{"bar"}, received)
+		assert.Equal(t, []uint64{1}, ack.Nacks())
+		assert.Equal(t, []uint64{1}, ack.Requeued())
+		assert.Equal(t, []uint64{2}, ack.Acks())
+	})
+
+	t.Run("it passes only the deliveries passing the gate to the batch handler", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := &fakeBatchHandler{
+			fakeHandler: newFakeHandler(ackAll),
+			receiveBatch: func(ctx context.Context, deliveries []amqp.Delivery) ([]HandlerAcknowledgement, error) {
+				cancel()
+
+				return []HandlerAcknowledgement{{Acknowledgement: Reject}}, nil
+			},
+		}
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{PrefetchCount: 2},
+			WithBatch(2, time.Hour),
+			WithFeatureGate(gateOnHeader),
+		)
+
+		channel.deliveries <- amqp.Delivery{
+			Acknowledger: ack,
+			DeliveryTag:  1,
+			Headers:      amqp.Table{"x-rollout": "enabled"},
+			Body:         []byte("foo"),
+		}
+		channel.deliveries <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 2, Body: []byte("bar")}
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, [][]string{{"foo"}}, handler.Batches())
+		assert.Equal(t, []uint64{2}, ack.Acks())
+		assert.Equal(t, []uint64{1}, ack.Rejects())
+	})
+}
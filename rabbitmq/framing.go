@@ -0,0 +1,136 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// ErrCorruptFrame is returned by a FrameCodec when the body contains a partial frame or a corrupt frame length.
+var ErrCorruptFrame = errors.New("corrupt frame")
+
+// FrameCodec splits the body of a delivery into frames, for the upstreams sending multiple messages
+// within a single AMQP body.
+type FrameCodec interface {
+	Split(body []byte) ([][]byte, error)
+}
+
+// WithFraming makes the consumer split the body of every delivery into frames with codec,
+// and pass every frame to the handler as a separate message.
+//
+// The delivery is acked only if the handler acks all its frames. Otherwise the frames after the first
+// failed one are not processed, and the delivery is acknowledged as the handler requested for the failed frame.
+// The deliveries which cannot be split, e.g. because of a partial frame, are rejected without requeue.
+//
+// NOTE: The framing does not apply to the batches consumed with WithBatch().
+func WithFraming(codec FrameCodec) ConsumerOption {
+	return func(c *Consumer) {
+		c.frameCodec = codec
+	}
+}
+
+// LengthPrefixedCodec is a FrameCodec for frames prefixed with their length, encoded as an unsigned integer
+// of PrefixSize bytes in ByteOrder.
+type LengthPrefixedCodec struct {
+	// PrefixSize is the size of the length prefix in bytes: 1, 2, 4 or 8.
+	PrefixSize int
+	ByteOrder  binary.ByteOrder
+}
+
+// NewLengthPrefixedCodec creates a LengthPrefixedCodec for frames prefixed with their length
+// as a big-endian unsigned integer of prefixSize bytes.
+func NewLengthPrefixedCodec(prefixSize int) *LengthPrefixedCodec {
+	return &LengthPrefixedCodec{
+		PrefixSize: prefixSize,
+		ByteOrder:  binary.BigEndian,
+	}
+}
+
+// Split implements FrameCodec.
+func (codec *LengthPrefixedCodec) Split(body []byte) ([][]byte, error) {
+	var frames [][]byte
+
+	for len(body) > 0 {
+		if len(body) < codec.PrefixSize {
+			return nil, fmt.Errorf("length prefix of %d bytes, got %d: %w", codec.PrefixSize, len(body), ErrCorruptFrame)
+		}
+
+		length, err := codec.frameLength(body[:codec.PrefixSize])
+		if err != nil {
+			return nil, err
+		}
+
+		body = body[codec.PrefixSize:]
+		if length > uint64(len(body)) {
+			return nil, fmt.Errorf("frame length %d exceeds the remaining %d bytes: %w", length, len(body), ErrCorruptFrame)
+		}
+
+		frames = append(frames, body[:length])
+		body = body[length:]
+	}
+
+	return frames, nil
+}
+
+func (codec *LengthPrefixedCodec) frameLength(prefix []byte) (uint64, error) {
+	switch codec.PrefixSize {
+	case 1:
+		return uint64(prefix[0]), nil
+	case 2:
+		return uint64(codec.ByteOrder.Uint16(prefix)), nil
+	case 4:
+		return uint64(codec.ByteOrder.Uint32(prefix)), nil
+	case 8:
+		return codec.ByteOrder.Uint64(prefix), nil
+	default:
+		return 0, fmt.Errorf("unsupported length prefix size %d", codec.PrefixSize)
+	}
+}
+
+// receiveFrames passes every frame of the delivery to the handler,
+// and returns how the delivery must be acknowledged.
+func (c *Consumer) receiveFrames(ctx context.Context, d *amqp.Delivery) (HandlerAcknowledgement, error) {
+	frames, err := c.frameCodec.Split(d.Body)
+	if err != nil {
+		c.logger.Warn(
+			"failed to split RMQ message into frames",
+			logger.ErrorField(err),
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
+
+		return HandlerAcknowledgement{Acknowledgement: Reject, Requeue: false}, nil
+	}
+
+	for _, frame := range frames {
+		acknowledgement, err := c.receive(ctx, d, frame)
+		if err != nil {
+			return acknowledgement, err
+		}
+
+		if acknowledgement.Acknowledgement != Ack {
+			return acknowledgement, nil
+		}
+	}
+
+	return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLengthPrefixedCodec_Split(t *testing.T) {
+	t.Run("it splits the body into frames", func(t *testing.T) {
+		t.Parallel()
+
+		frames, err := NewLengthPrefixedCodec(2).Split([]byte("\x00\x03foo\x00\x00\x00\x06barbaz"))
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("foo"), {}, []byte("barbaz")}, frames)
+	})
+
+	t.Run("it supports the little-endian length prefixes", func(t *testing.T) {
+		t.Parallel()
+
+		codec := &LengthPrefixedCodec{PrefixSize: 4, ByteOrder: binary.LittleEndian}
+
+		frames, err := codec.Split([]byte("\x03\x00\x00\x00foo"))
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("foo")}, frames)
+	})
+
+	t.Run("it returns an error for a partial length prefix", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewLengthPrefixedCodec(4).Split([]byte("\x00\x00\x00\x03foo\x00\x00"))
+		assert.True(t, errors.Is(err, ErrCorruptFrame))
+	})
+
+	t.Run("it returns an error when the length exceeds the body", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewLengthPrefixedCodec(1).Split([]byte("\x05foo"))
+		assert.True(t, errors.Is(err, ErrCorruptFrame))
+	})
+}
+
+func TestWithFraming(t *testing.T) {
+	newFramesHandler := func(cancel context.CancelFunc, frames *[]string) *fakeHandler {
+		return newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			*frames = append(*frames, string(msg.Body))
+			cancel()
+
+			if string(msg.Body) == "bad" {
+				return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+	}
+
+	t.Run("it passes every frame to the handler and acks the delivery", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var frames []string
+		consumer, _ := newTestConsumer(
+			newFramesHandler(cancel, &frames),
+			channel,
+			ConsumerConfig{},
+			WithFraming(NewLengthPrefixedCodec(1)),
+		)
+
+		channel.deliver(ack, 1, "\x03foo\x03bar\x03baz")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"foo", "bar", "baz"}, frames)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+
+	t.Run("when a frame fails, it stops and acknowledges the delivery as requested for the frame", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var frames []string
+		consumer, _ := newTestConsumer(
+			newFramesHandler(cancel, &frames),
+			channel,
+			ConsumerConfig{},
+			WithFraming(NewLengthPrefixedCodec(1)),
+		)
+
+		channel.deliver(ack, 1, "\x03foo\x03bad\x03baz")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"foo", "bad"}, frames)
+		assert.Empty(t, ack.Acks())
+		assert.Equal(t, []uint64{1}, ack.Nacks())
+	})
+
+	t.Run("it rejects the delivery with a corrupt frame", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var frames []string
+		consumer, _ := newTestConsumer(
+			newFramesHandler(cancel, &frames),
+			channel,
+			ConsumerConfig{},
+			WithFraming(NewLengthPrefixedCodec(1)),
+		)
+
+		channel.deliver(ack, 1, "\x03foo\x09bar")
+		channel.deliver(ack, 2, "\x02ok")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"ok"}, frames)
+		assert.Equal(t, []uint64{1}, ack.Rejects())
+		assert.Equal(t, []uint64{2}, ack.Acks())
+	})
+}
@@ -30,6 +30,15 @@ type Handler interface {
 	ReceiveMessage(ctx context.Context, msg *Message) (acknowledgement HandlerAcknowledgement, err error)
 }
 
+// WarmupHandler is an optional interface implemented by handlers that need to be initialized
+// before they receive any message, e.g. to open a DB connection or to warm a cache.
+//
+// The consumer calls Warmup once before it starts consuming, and if it fails the consumer
+// does not consume any message and its Run method returns the warm-up error.
+type WarmupHandler interface {
+	Warmup(ctx context.Context) error
+}
+
 type AcknowledgementType int
 
 const (
@@ -38,6 +47,20 @@ const (
 	Reject
 )
 
+// String returns the name of the acknowledgement type: "ack", "nack" or "reject".
+func (t AcknowledgementType) String() string {
+	switch t {
+	case Ack:
+		return "ack"
+	case Nack:
+		return "nack"
+	case Reject:
+		return "reject"
+	default:
+		return "unknown"
+	}
+}
+
 type HandlerAcknowledgement struct {
 	Acknowledgement AcknowledgementType
 	Requeue         bool
@@ -50,4 +73,9 @@ type Message struct {
 
 	// Correlation identifier
 	CorrelationID string
+
+	// DeliveryCount is how many times the message was delivered before, as reported by quorum queues.
+	// It can be used to give up on a message before the broker dead-letters it once it reaches
+	// the x-delivery-limit of the queue. See DeliveryCountHeader.
+	DeliveryCount int
 }
@@ -0,0 +1,87 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"time"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// WithIdleTimeout calls onIdle once the consumer receives no deliveries for timeout,
+// e.g. to monitor the low-traffic queues.
+//
+// The idle period starts when the consumer starts consuming, and restarts once every delivery is processed,
+// so onIdle is called once per idle period and again only after the next delivery.
+// It is not called once the consumer stops.
+// The onIdle function is called from the goroutine receiving the deliveries, so it must not block.
+func WithIdleTimeout(timeout time.Duration, onIdle func()) ConsumerOption {
+	return func(c *Consumer) {
+		c.idleTimeout = timeout
+		c.onIdle = onIdle
+	}
+}
+
+// idleWatch fires once the consumer is idle for the idle timeout.
+type idleWatch struct {
+	clock   task.Clock
+	timeout time.Duration
+	timer   task.Timer
+}
+
+// newIdleWatch starts the idle period, returns nil if the idle timeout is not configured.
+func (c *Consumer) newIdleWatch() *idleWatch {
+	if c.idleTimeout <= 0 || c.onIdle == nil {
+		return nil
+	}
+
+	w := &idleWatch{clock: c.clock, timeout: c.idleTimeout}
+	w.reset()
+
+	return w
+}
+
+// C returns the channel receiving once the idle period passes, nil if it is not running.
+func (w *idleWatch) C() <-chan time.Time {
+	if w == nil || w.timer == nil {
+		return nil
+	}
+
+	return w.timer.C()
+}
+
+// fired stops watching until the next reset.
+func (w *idleWatch) fired() {
+	w.timer = nil
+}
+
+// reset restarts the idle period.
+func (w *idleWatch) reset() {
+	if w == nil {
+		return
+	}
+
+	w.stop()
+	w.timer = w.clock.NewTimer(w.timeout)
+}
+
+func (w *idleWatch) stop() {
+	if w == nil || w.timer == nil {
+		return
+	}
+
+	w.timer.Stop()
+	w.timer = nil
+}
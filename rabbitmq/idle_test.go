@@ -0,0 +1,85 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithIdleTimeout(t *testing.T) {
+	t.Run("it calls onIdle after the idle period and restarts it once a delivery arrives", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		idle := make(chan struct{}, 2)
+		processed := make(chan struct{})
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			processed <- struct{}{}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithClock(clock),
+			WithIdleTimeout(time.Minute, func() {
+				idle <- struct{}{}
+			}),
+		)
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		<-idle
+
+		// NOTE: Once idle, it is not called again until the next delivery.
+		assert.Eventually(t, func() bool {
+			return clock.Waiters() == 0
+		}, time.Second, time.Millisecond)
+		clock.Advance(time.Hour)
+
+		channel.deliver(ack, 1, "foo")
+		<-processed
+
+		clock.BlockUntil(1)
+		clock.Advance(59 * time.Second)
+		assert.Empty(t, idle)
+
+		clock.Advance(time.Second)
+		<-idle
+
+		cancel()
+		assert.Error(t, <-runErr)
+		assert.Empty(t, idle)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+}
@@ -0,0 +1,55 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+// ProcessFunc processes a delivery with the handler, returning how the delivery must be acknowledged.
+type ProcessFunc func(ctx context.Context) (HandlerAcknowledgement, error)
+
+// DeliveryInterceptor wraps the processing of every delivery by the handler,
+// e.g. to run it within a tracing span.
+//
+// It must call next to process the delivery, and return the acknowledgement and error returned by it,
+// possibly changed. The ctx is the one derived with the functions registered by WithDeliveryContext().
+type DeliveryInterceptor func(ctx context.Context, d *amqp.Delivery, next ProcessFunc) (HandlerAcknowledgement, error)
+
+// WithDeliveryInterceptor registers interceptors wrapping the processing of every delivery by the handler.
+// The first interceptor is the outermost one, i.e. it is the first to be called.
+//
+// NOTE: The interceptors are not applied to the batches consumed with WithBatch(),
+// since a batch is not bound to a single delivery.
+func WithDeliveryInterceptor(interceptors ...DeliveryInterceptor) ConsumerOption {
+	return func(c *Consumer) {
+		c.deliveryInterceptors = append(c.deliveryInterceptors, interceptors...)
+	}
+}
+
+// intercept processes the delivery with process wrapped by the interceptors.
+func (c *Consumer) intercept(ctx context.Context, d *amqp.Delivery, process ProcessFunc) (HandlerAcknowledgement, error) {
+	for i := len(c.deliveryInterceptors) - 1; i >= 0; i-- {
+		interceptor := c.deliveryInterceptors[i]
+		next := process
+		process = func(ctx context.Context) (HandlerAcknowledgement, error) {
+			return interceptor(ctx, d, next)
+		}
+	}
+
+	return process(ctx)
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDeliveryInterceptor(t *testing.T) {
+	t.Run("it wraps the handler with the interceptors in order", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var calls []string
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			calls = append(calls, "handler")
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		intercept := func(name string) DeliveryInterceptor {
+			return func(ctx context.Context, d *amqp.Delivery, next ProcessFunc) (HandlerAcknowledgement, error) {
+				calls = append(calls, name+" before")
+				acknowledgement, err := next(ctx)
+				calls = append(calls, name+" after")
+
+				return acknowledgement, err
+			}
+		}
+
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithDeliveryInterceptor(intercept("outer"), intercept("inner")),
+		)
+
+		channel.deliver(ack, 1, "foo")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"outer before", "inner before", "handler", "inner after", "outer after"}, calls)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+
+	t.Run("it applies the acknowledgement returned by the interceptor", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		consumer, _ := newTestConsumer(
+			newFakeHandler(ackAll),
+			channel,
+			ConsumerConfig{},
+			WithDeliveryInterceptor(func(ctx context.Context, d *amqp.Delivery, next ProcessFunc) (HandlerAcknowledgement, error) {
+				defer cancel()
+
+				_, err := next(ctx)
+
+				return HandlerAcknowledgement{Acknowledgement: Reject}, err
+			}),
+		)
+
+		channel.deliver(ack, 1, "foo")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []uint64{1}, ack.Rejects())
+		assert.Empty(t, ack.Acks())
+	})
+}
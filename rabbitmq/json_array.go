@@ -0,0 +1,89 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// WithJSONArrayFanOut makes the consumer decode the body of every delivery as a JSON array,
+// and pass each of its elements to JSONHandler.ReceiveJSON() separately, e.g. for the producers
+// batching multiple events into a single message.
+//
+// The elements are processed in order, each one decoded into a new value returned by JSONHandler.NewJSONValue(),
+// and with a Message whose Body is the element. The delivery is acked once all the elements are acked.
+// When an element is not acked, the rest of them are not processed, and the delivery is acknowledged
+// with onPartialFailure, e.g. nacked with requeue so the whole message is redelivered.
+// NOTE: The elements processed before the failed one are processed again once the message is redelivered.
+//
+// A body which is not a JSON array, or an element which cannot be decoded, is handled
+// by the unmarshal error handler, see WithUnmarshalErrorHandler().
+// The handler must implement the JSONHandler interface.
+func WithJSONArrayFanOut(onPartialFailure HandlerAcknowledgement) ConsumerOption {
+	return func(c *Consumer) {
+		c.jsonArrayFanOut = true
+		c.jsonArrayPartialFailed = onPartialFailure
+	}
+}
+
+func (c *Consumer) receiveJSONArray(
+	ctx context.Context,
+	handler JSONHandler,
+	d *amqp.Delivery,
+	msg *Message,
+) (HandlerAcknowledgement, error) {
+	var elements []json.RawMessage
+
+	err := json.Unmarshal(msg.Body, &elements)
+	if err != nil {
+		return c.unmarshalFailed(ctx, d, err), nil
+	}
+
+	for i, element := range elements {
+		value := handler.NewJSONValue()
+
+		err = json.Unmarshal(element, value)
+		if err != nil {
+			return c.unmarshalFailed(ctx, d, err), nil
+		}
+
+		elementMsg := *msg
+		elementMsg.Body = element
+
+		acknowledgement, err := handler.ReceiveJSON(ctx, &elementMsg, value)
+		if err != nil {
+			return acknowledgement, err
+		}
+
+		if acknowledgement.Acknowledgement != Ack {
+			c.logger.Warn(
+				"RMQ handler failed to process an element of the JSON array message",
+				logger.Int("element", i),
+				logger.Int("elements", len(elements)),
+				tracingField(d.CorrelationId),
+				c.processingIDField(d),
+			)
+
+			return c.jsonArrayPartialFailed, nil
+		}
+	}
+
+	return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithJSONArrayFanOut(t *testing.T) {
+	newHandler := func(cancel context.CancelFunc, ids *[]string) *fakeJSONHandler {
+		return &fakeJSONHandler{
+			fakeHandler: newFakeHandler(ackAll),
+			receiveJSON: func(ctx context.Context, msg *Message, value interface{}) (HandlerAcknowledgement, error) {
+				id := value.(*testJSONPayload).ID
+				*ids = append(*ids, id)
+
+				if id == "fail" {
+					cancel()
+
+					return HandlerAcknowledgement{Acknowledgement: Reject}, nil
+				}
+
+				if id == "last" {
+					cancel()
+				}
+
+				return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+			},
+		}
+	}
+
+	t.Run("it passes every element to the handler and acks once all of them are acked", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var ids []string
+		consumer, _ := newTestConsumer(
+			newHandler(cancel, &ids),
+			channel,
+			ConsumerConfig{},
+			WithJSONArrayFanOut(HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}),
+		)
+
+		channel.deliver(ack, 1, `[{"id":"foo"},{"id":"bar"},{"id":"last"}]`)
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"foo", "bar", "last"}, ids)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+
+	t.Run("it stops at the failed element and applies the partial failure acknowledgement", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var ids []string
+		consumer, _ := newTestConsumer(
+			newHandler(cancel, &ids),
+			channel,
+			ConsumerConfig{},
+			WithJSONArrayFanOut(HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}),
+		)
+
+		channel.deliver(ack, 1, `[{"id":"foo"},{"id":"fail"},{"id":"last"}]`)
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"foo", "fail"}, ids)
+		assert.Equal(t, []uint64{1}, ack.Nacks())
+		assert.Empty(t, ack.Acks())
+		assert.Empty(t, ack.Rejects())
+	})
+
+	t.Run("by default, it rejects the deliveries which are not a JSON array", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var ids []string
+		consumer, _ := newTestConsumer(
+			newHandler(cancel, &ids),
+			channel,
+			ConsumerConfig{},
+			WithJSONArrayFanOut(HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}),
+		)
+
+		channel.deliver(ack, 1, `{"id":"foo"}`)
+		channel.deliver(ack, 2, `[{"id":"last"}]`)
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"last"}, ids)
+		assert.Equal(t, []uint64{1}, ack.Rejects())
+		assert.Equal(t, []uint64{2}, ack.Acks())
+	})
+}
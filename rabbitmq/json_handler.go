@@ -0,0 +1,90 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// JSONHandler is implemented by handlers which receive messages with a JSON payload.
+//
+// The consumer decodes the body of every delivery into the value returned by NewJSONValue(),
+// and passes it to ReceiveJSON() instead of calling ReceiveMessage().
+// When the body cannot be decoded, the delivery is acknowledged as decided by the unmarshal error handler,
+// see WithUnmarshalErrorHandler().
+type JSONHandler interface {
+	// NewJSONValue returns a pointer to a new value the message body is decoded into.
+	NewJSONValue() interface{}
+	ReceiveJSON(ctx context.Context, msg *Message, value interface{}) (acknowledgement HandlerAcknowledgement, err error)
+}
+
+// UnmarshalErrorHandler decides how a delivery whose body cannot be decoded by a JSONHandler is acknowledged.
+type UnmarshalErrorHandler func(ctx context.Context, d *amqp.Delivery, err error) HandlerAcknowledgement
+
+// WithUnmarshalErrorHandler sets what happens with the deliveries whose body cannot be decoded by a JSONHandler,
+// e.g. to discard them by acking, or to alert.
+//
+// By default such deliveries are rejected without requeue, so they end up in the dead letter queue if any.
+func WithUnmarshalErrorHandler(fn UnmarshalErrorHandler) ConsumerOption {
+	return func(c *Consumer) {
+		c.onUnmarshalError = fn
+	}
+}
+
+func rejectOnUnmarshalError(ctx context.Context, d *amqp.Delivery, err error) HandlerAcknowledgement {
+	return HandlerAcknowledgement{Acknowledgement: Reject, Requeue: false}
+}
+
+func (c *Consumer) receiveJSON(
+	ctx context.Context,
+	handler JSONHandler,
+	d *amqp.Delivery,
+	msg *Message,
+) (HandlerAcknowledgement, error) {
+	if c.jsonArrayFanOut {
+		return c.receiveJSONArray(ctx, handler, d, msg)
+	}
+
+	value := handler.NewJSONValue()
+
+	err := json.Unmarshal(msg.Body, value)
+	if err != nil {
+		return c.unmarshalFailed(ctx, d, err), nil
+	}
+
+	return handler.ReceiveJSON(ctx, msg, value)
+}
+
+// unmarshalFailed returns how the delivery whose body cannot be decoded is acknowledged.
+func (c *Consumer) unmarshalFailed(ctx context.Context, d *amqp.Delivery, err error) HandlerAcknowledgement {
+	c.logger.Warn(
+		"failed to unmarshal JSON message",
+		logger.ErrorField(err),
+		tracingField(d.CorrelationId),
+		c.processingIDField(d),
+	)
+
+	onUnmarshalError := c.onUnmarshalError
+	if onUnmarshalError == nil {
+		onUnmarshalError = rejectOnUnmarshalError
+	}
+
+	return onUnmarshalError(ctx, d, err)
+}
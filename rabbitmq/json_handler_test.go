@@ -0,0 +1,149 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+type testJSONPayload struct {
+	ID string `json:"id"`
+}
+
+type fakeJSONHandler struct {
+	*fakeHandler
+	receiveJSON func(ctx context.Context, msg *Message, value interface{}) (HandlerAcknowledgement, error)
+}
+
+func (h *fakeJSONHandler) NewJSONValue() interface{} {
+	return &testJSONPayload{}
+}
+
+func (h *fakeJSONHandler) ReceiveJSON(
+	ctx context.Context,
+	msg *Message,
+	value interface{},
+) (HandlerAcknowledgement, error) {
+	return h.receiveJSON(ctx, msg, value)
+}
+
+func TestJSONHandler(t *testing.T) {
+	newHandler := func(cancel context.CancelFunc, ids *[]string) *fakeJSONHandler {
+		return &fakeJSONHandler{
+			fakeHandler: newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+				t.Fatal("ReceiveMessage must not be called for a JSONHandler")
+
+				return HandlerAcknowledgement{}, nil
+			}),
+			receiveJSON: func(ctx context.Context, msg *Message, value interface{}) (HandlerAcknowledgement, error) {
+				*ids = append(*ids, value.(*testJSONPayload).ID)
+				cancel()
+
+				return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+			},
+		}
+	}
+
+	t.Run("it passes the decoded value to the handler", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var ids []string
+		consumer, _ := newTestConsumer(newHandler(cancel, &ids), channel, ConsumerConfig{})
+
+		channel.deliver(ack, 1, `{"id":"foo"}`)
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"foo"}, ids)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+
+	t.Run("by default, it rejects the deliveries which cannot be decoded", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var ids []string
+		consumer, _ := newTestConsumer(newHandler(cancel, &ids), channel, ConsumerConfig{})
+
+		channel.deliver(ack, 1, `not json`)
+		channel.deliver(ack, 2, `{"id":"bar"}`)
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"bar"}, ids)
+		assert.Equal(t, []uint64{1}, ack.Rejects())
+		assert.Equal(t, []uint64{2}, ack.Acks())
+	})
+}
+
+func TestWithUnmarshalErrorHandler(t *testing.T) {
+	t.Run("it applies the acknowledgement chosen by the unmarshal error handler", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var unmarshalErr error
+		var failedTag uint64
+		handler := &fakeJSONHandler{
+			fakeHandler: newFakeHandler(ackAll),
+			receiveJSON: func(ctx context.Context, msg *Message, value interface{}) (HandlerAcknowledgement, error) {
+				t.Fatal("ReceiveJSON must not be called for a delivery which cannot be decoded")
+
+				return HandlerAcknowledgement{}, nil
+			},
+		}
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithUnmarshalErrorHandler(func(ctx context.Context, d *amqp.Delivery, err error) HandlerAcknowledgement {
+				unmarshalErr = err
+				failedTag = d.DeliveryTag
+				cancel()
+
+				return HandlerAcknowledgement{Acknowledgement: Ack}
+			}),
+		)
+
+		channel.deliver(ack, 1, `not json`)
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Error(t, unmarshalErr)
+		assert.Equal(t, uint64(1), failedTag)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+		assert.Empty(t, ack.Rejects())
+	})
+}
@@ -1,11 +1,11 @@
 package rabbitmq
 
-import "go.uber.org/zap"
+import "github.com/sumup-oss/go-pkgs/logger"
 
-func tracingField(correlationID string) zap.Field {
+func tracingField(correlationID string) logger.Field {
 	if correlationID == "" {
-		return zap.Skip()
+		return logger.Skip()
 	}
 
-	return zap.String("tracing_id", correlationID)
+	return logger.String("tracing_id", correlationID)
 }
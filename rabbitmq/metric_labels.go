@@ -0,0 +1,54 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+// MetricLabels identify the consumer the observations of a metric come from.
+type MetricLabels struct {
+	Queue       string
+	ConsumerTag string
+}
+
+// LabeledMetric is an optional interface implemented by metrics which distinguish the observations
+// of the different consumers, e.g. when a single process runs many consumers reporting to the same registry.
+//
+// The consumer calls WithLabels once, when it is created, and uses the returned metric for all its observations.
+type LabeledMetric interface {
+	WithLabels(labels MetricLabels) Metric
+}
+
+// WithMetricLabels sets the labels passed to a LabeledMetric.
+// By default, the labels are the queue name and the consumer tag of the handler.
+func WithMetricLabels(labels MetricLabels) ConsumerOption {
+	return func(c *Consumer) {
+		c.metricLabels = &labels
+	}
+}
+
+func (c *Consumer) labelMetric() {
+	labeledMetric, ok := c.metric.(LabeledMetric)
+	if !ok {
+		return
+	}
+
+	labels := MetricLabels{
+		Queue:       c.handler.GetQueueName(),
+		ConsumerTag: c.handler.GetConsumerTag(),
+	}
+	if c.metricLabels != nil {
+		labels = *c.metricLabels
+	}
+
+	c.metric = labeledMetric.WithLabels(labels)
+}
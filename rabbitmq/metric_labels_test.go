@@ -0,0 +1,69 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type labeledMetric struct {
+	NullMetric
+	labels MetricLabels
+}
+
+func (m *labeledMetric) WithLabels(labels MetricLabels) Metric {
+	return &labeledMetric{labels: labels}
+}
+
+func TestConsumer_labelMetric(t *testing.T) {
+	t.Run("it labels the metric with the queue and the consumer tag of the handler", func(t *testing.T) {
+		t.Parallel()
+
+		consumer := NewConsumer(&fakeClient{}, newFakeHandler(ackAll), nil, &labeledMetric{}, ConsumerConfig{})
+
+		assert.Equal(
+			t,
+			MetricLabels{Queue: "test-queue", ConsumerTag: "test-consumer"},
+			consumer.metric.(*labeledMetric).labels,
+		)
+	})
+
+	t.Run("it uses the labels provided with WithMetricLabels", func(t *testing.T) {
+		t.Parallel()
+
+		labels := MetricLabels{Queue: "orders", ConsumerTag: "orders-1"}
+		consumer := NewConsumer(
+			&fakeClient{},
+			newFakeHandler(ackAll),
+			nil,
+			&labeledMetric{},
+			ConsumerConfig{},
+			WithMetricLabels(labels),
+		)
+
+		assert.Equal(t, labels, consumer.metric.(*labeledMetric).labels)
+	})
+
+	t.Run("it keeps the metric which does not support labels", func(t *testing.T) {
+		t.Parallel()
+
+		metric := &NullMetric{}
+		consumer := NewConsumer(&fakeClient{}, newFakeHandler(ackAll), nil, metric, ConsumerConfig{})
+
+		assert.Same(t, metric, consumer.metric)
+	})
+}
@@ -14,32 +14,76 @@
 
 package rabbitmq
 
+import "time"
+
+// Metric observes the connections of the client, and the throughput of the consumers and the producers,
+// see rabbitmqprometheus.Metric for an implementation exporting Prometheus metrics.
+//
+// The metrics may implement the optional interfaces observing more, e.g. HandlerLatencyMetric.
 type Metric interface {
+	// ObserveRabbitMQConnectionFailed counts the failed connection attempts.
 	ObserveRabbitMQConnectionFailed()
+	// ObserveRabbitMQConnectionRetry counts the connection retries.
 	ObserveRabbitMQConnectionRetry()
+	// ObserveRabbitMQConnection counts the established connections.
 	ObserveRabbitMQConnection()
 
+	// ObserveRabbitMQChanelConnectionFailed counts the failed channel creations.
 	ObserveRabbitMQChanelConnectionFailed()
+	// ObserveRabbitMQChanelConnectionRetry counts the channel creation retries.
 	ObserveRabbitMQChanelConnectionRetry()
+	// ObserveRabbitMQChanelConnection counts the created channels.
 	ObserveRabbitMQChanelConnection()
 
+	// ObserveMsgDelivered counts the deliveries received by the consumers.
 	ObserveMsgDelivered()
+	// ObserveAck counts the acked deliveries, and whether the ack succeeded.
 	ObserveAck(success bool)
+	// ObserveNack counts the nacked deliveries, and whether the nack succeeded.
 	ObserveNack(success bool)
+	// ObserveReject counts the rejected deliveries, and whether the reject succeeded.
 	ObserveReject(success bool)
+	// ObserveMsgPublish counts the published messages, and whether the publish succeeded.
 	ObserveMsgPublish(success bool)
+	// ObserveHandlerPanic counts the recovered panics of the consumer handlers.
+	ObserveHandlerPanic()
+}
+
+// HandlerLatencyMetric is an optional interface implemented by metrics observing how long
+// the consumer handlers take to process a delivery, or a batch of deliveries with WithBatch().
+type HandlerLatencyMetric interface {
+	ObserveHandlerLatency(latency time.Duration)
 }
 
+// NullMetric is a Metric which observes nothing.
 type NullMetric struct{}
 
-func (n *NullMetric) ObserveRabbitMQConnectionFailed()       {}
-func (n *NullMetric) ObserveRabbitMQConnectionRetry()        {}
-func (n *NullMetric) ObserveRabbitMQConnection()             {}
-func (n *NullMetric) ObserveRabbitMQChanelConnectionFailed() {}
-func (n *NullMetric) ObserveRabbitMQChanelConnectionRetry()  {}
-func (n *NullMetric) ObserveRabbitMQChanelConnection()       {}
-func (n *NullMetric) ObserveMsgDelivered()                   {}
-func (n *NullMetric) ObserveAck(success bool)                {}
-func (n *NullMetric) ObserveNack(success bool)               {}
-func (n *NullMetric) ObserveReject(success bool)             {}
-func (n *NullMetric) ObserveMsgPublish(success bool)         {}
+var (
+	_ DeliveryCountMetric  = (*NullMetric)(nil)
+	_ PublishLatencyMetric = (*NullMetric)(nil)
+	_ ConsumerStateMetric  = (*NullMetric)(nil)
+	_ HandlerLatencyMetric = (*NullMetric)(nil)
+)
+
+func (n *NullMetric) ObserveRabbitMQConnectionFailed()            {}
+func (n *NullMetric) ObserveRabbitMQConnectionRetry()             {}
+func (n *NullMetric) ObserveRabbitMQConnection()                  {}
+func (n *NullMetric) ObserveRabbitMQChanelConnectionFailed()      {}
+func (n *NullMetric) ObserveRabbitMQChanelConnectionRetry()       {}
+func (n *NullMetric) ObserveRabbitMQChanelConnection()            {}
+func (n *NullMetric) ObserveMsgDelivered()                        {}
+func (n *NullMetric) ObserveAck(success bool)                     {}
+func (n *NullMetric) ObserveNack(success bool)                    {}
+func (n *NullMetric) ObserveReject(success bool)                  {}
+func (n *NullMetric) ObserveMsgPublish(success bool)              {}
+func (n *NullMetric) ObserveDeliveryCount(count int)              {}
+func (n *NullMetric) ObserveHandlerPanic()                        {}
+func (n *NullMetric) ObservePublishLatency(latency time.Duration) {}
+func (n *NullMetric) ObserveConsumerState(state ConsumerState)    {}
+func (n *NullMetric) ObserveHandlerLatency(latency time.Duration) {}
+
+func (c *Consumer) observeHandlerLatency(latency time.Duration) {
+	if metric, ok := c.metric.(HandlerLatencyMetric); ok {
+		metric.ObserveHandlerLatency(latency)
+	}
+}
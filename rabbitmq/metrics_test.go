@@ -0,0 +1,123 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+type throughputMetric struct {
+	NullMetric
+
+	mu        sync.Mutex
+	delivered int
+	acks      int
+	nacks     int
+	rejects   int
+	latencies []time.Duration
+}
+
+func (m *throughputMetric) ObserveMsgDelivered() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.delivered++
+}
+
+func (m *throughputMetric) ObserveAck(success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.acks++
+}
+
+func (m *throughputMetric) ObserveNack(success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nacks++
+}
+
+func (m *throughputMetric) ObserveReject(success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rejects++
+}
+
+func (m *throughputMetric) ObserveHandlerLatency(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.latencies = append(m.latencies, latency)
+}
+
+func TestConsumer_Metric(t *testing.T) {
+	t.Run("it observes the deliveries, their acknowledgements and the handler latency", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+		metric := &throughputMetric{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var processed int
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			processed++
+			clock.Advance(time.Duration(processed) * time.Second)
+			if processed == 3 {
+				cancel()
+			}
+
+			switch string(msg.Body) {
+			case "retry":
+				return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+			case "bad":
+				return HandlerAcknowledgement{Acknowledgement: Reject}, nil
+			default:
+				return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+			}
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithClock(clock))
+		consumer.metric = metric
+
+		channel.deliver(ack, 1, "ok")
+		channel.deliver(ack, 2, "retry")
+		channel.deliver(ack, 3, "bad")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		metric.mu.Lock()
+		defer metric.mu.Unlock()
+
+		assert.Equal(t, 3, metric.delivered)
+		assert.Equal(t, 1, metric.acks)
+		assert.Equal(t, 1, metric.nacks)
+		assert.Equal(t, 1, metric.rejects)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, metric.latencies)
+	})
+}
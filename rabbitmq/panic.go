@@ -0,0 +1,53 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"fmt"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// recoverHandlerPanic recovers a panic of the handler processing the delivery, so it does not crash the consumer.
+//
+// The panic is logged with its stack trace and counted by Metric.ObserveHandlerPanic(),
+// and the delivery is rejected without requeue, so a message which makes the handler panic
+// is dead-lettered instead of being redelivered over and over.
+// It must be deferred directly by the function invoking the handler.
+func (c *Consumer) recoverHandlerPanic(
+	d *amqp.Delivery,
+	acknowledgement *HandlerAcknowledgement,
+	err *error,
+) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	c.metric.ObserveHandlerPanic()
+	c.logger.Error(
+		"RMQ handler panicked, rejecting the message",
+		logger.String("panic", fmt.Sprint(recovered)),
+		logger.Stack("stack"),
+		logger.String("queue", c.handler.GetQueueName()),
+		tracingField(d.CorrelationId),
+		c.processingIDField(d),
+	)
+
+	*acknowledgement = HandlerAcknowledgement{Acknowledgement: Reject, Requeue: false}
+	*err = nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+type panicMetric struct {
+	NullMetric
+
+	panics int64
+}
+
+func (m *panicMetric) ObserveHandlerPanic() {
+	atomic.AddInt64(&m.panics, 1)
+}
+
+func TestConsumer_HandlerPanic(t *testing.T) {
+	t.Run("it recovers the panic, counts it and rejects the message", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+		metric := &panicMetric{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			if string(msg.Body) == "poison" {
+				panic("boom")
+			}
+
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{})
+		consumer.metric = metric
+
+		channel.deliver(ack, 1, "poison")
+		channel.deliver(ack, 2, "valid")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, int64(1), atomic.LoadInt64(&metric.panics))
+
+		ack.mu.Lock()
+		defer ack.mu.Unlock()
+		assert.Equal(t, []uint64{1}, ack.rejects)
+		assert.Equal(t, []uint64{2}, ack.acks)
+	})
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// pauseMinDeliveries is the minimum number of deliveries in the window needed to compute the error rate,
+// so a single failure does not pause the consumer.
+const pauseMinDeliveries = 5
+
+// WithPauseOnErrorRate pauses consuming for cooldown when the share of the deliveries failed by the handler,
+// i.e. nacked or rejected, exceeds threshold (between 0 and 1) over the sliding window.
+//
+// While paused, the consumer does not receive any deliveries, and once the cooldown passes it resumes
+// with an empty window. Note that the deliveries already prefetched by the consumer wait for it to resume.
+// The error rate is computed only once the window contains at least 5 deliveries.
+//
+// NOTE: The deliveries consumed with WithBatch() are not taken into account.
+func WithPauseOnErrorRate(threshold float64, window, cooldown time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.errorRate = &errorRateWindow{
+			threshold: threshold,
+			window:    window,
+		}
+		c.pauseCooldown = cooldown
+	}
+}
+
+type deliveryOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// errorRateWindow tracks the outcome of the deliveries over a sliding window.
+// It is safe for concurrent use, since the deliveries can be processed by multiple workers, see WithWorkers().
+type errorRateWindow struct {
+	threshold float64
+	window    time.Duration
+
+	// mu protects the outcomes and the failed properties
+	mu       sync.Mutex
+	outcomes []deliveryOutcome
+	failed   int
+}
+
+func (w *errorRateWindow) record(now time.Time, failed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.outcomes = append(w.outcomes, deliveryOutcome{at: now, failed: failed})
+	if failed {
+		w.failed++
+	}
+
+	w.prune(now)
+}
+
+// exceeded returns the error rate over the window and whether it is above the threshold.
+func (w *errorRateWindow) exceeded(now time.Time) (float64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.prune(now)
+
+	if len(w.outcomes) < pauseMinDeliveries {
+		return 0, false
+	}
+
+	rate := float64(w.failed) / float64(len(w.outcomes))
+
+	return rate, rate > w.threshold
+}
+
+func (w *errorRateWindow) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.outcomes = nil
+	w.failed = 0
+}
+
+func (w *errorRateWindow) prune(now time.Time) {
+	cutoff := now.Add(-w.window)
+
+	i := 0
+	for ; i < len(w.outcomes) && w.outcomes[i].at.Before(cutoff); i++ {
+		if w.outcomes[i].failed {
+			w.failed--
+		}
+	}
+
+	w.outcomes = w.outcomes[i:]
+}
+
+func (c *Consumer) recordDeliveryOutcome(acknowledgement HandlerAcknowledgement) {
+	if c.errorRate == nil {
+		return
+	}
+
+	c.errorRate.record(c.clock.Now(), acknowledgement.Acknowledgement != Ack)
+}
+
+// pauseOnErrorRate blocks for the cooldown if the error rate exceeds the threshold.
+// Returns the context error if the context is done while paused.
+func (c *Consumer) pauseOnErrorRate(ctx context.Context) error {
+	if c.errorRate == nil {
+		return nil
+	}
+
+	rate, exceeded := c.errorRate.exceeded(c.clock.Now())
+	if !exceeded {
+		return nil
+	}
+
+	c.logger.Warn(
+		"RMQ consumer paused due to elevated handler error rate",
+		logger.String("queue", c.handler.GetQueueName()),
+		logger.Float64("error_rate", rate),
+		logger.Duration("cooldown", c.pauseCooldown),
+	)
+
+	c.setPaused(true)
+	defer c.setPaused(false)
+
+	timer := c.clock.NewTimer(c.pauseCooldown)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+	}
+
+	c.errorRate.reset()
+
+	c.logger.Info(
+		"RMQ consumer resumed after pause",
+		logger.String("queue", c.handler.GetQueueName()),
+	)
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPauseOnErrorRate(t *testing.T) {
+	t.Run("it pauses consuming for the cooldown once the error rate exceeds the threshold", func(t *testing.T) {
+		t.Parallel()
+
+		const cooldown = 50 * time.Millisecond
+
+		channel := newFakeChannel(pauseMinDeliveries + 1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		var receivedAt []time.Time
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			receivedAt = append(receivedAt, time.Now())
+			if string(msg.Body) == "ok" {
+				cancel()
+
+				return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: false}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithPauseOnErrorRate(0.5, time.Minute, cooldown),
+		)
+
+		for i := 1; i <= pauseMinDeliveries; i++ {
+			channel.deliver(ack, uint64(i), "fail")
+		}
+		channel.deliver(ack, pauseMinDeliveries+1, "ok")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		require.Len(t, receivedAt, pauseMinDeliveries+1)
+		for i := 1; i < pauseMinDeliveries; i++ {
+			assert.Less(t, int64(receivedAt[i].Sub(receivedAt[i-1])), int64(cooldown))
+		}
+
+		pause := receivedAt[pauseMinDeliveries].Sub(receivedAt[pauseMinDeliveries-1])
+		assert.GreaterOrEqual(t, int64(pause), int64(cooldown))
+		assert.Equal(t, []uint64{pauseMinDeliveries + 1}, ack.Acks())
+	})
+
+	t.Run("it does not pause while the error rate is below the threshold", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(pauseMinDeliveries * 2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := 0
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			received++
+			if received == pauseMinDeliveries*2 {
+				cancel()
+			}
+
+			if received%2 == 0 {
+				return HandlerAcknowledgement{Acknowledgement: Reject}, nil
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithPauseOnErrorRate(0.5, time.Minute, time.Hour),
+		)
+
+		for i := 1; i <= pauseMinDeliveries*2; i++ {
+			channel.deliver(ack, uint64(i), "msg")
+		}
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, pauseMinDeliveries*2, received)
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// Stage is a step of a Pipeline processing the body of a delivery before it is passed to the handler,
+// e.g. to decrypt, decompress or validate it.
+type Stage interface {
+	// Process returns the body passed to the next stage, or to the handler after the last stage.
+	// An error short-circuits the pipeline, and the delivery is rejected.
+	Process(ctx context.Context, body []byte) ([]byte, error)
+}
+
+// StageFunc is a function implementing Stage.
+type StageFunc func(ctx context.Context, body []byte) ([]byte, error)
+
+// Process implements Stage.
+func (fn StageFunc) Process(ctx context.Context, body []byte) ([]byte, error) {
+	return fn(ctx, body)
+}
+
+// Pipeline is a list of stages applied in order to the body of the deliveries, see WithPipeline().
+//
+// A Pipeline is a Stage itself, so the pipelines can be nested.
+type Pipeline []Stage
+
+// Process passes the body through every stage in order, and returns the body returned by the last one.
+// It stops at the first stage returning an error, and returns it.
+func (p Pipeline) Process(ctx context.Context, body []byte) ([]byte, error) {
+	for i, stage := range p {
+		var err error
+
+		body, err = stage.Process(ctx, body)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "RMQ pipeline stage %d failed", i)
+		}
+	}
+
+	return body, nil
+}
+
+// WithPipeline makes the consumer pass the body of every delivery through the stages of the pipeline,
+// before it is passed to the handler, e.g. decrypt, decompress, validate and transform it:
+//
+//	rabbitmq.WithPipeline(rabbitmq.Pipeline{decrypt, decompress, validate})
+//
+// When a stage fails, the delivery is rejected without requeue and logged with the error,
+// and the handler is not called. With WithFraming(), the pipeline is applied to every frame of the delivery.
+// The pipeline is not applied to the batches of WithBatch().
+func WithPipeline(pipeline Pipeline) ConsumerOption {
+	return func(c *Consumer) {
+		c.pipeline = pipeline
+	}
+}
+
+// runPipeline returns the body processed by the pipeline, and false if a stage failed and the delivery
+// must be rejected.
+func (c *Consumer) runPipeline(ctx context.Context, d *amqp.Delivery, body []byte) ([]byte, bool) {
+	if len(c.pipeline) == 0 {
+		return body, true
+	}
+
+	body, err := c.pipeline.Process(ctx, body)
+	if err != nil {
+		c.logger.Warn(
+			"RMQ delivery failed the pipeline, rejecting it",
+			logger.ErrorField(err),
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
+
+		return nil, false
+	}
+
+	return body, true
+}
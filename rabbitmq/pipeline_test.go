@@ -0,0 +1,95 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPipeline(t *testing.T) {
+	upper := StageFunc(func(ctx context.Context, body []byte) ([]byte, error) {
+		return bytes.ToUpper(body), nil
+	})
+
+	t.Run("it passes the body processed by the stages in order to the handler", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var body string
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			body = string(msg.Body)
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		suffix := StageFunc(func(ctx context.Context, body []byte) ([]byte, error) {
+			return append(body, "-bar"...), nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithPipeline(Pipeline{upper, suffix}))
+
+		channel.deliver(ack, 1, "foo")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, "FOO-bar", body)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+	})
+
+	t.Run("when a stage fails, it rejects the delivery without calling the handler", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+		capturingLog := newCapturingLogger()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var called bool
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			called = true
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		invalid := StageFunc(func(ctx context.Context, body []byte) ([]byte, error) {
+			defer cancel()
+
+			return nil, errors.New("invalid payload")
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithPipeline(Pipeline{upper, invalid}))
+		consumer.logger = capturingLog
+
+		channel.deliver(ack, 1, "foo")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.False(t, called)
+		assert.Equal(t, []uint64{1}, ack.Rejects())
+		assert.Empty(t, ack.Requeued())
+		assert.Empty(t, ack.Acks())
+		assert.Len(t, capturingLog.logs.FilterMessage("RMQ delivery failed the pipeline, rejecting it").All(), 1)
+	})
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"container/heap"
+	"context"
+
+	"github.com/streadway/amqp"
+)
+
+// WithPriorityOrdering makes the consumer process the deliveries it has already received
+// in the order of their Priority property, the highest first.
+//
+// The deliveries with the same priority are processed in the order they are received.
+// Only the deliveries sent by the broker ahead of time are reordered, so the PrefetchCount
+// of the consumer bounds how many of them are considered at once.
+// When the channel closes, the deliveries not processed yet are discarded,
+// and the broker redelivers them.
+func WithPriorityOrdering() ConsumerOption {
+	return func(c *Consumer) {
+		c.priorityOrdering = true
+	}
+}
+
+// prioritize returns the deliveries reordered by priority if the priority ordering is enabled.
+//
+// The returned channel is closed once the deliveries channel is closed or the context is done.
+func (c *Consumer) prioritize(ctx context.Context, deliveries <-chan amqp.Delivery) <-chan amqp.Delivery {
+	if !c.priorityOrdering {
+		return deliveries
+	}
+
+	ordered := make(chan amqp.Delivery)
+
+	go func() {
+		defer close(ordered)
+
+		pending := &deliveryHeap{}
+
+		for {
+			if pending.Len() == 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case d, hasMore := <-deliveries:
+					if !hasMore {
+						return
+					}
+
+					pending.push(d)
+				}
+			}
+
+			// NOTE: Take all the deliveries already received before dispatching the next one,
+			// so the one dispatched has the highest priority of them.
+		received:
+			for {
+				select {
+				case d, hasMore := <-deliveries:
+					if !hasMore {
+						return
+					}
+
+					pending.push(d)
+				default:
+					break received
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case ordered <- pending.top():
+				heap.Pop(pending)
+			case d, hasMore := <-deliveries:
+				if !hasMore {
+					return
+				}
+
+				pending.push(d)
+			}
+		}
+	}()
+
+	return ordered
+}
+
+type prioritizedDelivery struct {
+	delivery amqp.Delivery
+	seq      uint64
+}
+
+// deliveryHeap is a heap.Interface of the deliveries, ordered by priority, then by the order they are received.
+type deliveryHeap struct {
+	items []prioritizedDelivery
+	seq   uint64
+}
+
+func (h *deliveryHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *deliveryHeap) Less(i, j int) bool {
+	if h.items[i].delivery.Priority != h.items[j].delivery.Priority {
+		return h.items[i].delivery.Priority > h.items[j].delivery.Priority
+	}
+
+	return h.items[i].seq < h.items[j].seq
+}
+
+func (h *deliveryHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *deliveryHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(prioritizedDelivery))
+}
+
+func (h *deliveryHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items[len(h.items)-1] = prioritizedDelivery{}
+	h.items = h.items[:len(h.items)-1]
+
+	return last
+}
+
+func (h *deliveryHeap) push(d amqp.Delivery) {
+	h.seq++
+	heap.Push(h, prioritizedDelivery{delivery: d, seq: h.seq})
+}
+
+func (h *deliveryHeap) top() amqp.Delivery {
+	return h.items[0].delivery
+}
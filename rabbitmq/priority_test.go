@@ -0,0 +1,60 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPriorityOrdering(t *testing.T) {
+	t.Run("it processes the received deliveries with higher priority first", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(5)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var received []string
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			received = append(received, string(msg.Body))
+			if len(received) == 5 {
+				cancel()
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{PrefetchCount: 5}, WithPriorityOrdering())
+
+		for i, priority := range []uint8{1, 5, 0, 5, 9} {
+			channel.deliveries <- amqp.Delivery{
+				Acknowledger: ack,
+				DeliveryTag:  uint64(i + 1),
+				Priority:     priority,
+				Body:         []byte(string(rune('a' + i))),
+			}
+		}
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, []string{"e", "b", "d", "a", "c"}, received)
+	})
+}
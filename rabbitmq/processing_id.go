@@ -0,0 +1,83 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+type processingIDKey struct{}
+
+// ProcessingIDFromContext returns the ID of the attempt to process the delivery passed to the handler with ctx.
+//
+// The consumer assigns a new processing ID every time it receives a delivery, so unlike the correlation ID
+// it differs across the redeliveries of a message. The consumer logs it as the processing_id field
+// and reports it in ProcessingReport.ProcessingID, so the handler can include it in its own logs
+// to correlate all the logs of one processing attempt.
+// It returns an empty string for the batches consumed with WithBatch(), since a batch is not bound
+// to a single delivery.
+func ProcessingIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(processingIDKey{}).(string)
+
+	return id
+}
+
+func withProcessingID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, processingIDKey{}, id)
+}
+
+// startProcessing assigns a new processing ID to the delivery until finishProcessing() is called for it.
+func (c *Consumer) startProcessing(d *amqp.Delivery) string {
+	id := newProcessingID()
+	c.processingIDs.Store(d, id)
+
+	return id
+}
+
+func (c *Consumer) finishProcessing(d *amqp.Delivery) {
+	c.processingIDs.Delete(d)
+}
+
+// processingID returns the processing ID of the delivery, or an empty string if it is not being processed.
+func (c *Consumer) processingID(d *amqp.Delivery) string {
+	id, _ := c.processingIDs.Load(d)
+	s, _ := id.(string)
+
+	return s
+}
+
+func (c *Consumer) processingIDField(d *amqp.Delivery) logger.Field {
+	id := c.processingID(d)
+	if id == "" {
+		return logger.Skip()
+	}
+
+	return logger.String("processing_id", id)
+}
+
+func newProcessingID() string {
+	var id [16]byte
+
+	// NOTE: crypto/rand does not fail on the supported platforms.
+	_, _ = rand.Read(id[:])
+
+	return hex.EncodeToString(id[:])
+}
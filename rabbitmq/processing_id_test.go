@@ -0,0 +1,99 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestProcessingIDFromContext(t *testing.T) {
+	t.Run("it shares the processing ID within one processing attempt and changes it across attempts", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+		log := newCapturingLogger()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var handlerIDs []string
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			handlerIDs = append(handlerIDs, ProcessingIDFromContext(ctx))
+			if len(handlerIDs) == 1 {
+				return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+			}
+
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+
+		var reports []ProcessingReport
+		consumer := NewConsumer(
+			&fakeClient{},
+			handler,
+			log,
+			&NullMetric{},
+			ConsumerConfig{},
+			WithAuditLog(log, zapcore.InfoLevel),
+			WithProcessingReport(func(report ProcessingReport) {
+				reports = append(reports, report)
+			}),
+		)
+		consumer.createChannel = func(ctx context.Context) (amqpChannel, error) {
+			return channel, nil
+		}
+
+		// NOTE: The same message is redelivered once it is nacked with requeue.
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "foo")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		require.Len(t, handlerIDs, 2)
+		assert.NotEmpty(t, handlerIDs[0])
+		assert.NotEmpty(t, handlerIDs[1])
+		assert.NotEqual(t, handlerIDs[0], handlerIDs[1])
+
+		require.Len(t, reports, 2)
+		assert.Equal(t, handlerIDs[0], reports[0].ProcessingID)
+		assert.Equal(t, handlerIDs[1], reports[1].ProcessingID)
+
+		for i, message := range []string{"successful nack message", "successful ack message"} {
+			entries := log.logs.FilterMessage(message).All()
+			require.Len(t, entries, 1)
+			assert.Equal(t, handlerIDs[i], entries[0].ContextMap()["processing_id"])
+		}
+
+		audits := log.logs.FilterMessage(AuditLogMessage).All()
+		require.Len(t, audits, 2)
+		assert.Equal(t, handlerIDs[0], audits[0].ContextMap()["processing_id"])
+		assert.Equal(t, handlerIDs[1], audits[1].ContextMap()["processing_id"])
+	})
+
+	t.Run("it returns an empty processing ID for a context of no delivery", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, ProcessingIDFromContext(context.Background()))
+	})
+}
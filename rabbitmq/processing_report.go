@@ -0,0 +1,95 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// ProcessingReport describes how a delivery was processed, see WithProcessingReport().
+type ProcessingReport struct {
+	MessageID     string
+	CorrelationID string
+	Exchange      string
+	RoutingKey    string
+	DeliveryTag   uint64
+	// ProcessingID identifies the attempt to process the delivery, see ProcessingIDFromContext().
+	ProcessingID string
+	// DeliveryCount is the number of times the message was delivered before, see DeliveryCount().
+	DeliveryCount int
+	// RetryAttempt is the number of times the message was retried through the retry ladder, see WithRetryLadder().
+	RetryAttempt int
+
+	// Acknowledged is false when the handler returned an error, or acknowledging the delivery to RMQ failed.
+	Acknowledged bool
+	// Acknowledgement is how the delivery was acknowledged, after the retry ladder is applied.
+	Acknowledgement HandlerAcknowledgement
+	// Duration is how long the handler took to process the delivery.
+	Duration time.Duration
+	// Err is the error returned by the handler.
+	Err error
+	// AckErr is the error of acknowledging the delivery to RMQ.
+	AckErr error
+}
+
+// WithProcessingReport calls onReport with a report of every delivery once it is processed.
+//
+// It is a single place to observe the outcome of the deliveries, instead of wiring
+// the metric, the log and the audit log separately.
+// The report is made once the delivery is acknowledged to RMQ, or once the handler returns an error.
+// onReport is called from the goroutine processing the deliveries, so it must not block.
+func WithProcessingReport(onReport func(report ProcessingReport)) ConsumerOption {
+	return func(c *Consumer) {
+		c.onProcessingReport = onReport
+	}
+}
+
+func (c *Consumer) reportProcessing(
+	d *amqp.Delivery,
+	acknowledgement HandlerAcknowledgement,
+	duration time.Duration,
+	err error,
+	ackErr error,
+) {
+	if c.onProcessingReport == nil && c.debugRing == nil {
+		return
+	}
+
+	report := ProcessingReport{
+		MessageID:       d.MessageId,
+		CorrelationID:   d.CorrelationId,
+		Exchange:        d.Exchange,
+		RoutingKey:      d.RoutingKey,
+		DeliveryTag:     d.DeliveryTag,
+		ProcessingID:    c.processingID(d),
+		DeliveryCount:   DeliveryCount(d),
+		RetryAttempt:    retryAttempt(d),
+		Acknowledged:    err == nil && ackErr == nil,
+		Acknowledgement: acknowledgement,
+		Duration:        duration,
+		Err:             err,
+		AckErr:          ackErr,
+	}
+
+	if c.debugRing != nil {
+		c.debugRing.add(DeliveryRecord{ProcessingReport: report, ProcessedAt: c.clock.Now()})
+	}
+
+	if c.onProcessingReport != nil {
+		c.onProcessingReport(report)
+	}
+}
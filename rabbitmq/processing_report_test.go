@@ -0,0 +1,137 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithProcessingReport(t *testing.T) {
+	t.Run("it reports the outcome and the duration of the handled messages", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			clock.Advance(3 * time.Second)
+
+			if string(msg.Body) == "failed" {
+				cancel()
+
+				return HandlerAcknowledgement{}, errors.New("handler failed")
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+		})
+
+		var reports []ProcessingReport
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithClock(clock),
+			WithProcessingReport(func(report ProcessingReport) {
+				reports = append(reports, report)
+			}),
+		)
+
+		channel.deliveries <- amqp.Delivery{
+			Acknowledger: ack,
+			DeliveryTag:  1,
+			MessageId:    "message-1",
+			RoutingKey:   "orders.created",
+			Headers:      amqp.Table{DeliveryCountHeader: int64(2)},
+			Body:         []byte("handled"),
+		}
+		channel.deliver(ack, 2, "failed")
+
+		err := consumer.Run(ctx)
+		assert.Error(t, err)
+		require.Len(t, reports, 2)
+
+		// NOTE: The processing ID is random, see TestProcessingIDFromContext.
+		assert.NotEmpty(t, reports[0].ProcessingID)
+		reports[0].ProcessingID = ""
+
+		assert.Equal(
+			t,
+			ProcessingReport{
+				MessageID:       "message-1",
+				RoutingKey:      "orders.created",
+				DeliveryTag:     1,
+				DeliveryCount:   2,
+				Acknowledged:    true,
+				Acknowledgement: HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true},
+				Duration:        3 * time.Second,
+			},
+			reports[0],
+		)
+
+		assert.False(t, reports[1].Acknowledged)
+		assert.Equal(t, uint64(2), reports[1].DeliveryTag)
+		assert.Equal(t, 3*time.Second, reports[1].Duration)
+		assert.EqualError(t, reports[1].Err, "handler failed")
+	})
+	t.Run("it reports the deliveries RMQ failed to acknowledge as not acknowledged", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{err: amqp.ErrClosed}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+
+		var reports []ProcessingReport
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithProcessingReport(func(report ProcessingReport) {
+				reports = append(reports, report)
+			}),
+		)
+
+		channel.deliver(ack, 1, "handled")
+
+		err := consumer.Run(ctx)
+		assert.Error(t, err)
+		require.Len(t, reports, 1)
+
+		assert.False(t, reports[0].Acknowledged)
+		assert.Equal(t, HandlerAcknowledgement{Acknowledgement: Ack}, reports[0].Acknowledgement)
+		assert.NoError(t, reports[0].Err)
+		assert.Equal(t, amqp.ErrClosed, reports[0].AckErr)
+	})
+}
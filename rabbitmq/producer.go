@@ -21,7 +21,6 @@ import (
 
 	"github.com/palantir/stacktrace"
 	"github.com/streadway/amqp"
-	"go.uber.org/zap"
 
 	"github.com/sumup-oss/go-pkgs/logger"
 )
@@ -39,10 +38,12 @@ type MessageArgs struct {
 }
 
 type Producer struct {
-	client  RabbitMQClientInterface
-	logger  logger.StructuredLogger
-	metric  Metric
-	channel *amqp.Channel
+	client RabbitMQClientInterface
+	// releaseClient releases the reference of the producer to its client, see RabbitMQClient.Retain()
+	releaseClient func() error
+	logger        logger.StructuredLogger
+	metric        Metric
+	channel       *amqp.Channel
 
 	closeCh chan *amqp.Error
 
@@ -58,12 +59,13 @@ func NewProducer(client RabbitMQClientInterface, logger logger.StructuredLogger,
 	}
 
 	return &Producer{
-		client:   client,
-		logger:   logger,
-		metric:   metric,
-		channel:  channel,
-		closeCh:  channel.NotifyClose(make(chan *amqp.Error)),
-		isClosed: 0,
+		client:        client,
+		releaseClient: retain(client),
+		logger:        logger,
+		metric:        metric,
+		channel:       channel,
+		closeCh:       channel.NotifyClose(make(chan *amqp.Error)),
+		isClosed:      0,
 	}, nil
 }
 
@@ -81,10 +83,10 @@ func (p *Producer) Publish(
 		if rmqErr != nil {
 			p.logger.Warn(
 				"RMQ closed the connection",
-				zap.String("reason", rmqErr.Reason),
-				zap.Int("code", rmqErr.Code),
-				zap.Bool("recover", rmqErr.Recover),
-				zap.Bool("server", rmqErr.Server),
+				logger.String("reason", rmqErr.Reason),
+				logger.Int("code", rmqErr.Code),
+				logger.Bool("recover", rmqErr.Recover),
+				logger.Bool("server", rmqErr.Server),
 				tracingField(args.CorrelationID),
 			)
 		} else {
@@ -119,8 +121,11 @@ func (p *Producer) Publish(
 	return nil
 }
 
+// Close releases the reference of the producer to its client,
+// closing the connection if there are no more references to the client, see RabbitMQClient.Retain().
+// Calling Close again is a no-op.
 func (p *Producer) Close() error {
-	err := p.client.Close()
+	err := p.releaseClient()
 
 	return stacktrace.Propagate(err, "failed to close RMQ producer")
 }
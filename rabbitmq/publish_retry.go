@@ -0,0 +1,155 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/backoff"
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// RetryConfig configures the retries of RabbitMQPublisher.PublishWithRetry().
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of publish attempts, including the first one.
+	// A MaxAttempts < 2 publishes the message once, as RabbitMQPublisher.Publish() does.
+	MaxAttempts int
+	// Backoff configures the delay between the attempts, backoff.DefaultConfig if nil.
+	Backoff *backoff.Config
+}
+
+// PublishWithRetry publishes msg to the exchange with the routing key as RabbitMQPublisher.Publish() does,
+// retrying the publishes failing with a retryable error, see IsRetryable(), e.g. because the channel
+// or the connection was closed.
+//
+// The attempts are delayed by cfg.Backoff, and the publisher re-opens its channel before the next attempt
+// once an attempt finds it closed. The retries stop once ctx is done, returning its error.
+// The non-retryable errors, e.g. amqp.PreconditionFailed, are returned right away, and once all the attempts
+// fail the error of the last one is returned.
+//
+// NOTE: A message whose confirmation is lost with the closed channel is published again,
+// so the consumers may receive it twice.
+func (p *RabbitMQPublisher) PublishWithRetry(
+	ctx context.Context,
+	exchange,
+	routingKey string,
+	msg amqp.Publishing,
+	cfg RetryConfig,
+) error {
+	backoffConfig := backoff.DefaultConfig
+	if cfg.Backoff != nil {
+		backoffConfig = cfg.Backoff
+	}
+
+	// NOTE: Copy the config, since the backoff fills in its defaults.
+	retryConfig := *backoffConfig
+	retryBackoff := backoff.NewBackoff(&retryConfig)
+
+	// closed is the channel found closed by the last attempt, nil if it was not
+	var closed amqpChannel
+
+	for attempt := 1; ; attempt++ {
+		var err error
+
+		if closed != nil {
+			err = p.reopenChannel(ctx, closed)
+			if err == nil {
+				closed = nil
+			}
+		}
+
+		channel := p.currentChannel()
+		if err == nil {
+			err = p.Publish(ctx, exchange, routingKey, msg)
+			if err == nil {
+				return nil
+			}
+
+			if isChannelClosedError(err) {
+				closed = channel
+			}
+		}
+
+		if !IsRetryable(err) {
+			return stacktrace.Propagate(err, "failed to publish RMQ message with non-retryable error")
+		}
+
+		if attempt >= cfg.MaxAttempts {
+			return stacktrace.Propagate(err, "failed to publish RMQ message after %d attempts", attempt)
+		}
+
+		delay := retryBackoff.Next()
+		p.logger.Warn(
+			"failed to publish RMQ message, retrying",
+			logger.ErrorField(err),
+			logger.Int("attempt", attempt),
+			logger.Duration("delay", delay),
+		)
+
+		timer := p.clock.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return stacktrace.Propagate(ctx.Err(), "stopped retrying the RMQ publish")
+		case <-timer.C():
+		}
+	}
+}
+
+// reopenChannel replaces the closed channel of the publisher with a new one.
+//
+// It does nothing if the closed channel was replaced already, e.g. by another retried publish,
+// so the concurrent retries do not close the channels re-opened by each other.
+func (p *RabbitMQPublisher) reopenChannel(ctx context.Context, closed amqpChannel) error {
+	// NOTE: Hold publishMu, so the delivery tags of the confirmed publishes start over on the new channel.
+	p.publishMu.Lock()
+	defer p.publishMu.Unlock()
+
+	if p.currentChannel() != closed {
+		return nil
+	}
+
+	channel, err := p.openChannel(ctx)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to re-open the RMQ publisher channel")
+	}
+
+	p.channelMu.Lock()
+	p.channel = channel
+	p.channelMu.Unlock()
+
+	p.lastTag = 0
+	_ = closed.Close()
+
+	p.logger.Info("RMQ publisher channel re-opened")
+
+	return nil
+}
+
+// isChannelClosedError reports whether err is returned because the channel or its connection is closed.
+func isChannelClosedError(err error) bool {
+	var amqpErr *amqp.Error
+	if errors.As(stacktrace.RootCause(err), &amqpErr) {
+		return amqpErr.Code == amqp.ChannelError
+	}
+
+	return false
+}
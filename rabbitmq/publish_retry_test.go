@@ -0,0 +1,177 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/backoff"
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// newRetryTestPublisher creates a publisher which creates the channels in order, and reuses the last one.
+func newRetryTestPublisher(
+	t *testing.T,
+	channels []*fakeChannel,
+	opts ...PublisherOption,
+) (*RabbitMQPublisher, func() int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var created int
+	createChannel := func(ctx context.Context) (amqpChannel, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		channel := channels[len(channels)-1]
+		if created < len(channels) {
+			channel = channels[created]
+		}
+		created++
+
+		return channel, nil
+	}
+
+	publisher, err := NewPublisher(
+		&fakeClient{},
+		logger.NewStructuredNopLogger("info"),
+		&NullMetric{},
+		append(opts, func(p *RabbitMQPublisher) {
+			p.createChannel = createChannel
+		})...,
+	)
+	require.NoError(t, err)
+
+	return publisher, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return created
+	}
+}
+
+func TestRabbitMQPublisher_PublishWithRetry(t *testing.T) {
+	retryConfig := RetryConfig{
+		MaxAttempts: 3,
+		Backoff:     &backoff.Config{Base: time.Millisecond, Max: time.Millisecond},
+	}
+
+	t.Run("when the channel is closed, it re-opens the channel and publishes the message", func(t *testing.T) {
+		t.Parallel()
+
+		closed := newFakeChannel(1)
+		closed.publishErr = amqp.ErrClosed
+		reopened := newFakeChannel(1)
+		publisher, created := newRetryTestPublisher(t, []*fakeChannel{closed, reopened})
+
+		err := publisher.PublishWithRetry(context.Background(), "orders", "orders.created", amqp.Publishing{}, retryConfig)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, created())
+		assert.True(t, closed.IsClosed())
+		assert.Len(t, reopened.Published(), 1)
+	})
+
+	t.Run("with confirms, it waits for the confirmation on the re-opened channel", func(t *testing.T) {
+		t.Parallel()
+
+		closed := newFakeChannel(1)
+		closed.publishErr = amqp.ErrClosed
+		reopened := newFakeChannel(1)
+		publisher, _ := newRetryTestPublisher(t, []*fakeChannel{closed, reopened}, WithPublishConfirms())
+
+		publishErr := make(chan error)
+		go func() {
+			publishErr <- publisher.PublishWithRetry(
+				context.Background(),
+				"orders",
+				"orders.created",
+				amqp.Publishing{},
+				retryConfig,
+			)
+		}()
+
+		<-reopened.publishedCh
+		reopened.confirm(true)
+		assert.NoError(t, <-publishErr)
+	})
+
+	t.Run("it returns a non-retryable error right away", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		channel.publishErr = &amqp.Error{Code: amqp.PreconditionFailed, Reason: "PRECONDITION_FAILED"}
+		publisher, created := newRetryTestPublisher(t, []*fakeChannel{channel})
+
+		err := publisher.PublishWithRetry(context.Background(), "orders", "orders.created", amqp.Publishing{}, retryConfig)
+		assert.Equal(t, channel.publishErr, stacktrace.RootCause(err))
+		assert.Equal(t, 1, created())
+	})
+
+	t.Run("once all the attempts fail, it returns the last error", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		channel.publishErr = amqp.ErrClosed
+		publisher, created := newRetryTestPublisher(t, []*fakeChannel{channel})
+
+		err := publisher.PublishWithRetry(context.Background(), "orders", "orders.created", amqp.Publishing{}, retryConfig)
+		assert.Equal(t, amqp.ErrClosed, stacktrace.RootCause(err))
+		assert.Contains(t, err.Error(), "after 3 attempts")
+		// NOTE: The channel is re-opened before the second and the third attempt.
+		assert.Equal(t, 3, created())
+	})
+
+	t.Run("when the context is done, it stops retrying", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		channel.publishErr = amqp.ErrClosed
+		publisher, created := newRetryTestPublisher(t, []*fakeChannel{channel})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := publisher.PublishWithRetry(ctx, "orders", "orders.created", amqp.Publishing{}, RetryConfig{
+			MaxAttempts: 5,
+			Backoff:     &backoff.Config{Base: time.Hour, Max: time.Hour},
+		})
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Equal(t, 1, created())
+	})
+
+	t.Run("it does not re-open the channel re-opened already by another publish", func(t *testing.T) {
+		t.Parallel()
+
+		closed := newFakeChannel(1)
+		reopened := newFakeChannel(1)
+		publisher, created := newRetryTestPublisher(t, []*fakeChannel{closed, reopened, newFakeChannel(1)})
+
+		require.NoError(t, publisher.reopenChannel(context.Background(), closed))
+		require.NoError(t, publisher.reopenChannel(context.Background(), closed))
+
+		assert.Equal(t, 2, created())
+		assert.Same(t, reopened, publisher.currentChannel())
+		assert.False(t, reopened.IsClosed())
+	})
+}
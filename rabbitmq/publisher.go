@@ -0,0 +1,274 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// ErrPublishNacked is returned when the broker nacks a published message, see WithPublishConfirms().
+var ErrPublishNacked = errors.New("RMQ broker nacked the published message")
+
+// PublishLatencyMetric is an optional interface implemented by metrics observing how long
+// the publishes of RabbitMQPublisher take, including the wait for their confirmation.
+type PublishLatencyMetric interface {
+	ObservePublishLatency(latency time.Duration)
+}
+
+// RabbitMQPublisher publishes messages on its own channel of a client.
+//
+// Unlike Producer, it can wait for the broker to confirm every published message, see WithPublishConfirms().
+// It is safe to publish from multiple goroutines.
+type RabbitMQPublisher struct {
+	client RabbitMQClientInterface
+	// releaseClient releases the reference of the publisher to its client, see RabbitMQClient.Retain()
+	releaseClient func() error
+	logger        logger.StructuredLogger
+	metric        Metric
+	clock         task.Clock
+	confirms      bool
+
+	createChannel func(ctx context.Context) (amqpChannel, error)
+	// channelMu protects the channel from being read while it is re-opened, see PublishWithRetry()
+	channelMu sync.RWMutex
+	channel   amqpChannel
+
+	// publishMu serializes the publishes, so the delivery tags of the confirmations follow their order
+	publishMu sync.Mutex
+	lastTag   uint64
+	// pendingMu protects the pending confirmations of the current channel, by the delivery tag of their message
+	pendingMu sync.Mutex
+	pending   map[uint64]chan amqp.Confirmation
+}
+
+// PublisherOption configures optional behavior of a RabbitMQPublisher.
+type PublisherOption func(p *RabbitMQPublisher)
+
+// WithPublishConfirms puts the publisher channel in confirm mode, so RabbitMQPublisher.Publish() returns
+// only once the broker confirms the message.
+//
+// A message nacked by the broker fails with ErrPublishNacked as the root cause.
+func WithPublishConfirms() PublisherOption {
+	return func(p *RabbitMQPublisher) {
+		p.confirms = true
+	}
+}
+
+// NewPublisher creates a publisher with a new channel of the client.
+//
+// The publisher holds a reference to the client until it is closed, see RabbitMQClient.Retain().
+func NewPublisher(
+	client RabbitMQClientInterface,
+	logger logger.StructuredLogger,
+	metric Metric,
+	opts ...PublisherOption,
+) (*RabbitMQPublisher, error) {
+	publisher := &RabbitMQPublisher{
+		client:        client,
+		logger:        logger,
+		metric:        metric,
+		clock:         task.RealClock(),
+		createChannel: newChannelFactory(client),
+	}
+
+	for _, opt := range opts {
+		opt(publisher)
+	}
+
+	channel, err := publisher.openChannel(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	publisher.channel = channel
+	publisher.releaseClient = retain(client)
+
+	return publisher, nil
+}
+
+// openChannel creates a new channel of the client, in confirm mode with WithPublishConfirms().
+func (p *RabbitMQPublisher) openChannel(ctx context.Context) (amqpChannel, error) {
+	channel, err := p.createChannel(ctx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create a channel")
+	}
+
+	if p.confirms {
+		err = channel.Confirm(false)
+		if err != nil {
+			_ = channel.Close()
+
+			return nil, stacktrace.Propagate(err, "failed to put the RMQ channel in confirm mode")
+		}
+
+		// NOTE: The delivery tags are valid only on the channel they come from, so every channel
+		// has its own pending confirmations.
+		pending := make(map[uint64]chan amqp.Confirmation)
+
+		p.pendingMu.Lock()
+		p.pending = pending
+		p.pendingMu.Unlock()
+
+		go p.dispatchConfirms(channel.NotifyPublish(make(chan amqp.Confirmation, 1)), pending)
+	}
+
+	return channel, nil
+}
+
+func (p *RabbitMQPublisher) currentChannel() amqpChannel {
+	p.channelMu.RLock()
+	defer p.channelMu.RUnlock()
+
+	return p.channel
+}
+
+// Publish publishes msg to the exchange with the routing key.
+//
+// With WithPublishConfirms(), it waits until the broker confirms the message, or until ctx is done.
+// The message is published regardless of ctx, which bounds only the wait for the confirmation.
+func (p *RabbitMQPublisher) Publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	startedAt := p.clock.Now()
+
+	err := p.publish(ctx, exchange, routingKey, msg)
+
+	p.metric.ObserveMsgPublish(err == nil)
+	if metric, ok := p.metric.(PublishLatencyMetric); ok {
+		metric.ObservePublishLatency(p.clock.Now().Sub(startedAt))
+	}
+
+	return err
+}
+
+func (p *RabbitMQPublisher) publish(ctx context.Context, exchange, routingKey string, msg amqp.Publishing) error {
+	if !p.confirms {
+		err := p.currentChannel().Publish(exchange, routingKey, false, false, msg)
+
+		return stacktrace.Propagate(err, "failed to publish RMQ message")
+	}
+
+	tag, confirmation, err := p.publishConfirmed(exchange, routingKey, msg)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to publish RMQ message")
+	}
+
+	select {
+	case <-ctx.Done():
+		p.removePending(tag, confirmation)
+
+		return stacktrace.Propagate(ctx.Err(), "stopped waiting for the RMQ confirmation of the published message")
+	case c, ok := <-confirmation:
+		if !ok {
+			return stacktrace.NewError("RMQ channel closed before confirming the published message")
+		}
+
+		if !c.Ack {
+			return stacktrace.Propagate(ErrPublishNacked, "RMQ message not confirmed")
+		}
+
+		return nil
+	}
+}
+
+// publishConfirmed publishes msg, and returns its delivery tag with the channel receiving its confirmation.
+func (p *RabbitMQPublisher) publishConfirmed(
+	exchange,
+	routingKey string,
+	msg amqp.Publishing,
+) (uint64, chan amqp.Confirmation, error) {
+	p.publishMu.Lock()
+	defer p.publishMu.Unlock()
+
+	// NOTE: Register the pending confirmation before publishing, since the broker may confirm the message
+	// before Publish returns.
+	tag := p.lastTag + 1
+	confirmation := make(chan amqp.Confirmation, 1)
+
+	p.pendingMu.Lock()
+	p.pending[tag] = confirmation
+	p.pendingMu.Unlock()
+
+	err := p.currentChannel().Publish(exchange, routingKey, false, false, msg)
+	if err != nil {
+		p.removePending(tag, confirmation)
+
+		return 0, nil, err
+	}
+
+	p.lastTag = tag
+
+	return tag, confirmation, nil
+}
+
+// removePending forgets the pending confirmation, unless the channel was re-opened since
+// and the tag belongs to a message published on the new channel.
+func (p *RabbitMQPublisher) removePending(tag uint64, confirmation chan amqp.Confirmation) {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
+	if p.pending[tag] == confirmation {
+		delete(p.pending, tag)
+	}
+}
+
+// dispatchConfirms passes the confirmations to the publishes waiting for them,
+// and closes the pending ones once the channel is closed.
+//
+// NOTE: The confirmations are read even when no publish waits for them, e.g. because its context is done,
+// otherwise amqp blocks the connection.
+func (p *RabbitMQPublisher) dispatchConfirms(
+	confirms <-chan amqp.Confirmation,
+	pending map[uint64]chan amqp.Confirmation,
+) {
+	for c := range confirms {
+		p.pendingMu.Lock()
+		confirmation, ok := pending[c.DeliveryTag]
+		delete(pending, c.DeliveryTag)
+		p.pendingMu.Unlock()
+
+		if ok {
+			confirmation <- c
+		}
+	}
+
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
+	for tag, confirmation := range pending {
+		close(confirmation)
+		delete(pending, tag)
+	}
+}
+
+// Close closes the channel of the publisher, and releases its reference to the client,
+// closing the connection if there are no more references to the client, see RabbitMQClient.Retain().
+func (p *RabbitMQPublisher) Close() error {
+	err := p.currentChannel().Close()
+	if err != nil {
+		p.logger.Warn("failed to close the RMQ publisher channel", logger.ErrorField(err))
+	}
+
+	err = p.releaseClient()
+
+	return stacktrace.Propagate(err, "failed to close RMQ publisher")
+}
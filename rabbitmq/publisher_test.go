@@ -0,0 +1,203 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+type publishMetric struct {
+	NullMetric
+
+	mu        sync.Mutex
+	published []bool
+	latencies []time.Duration
+}
+
+func (m *publishMetric) ObserveMsgPublish(success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.published = append(m.published, success)
+}
+
+func (m *publishMetric) ObservePublishLatency(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.latencies = append(m.latencies, latency)
+}
+
+func (m *publishMetric) Published() []bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]bool(nil), m.published...)
+}
+
+func (m *publishMetric) Latencies() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.latencies)
+}
+
+func newTestPublisher(t *testing.T, channel *fakeChannel, metric Metric, opts ...PublisherOption) *RabbitMQPublisher {
+	t.Helper()
+
+	opts = append(opts, func(p *RabbitMQPublisher) {
+		p.createChannel = func(ctx context.Context) (amqpChannel, error) {
+			return channel, nil
+		}
+	})
+
+	publisher, err := NewPublisher(&fakeClient{}, logger.NewStructuredNopLogger("info"), metric, opts...)
+	require.NoError(t, err)
+
+	return publisher
+}
+
+func TestRabbitMQPublisher_Publish(t *testing.T) {
+	t.Run("it publishes the message and observes it", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		metric := &publishMetric{}
+		publisher := newTestPublisher(t, channel, metric)
+
+		err := publisher.Publish(context.Background(), "orders", "orders.created", amqp.Publishing{Body: []byte("foo")})
+		require.NoError(t, err)
+
+		published := channel.Published()
+		require.Len(t, published, 1)
+		assert.Equal(t, "orders", published[0].exchange)
+		assert.Equal(t, "orders.created", published[0].key)
+		assert.Equal(t, []byte("foo"), published[0].msg.Body)
+
+		assert.Equal(t, []bool{true}, metric.Published())
+		assert.Equal(t, 1, metric.Latencies())
+	})
+
+	t.Run("with confirms, it returns once the broker confirms the message", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		metric := &publishMetric{}
+		publisher := newTestPublisher(t, channel, metric, WithPublishConfirms())
+
+		publishErr := make(chan error)
+		go func() {
+			publishErr <- publisher.Publish(context.Background(), "orders", "orders.created", amqp.Publishing{})
+		}()
+
+		<-channel.publishedCh
+		select {
+		case <-publishErr:
+			t.Fatal("the message was not confirmed yet")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		channel.confirm(true)
+		assert.NoError(t, <-publishErr)
+		assert.Equal(t, []bool{true}, metric.Published())
+	})
+
+	t.Run("with confirms, it returns an error once the broker nacks the message", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		metric := &publishMetric{}
+		publisher := newTestPublisher(t, channel, metric, WithPublishConfirms())
+
+		publishErr := make(chan error)
+		go func() {
+			publishErr <- publisher.Publish(context.Background(), "orders", "orders.created", amqp.Publishing{})
+		}()
+
+		<-channel.publishedCh
+		channel.confirm(false)
+
+		err := <-publishErr
+		assert.True(t, errors.Is(stacktrace.RootCause(err), ErrPublishNacked))
+		assert.Equal(t, []bool{false}, metric.Published())
+	})
+
+	t.Run("with confirms, it stops waiting for the confirmation once the context is done", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		publisher := newTestPublisher(t, channel, &NullMetric{}, WithPublishConfirms())
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		publishErr := make(chan error)
+		go func() {
+			publishErr <- publisher.Publish(ctx, "orders", "orders.created", amqp.Publishing{})
+		}()
+
+		<-channel.publishedCh
+		cancel()
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(<-publishErr))
+
+		// NOTE: The late confirmation of the abandoned message is not taken for the one of the next message.
+		channel.confirm(true)
+
+		go func() {
+			publishErr <- publisher.Publish(context.Background(), "orders", "orders.created", amqp.Publishing{})
+		}()
+
+		<-channel.publishedCh
+		select {
+		case <-publishErr:
+			t.Fatal("the message was not confirmed yet")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		channel.confirm(true)
+		assert.NoError(t, <-publishErr)
+	})
+
+	t.Run("with confirms, it returns an error once the channel closes before the confirmation", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		publisher := newTestPublisher(t, channel, &NullMetric{}, WithPublishConfirms())
+
+		publishErr := make(chan error)
+		go func() {
+			publishErr <- publisher.Publish(context.Background(), "orders", "orders.created", amqp.Publishing{})
+		}()
+
+		<-channel.publishedCh
+		assert.NoError(t, publisher.Close())
+
+		assert.EqualError(
+			t,
+			stacktrace.RootCause(<-publishErr),
+			"RMQ channel closed before confirming the published message",
+		)
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/palantir/stacktrace"
+
+	"github.com/sumup-oss/go-pkgs/backoff"
+)
+
+// WithPullMode makes the consumer poll the queue for deliveries with basic.get instead of
+// subscribing to it with basic.consume, e.g. for the low-traffic queues where a persistent
+// consumer is undesirable.
+//
+// Once the queue is empty, the next poll is delayed by the pollBackoff, which grows with every
+// consecutive empty poll and starts over from its base once a delivery is received.
+// The deliveries are passed to the same handler, and acknowledged the same way, as in the push mode.
+//
+// NOTE: The deliveries are pulled and processed one at a time, so WithBatch(), WithWorkers()
+// and WithPriorityOrdering() do not apply to the pull mode.
+func WithPullMode(pollBackoff *backoff.Config) ConsumerOption {
+	return func(c *Consumer) {
+		c.pollBackoff = pollBackoff
+	}
+}
+
+// pull handles the deliveries polled from the queue until it fails or stops.
+func (c *Consumer) pull(ctx context.Context, channel amqpChannel) error {
+	c.setConsuming(true)
+	defer c.setConsuming(false)
+
+	pollBackoff := backoff.NewBackoff(c.pollBackoff)
+
+	idle := c.newIdleWatch()
+	defer idle.stop()
+
+	for {
+		if ctx.Err() != nil {
+			c.logger.Warn("RMQ handler stopping")
+
+			return ctx.Err()
+		}
+
+		d, ok, err := channel.Get(c.handler.GetQueueName(), c.handler.QueueAutoAck())
+		if err != nil {
+			if ctx.Err() != nil {
+				// NOTE: The consumer closed the channel while stopping.
+				return ctx.Err()
+			}
+
+			return stacktrace.Propagate(err, "couldn't get a delivery from RMQ channel")
+		}
+
+		if !ok {
+			err = c.waitPoll(ctx, pollBackoff, idle)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		pollBackoff = backoff.NewBackoff(c.pollBackoff)
+
+		inflight := c.startInflight()
+		if inflight == nil {
+			return ctx.Err()
+		}
+
+		err = c.handleSingleDelivery(ctx, &d)
+		inflight.done()
+		if err != nil {
+			return stacktrace.Propagate(err, "failed to process RMQ delivery")
+		}
+
+		err = c.pauseOnErrorRate(ctx)
+		if err != nil {
+			return err
+		}
+
+		idle.reset()
+	}
+}
+
+// waitPoll waits for the next poll of the empty queue, as delayed by the pollBackoff.
+func (c *Consumer) waitPoll(ctx context.Context, pollBackoff *backoff.Backoff, idle *idleWatch) error {
+	timer := c.clock.NewTimer(pollBackoff.Next())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Warn("RMQ handler stopping")
+
+			return ctx.Err()
+		case <-idle.C():
+			idle.fired()
+			c.onIdle()
+		case <-timer.C():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/backoff"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithPullMode(t *testing.T) {
+	t.Run("it backs off between the polls of the empty queue and acks the pulled delivery", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		processed := make(chan string, 1)
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			processed <- string(msg.Body)
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithClock(clock),
+			WithPullMode(&backoff.Config{
+				Base: time.Second,
+				Max:  time.Minute,
+				Jitter: func(randomGen backoff.RandomGenerator, factor int64) time.Duration {
+					return time.Duration(factor)
+				},
+			}),
+		)
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		clock.BlockUntil(1)
+		assert.Equal(t, 1, channel.GetCalls())
+		clock.Advance(time.Second)
+
+		// NOTE: The second empty poll doubles the backoff.
+		clock.BlockUntil(1)
+		assert.Equal(t, 2, channel.GetCalls())
+		clock.Advance(time.Second)
+		assert.Equal(t, 2, channel.GetCalls())
+
+		channel.deliver(ack, 1, "foo")
+		clock.Advance(time.Second)
+		assert.Equal(t, "foo", <-processed)
+
+		// NOTE: Once a delivery is pulled, the backoff starts over from its base.
+		clock.BlockUntil(1)
+		assert.Equal(t, 4, channel.GetCalls())
+		clock.Advance(time.Second)
+		assert.Eventually(t, func() bool {
+			return channel.GetCalls() == 5
+		}, time.Second, time.Millisecond)
+
+		cancel()
+		assert.Error(t, <-runErr)
+		assert.Equal(t, []uint64{1}, ack.Acks())
+		assert.Equal(t, 0, channel.ConsumeCalls())
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+
+	"github.com/palantir/stacktrace"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// ErrQueueDeleted is the root cause of the error returned by Consumer.Run() when the broker cancels
+// the consumer, e.g. since its queue was deleted, see WithOnQueueDeleted().
+var ErrQueueDeleted = errors.New("RMQ consumer canceled by the broker, the queue may be deleted")
+
+// QueueDeletedPolicy is how the consumer reacts when the broker cancels it, e.g. since its queue was deleted.
+type QueueDeletedPolicy struct {
+	enabled bool
+	// setup is the topology re-declared before resuming, nil when the consumer must fail.
+	setup *Setup
+}
+
+// FailOnQueueDeleted makes the consumer fail with ErrQueueDeleted as the root cause.
+func FailOnQueueDeleted() QueueDeletedPolicy {
+	return QueueDeletedPolicy{enabled: true}
+}
+
+// RedeclareOnQueueDeleted makes the consumer re-declare the queue and resume consuming from it.
+//
+// The setup must declare the queue, along with its bindings, since they are deleted together with the queue.
+// It is meant for the consumers owning their topology, e.g. the ones using exclusive or auto-delete queues.
+func RedeclareOnQueueDeleted(setup *Setup) QueueDeletedPolicy {
+	return QueueDeletedPolicy{enabled: true, setup: setup}
+}
+
+// WithOnQueueDeleted sets how the consumer reacts when the broker cancels it, e.g. since its queue was deleted.
+//
+// Without it, the consumer fails the same way it does when the deliveries channel is closed for any other reason.
+func WithOnQueueDeleted(policy QueueDeletedPolicy) ConsumerOption {
+	return func(c *Consumer) {
+		c.queueDeletedPolicy = policy
+	}
+}
+
+// watchServerCancel subscribes to the cancellations of the consumer by the broker if there is a policy for them.
+func (c *Consumer) watchServerCancel(channel amqpChannel) {
+	if !c.queueDeletedPolicy.enabled {
+		return
+	}
+
+	// NOTE: Buffered, since the broker cancels the consumer once per Consume call,
+	// so the notification does not block the channel until the deliveries channel is closed.
+	c.serverCancelCh = channel.NotifyCancel(make(chan string, 1))
+}
+
+// deliveriesClosedError returns the error of the deliveries channel being closed,
+// with ErrQueueDeleted as the root cause if the broker canceled the consumer.
+func (c *Consumer) deliveriesClosedError() error {
+	select {
+	case <-c.serverCancelCh:
+		return stacktrace.Propagate(ErrQueueDeleted, "RMQ handler deliveries channel closed.")
+	default:
+		return stacktrace.NewError("RMQ handler deliveries channel closed.")
+	}
+}
+
+func (c *Consumer) mustRedeclare(err error) bool {
+	return c.queueDeletedPolicy.setup != nil && stacktrace.RootCause(err) == ErrQueueDeleted
+}
+
+// redeclareQueue re-declares the topology of the policy, so the consumer can resume consuming.
+func (c *Consumer) redeclareQueue(ctx context.Context) error {
+	c.logger.Warn(
+		"RMQ consumer canceled by the broker, re-declaring the queue",
+		logger.String("queue", c.handler.GetQueueName()),
+	)
+
+	err := c.client.Setup(ctx, c.queueDeletedPolicy.setup)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to re-declare the deleted RMQ queue")
+	}
+
+	return nil
+}
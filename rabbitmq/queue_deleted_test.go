@@ -0,0 +1,98 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOnQueueDeleted(t *testing.T) {
+	t.Run("it re-declares the queue and resumes consuming", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+		setup := &Setup{Queues: []QueueConfig{{Name: "test-queue", AutoDelete: true}}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		processed := make(chan string)
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			processed <- string(msg.Body)
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, client := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithOnQueueDeleted(RedeclareOnQueueDeleted(setup)),
+		)
+
+		ran := make(chan error)
+		go func() {
+			ran <- consumer.Run(ctx)
+		}()
+
+		channel.deliver(ack, 1, "before")
+		assert.Equal(t, "before", <-processed)
+
+		channel.cancelByServer("test-consumer")
+		channel.deliver(ack, 2, "after")
+		assert.Equal(t, "after", <-processed)
+
+		cancel()
+		assert.Error(t, <-ran)
+
+		require.Len(t, client.Setups(), 1)
+		assert.Same(t, setup, client.Setups()[0])
+		assert.Equal(t, 2, channel.ConsumeCalls())
+	})
+
+	t.Run("it fails with ErrQueueDeleted", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+
+		consumer, client := newTestConsumer(
+			newFakeHandler(ackAll),
+			channel,
+			ConsumerConfig{},
+			WithOnQueueDeleted(FailOnQueueDeleted()),
+		)
+
+		ran := make(chan error)
+		go func() {
+			ran <- consumer.Run(context.Background())
+		}()
+
+		// NOTE: Wait until the consumer subscribes to the cancellations, so it is notified.
+		assert.Eventually(t, func() bool {
+			return channel.ConsumeCalls() == 1
+		}, time.Second, time.Millisecond)
+		channel.cancelByServer("test-consumer")
+
+		err := <-ran
+		assert.Equal(t, ErrQueueDeleted, stacktrace.RootCause(err))
+		assert.Empty(t, client.Setups())
+	})
+}
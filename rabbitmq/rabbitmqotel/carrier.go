@@ -0,0 +1,48 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmqotel
+
+import (
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// HeadersCarrier adapts the RMQ message headers to propagation.TextMapCarrier,
+// so the OpenTelemetry propagators can inject into and extract from them.
+type HeadersCarrier amqp.Table
+
+var _ propagation.TextMapCarrier = HeadersCarrier(nil)
+
+// Get returns the value of the string header with this key, or "" if there is none.
+func (c HeadersCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+
+	return value
+}
+
+// Set sets the header with this key.
+func (c HeadersCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Keys lists the names of the headers.
+func (c HeadersCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rabbitmqotel integrates the rabbitmq consumers with OpenTelemetry.
+package rabbitmqotel
+
+import (
+	"context"
+	"strings"
+
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sumup-oss/go-pkgs/rabbitmq"
+)
+
+// Option configures the OpenTelemetry integration.
+type Option func(e *extractor)
+
+// WithPropagator sets the propagator used to extract the trace context from the delivery headers.
+// Defaults to the global propagator returned by otel.GetTextMapPropagator().
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(e *extractor) {
+		e.propagator = propagator
+	}
+}
+
+// WithBaggageHeaderPrefix copies the delivery headers starting with prefix into the baggage of the handler context.
+//
+// The prefix is stripped from the header name to get the baggage member key,
+// e.g. with the prefix "baggage-" the header "baggage-tenant: acme" becomes the baggage member "tenant=acme".
+// Only the string headers are copied, and the headers which are not valid baggage members are skipped.
+func WithBaggageHeaderPrefix(prefix string) Option {
+	return func(e *extractor) {
+		e.baggageHeaderPrefix = prefix
+	}
+}
+
+type extractor struct {
+	propagator          propagation.TextMapPropagator
+	baggageHeaderPrefix string
+	tracerProvider      trace.TracerProvider
+	spanAttributes      func(d *amqp.Delivery) []attribute.KeyValue
+}
+
+// DeliveryContext returns a function extracting the trace context and baggage from the delivery headers
+// into the handler context. Use it with rabbitmq.WithDeliveryContext().
+func DeliveryContext(opts ...Option) rabbitmq.DeliveryContextFunc {
+	e := &extractor{}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e.extract
+}
+
+func (e *extractor) extract(ctx context.Context, d *amqp.Delivery) context.Context {
+	propagator := e.propagator
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+
+	ctx = propagator.Extract(ctx, HeadersCarrier(d.Headers))
+
+	if e.baggageHeaderPrefix == "" {
+		return ctx
+	}
+
+	return e.extractBaggage(ctx, d.Headers)
+}
+
+func (e *extractor) extractBaggage(ctx context.Context, headers amqp.Table) context.Context {
+	bag := baggage.FromContext(ctx)
+	changed := false
+
+	for name, value := range headers {
+		if !strings.HasPrefix(name, e.baggageHeaderPrefix) {
+			continue
+		}
+
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		member, err := baggage.NewMemberRaw(strings.TrimPrefix(name, e.baggageHeaderPrefix), str)
+		if err != nil {
+			continue
+		}
+
+		withMember, err := bag.SetMember(member)
+		if err != nil {
+			continue
+		}
+
+		bag = withMember
+		changed = true
+	}
+
+	if !changed {
+		return ctx
+	}
+
+	return baggage.ContextWithBaggage(ctx, bag)
+}
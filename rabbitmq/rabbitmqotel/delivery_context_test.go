@@ -0,0 +1,99 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmqotel_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sumup-oss/go-pkgs/rabbitmq/rabbitmqotel"
+)
+
+func TestDeliveryContext(t *testing.T) {
+	t.Run("it extracts the prefixed headers into the baggage", func(t *testing.T) {
+		t.Parallel()
+
+		extract := rabbitmqotel.DeliveryContext(rabbitmqotel.WithBaggageHeaderPrefix("x-baggage-"))
+
+		ctx := extract(context.Background(), &amqp.Delivery{
+			Headers: amqp.Table{
+				"x-baggage-tenant": "acme",
+				"x-baggage-region": "eu west",
+				"x-baggage-count":  int64(3),
+				"x-retry-attempt":  int64(1),
+				"content-language": "en",
+			},
+		})
+
+		bag := baggage.FromContext(ctx)
+		assert.Equal(t, 2, bag.Len())
+		assert.Equal(t, "acme", bag.Member("tenant").Value())
+		assert.Equal(t, "eu west", bag.Member("region").Value())
+	})
+
+	t.Run("it keeps the baggage already in the context", func(t *testing.T) {
+		t.Parallel()
+
+		existing, err := baggage.NewMemberRaw("service", "payments")
+		assert.NoError(t, err)
+		bag, err := baggage.New(existing)
+		assert.NoError(t, err)
+
+		extract := rabbitmqotel.DeliveryContext(rabbitmqotel.WithBaggageHeaderPrefix("x-baggage-"))
+
+		ctx := extract(baggage.ContextWithBaggage(context.Background(), bag), &amqp.Delivery{
+			Headers: amqp.Table{"x-baggage-tenant": "acme"},
+		})
+
+		got := baggage.FromContext(ctx)
+		assert.Equal(t, "payments", got.Member("service").Value())
+		assert.Equal(t, "acme", got.Member("tenant").Value())
+	})
+
+	t.Run("without a baggage header prefix, it does not copy headers into the baggage", func(t *testing.T) {
+		t.Parallel()
+
+		extract := rabbitmqotel.DeliveryContext()
+
+		ctx := extract(context.Background(), &amqp.Delivery{
+			Headers: amqp.Table{"x-baggage-tenant": "acme"},
+		})
+
+		assert.Equal(t, 0, baggage.FromContext(ctx).Len())
+	})
+
+	t.Run("it extracts the trace context with the configured propagator", func(t *testing.T) {
+		t.Parallel()
+
+		extract := rabbitmqotel.DeliveryContext(rabbitmqotel.WithPropagator(propagation.TraceContext{}))
+
+		ctx := extract(context.Background(), &amqp.Delivery{
+			Headers: amqp.Table{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			},
+		})
+
+		spanContext := trace.SpanContextFromContext(ctx)
+		assert.True(t, spanContext.IsRemote())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spanContext.SpanID().String())
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmqotel
+
+import (
+	"context"
+
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sumup-oss/go-pkgs/rabbitmq"
+)
+
+const tracerName = "github.com/sumup-oss/go-pkgs/rabbitmq/rabbitmqotel"
+
+// WithTracerProvider sets the tracer provider used to start the processing spans.
+// Defaults to the global tracer provider returned by otel.GetTracerProvider().
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(e *extractor) {
+		e.tracerProvider = provider
+	}
+}
+
+// WithSpanAttributes adds the attributes returned by fn to the processing span of every delivery,
+// e.g. the routing key, the message type or a business key from the headers.
+func WithSpanAttributes(fn func(d *amqp.Delivery) []attribute.KeyValue) Option {
+	return func(e *extractor) {
+		e.spanAttributes = fn
+	}
+}
+
+// ProcessingSpan returns an interceptor processing every delivery within a span,
+// which is a child of the trace context extracted with DeliveryContext(), if any.
+// Use it with rabbitmq.WithDeliveryInterceptor().
+//
+// The span records the acknowledgement of the delivery, and the error returned by the handler, if any.
+func ProcessingSpan(opts ...Option) rabbitmq.DeliveryInterceptor {
+	e := &extractor{}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	provider := e.tracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	tracer := provider.Tracer(tracerName)
+
+	return func(
+		ctx context.Context,
+		d *amqp.Delivery,
+		next rabbitmq.ProcessFunc,
+	) (rabbitmq.HandlerAcknowledgement, error) {
+		attributes := []attribute.KeyValue{
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.operation", "process"),
+		}
+		if e.spanAttributes != nil {
+			attributes = append(attributes, e.spanAttributes(d)...)
+		}
+
+		ctx, span := tracer.Start(
+			ctx,
+			"process "+d.RoutingKey,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attributes...),
+		)
+		defer span.End()
+
+		acknowledgement, err := next(ctx)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			return acknowledgement, err
+		}
+
+		span.SetAttributes(attribute.String("messaging.rabbitmq.acknowledgement", acknowledgement.Acknowledgement.String()))
+
+		return acknowledgement, nil
+	}
+}
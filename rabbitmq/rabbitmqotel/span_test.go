@@ -0,0 +1,139 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmqotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sumup-oss/go-pkgs/rabbitmq"
+	"github.com/sumup-oss/go-pkgs/rabbitmq/rabbitmqotel"
+)
+
+func TestProcessingSpan(t *testing.T) {
+	newProvider := func() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+		recorder := tracetest.NewSpanRecorder()
+
+		return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)), recorder
+	}
+
+	t.Run("it records the configured attributes on the processing span", func(t *testing.T) {
+		t.Parallel()
+
+		provider, recorder := newProvider()
+		intercept := rabbitmqotel.ProcessingSpan(
+			rabbitmqotel.WithTracerProvider(provider),
+			rabbitmqotel.WithSpanAttributes(func(d *amqp.Delivery) []attribute.KeyValue {
+				orderID, _ := d.Headers["x-order-id"].(string)
+
+				return []attribute.KeyValue{
+					attribute.String("messaging.rabbitmq.routing_key", d.RoutingKey),
+					attribute.String("message.type", d.Type),
+					attribute.String("order.id", orderID),
+				}
+			}),
+		)
+
+		var handlerSpan trace.SpanContext
+		acknowledgement, err := intercept(
+			context.Background(),
+			&amqp.Delivery{
+				RoutingKey: "orders.created",
+				Type:       "OrderCreated",
+				Headers:    amqp.Table{"x-order-id": "42"},
+			},
+			func(ctx context.Context) (rabbitmq.HandlerAcknowledgement, error) {
+				handlerSpan = trace.SpanContextFromContext(ctx)
+
+				return rabbitmq.HandlerAcknowledgement{Acknowledgement: rabbitmq.Ack}, nil
+			},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, rabbitmq.Ack, acknowledgement.Acknowledgement)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		assert.Equal(t, "process orders.created", span.Name())
+		assert.Equal(t, trace.SpanKindConsumer, span.SpanKind())
+		assert.Equal(t, span.SpanContext(), handlerSpan)
+		assert.Subset(t, span.Attributes(), []attribute.KeyValue{
+			attribute.String("messaging.system", "rabbitmq"),
+			attribute.String("messaging.rabbitmq.routing_key", "orders.created"),
+			attribute.String("message.type", "OrderCreated"),
+			attribute.String("order.id", "42"),
+			attribute.String("messaging.rabbitmq.acknowledgement", "ack"),
+		})
+	})
+
+	t.Run("it records the error returned by the handler", func(t *testing.T) {
+		t.Parallel()
+
+		provider, recorder := newProvider()
+		intercept := rabbitmqotel.ProcessingSpan(rabbitmqotel.WithTracerProvider(provider))
+
+		errHandler := errors.New("handler failed")
+		_, err := intercept(
+			context.Background(),
+			&amqp.Delivery{RoutingKey: "orders.created"},
+			func(ctx context.Context) (rabbitmq.HandlerAcknowledgement, error) {
+				return rabbitmq.HandlerAcknowledgement{}, errHandler
+			},
+		)
+		assert.Equal(t, errHandler, err)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Equal(t, "handler failed", spans[0].Status().Description)
+		require.Len(t, spans[0].Events(), 1)
+		assert.Equal(t, "exception", spans[0].Events()[0].Name)
+	})
+
+	t.Run("it starts the span as a child of the trace context extracted from the delivery", func(t *testing.T) {
+		t.Parallel()
+
+		provider, recorder := newProvider()
+		extract := rabbitmqotel.DeliveryContext(rabbitmqotel.WithPropagator(propagation.TraceContext{}))
+		intercept := rabbitmqotel.ProcessingSpan(rabbitmqotel.WithTracerProvider(provider))
+
+		d := &amqp.Delivery{
+			Headers: amqp.Table{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			},
+		}
+		_, err := intercept(extract(context.Background(), d), d, func(ctx context.Context) (rabbitmq.HandlerAcknowledgement, error) {
+			return rabbitmq.HandlerAcknowledgement{Acknowledgement: rabbitmq.Ack}, nil
+		})
+		require.NoError(t, err)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	})
+}
@@ -0,0 +1,38 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmqotel
+
+import (
+	"github.com/sumup-oss/go-pkgs/rabbitmq"
+)
+
+// Tracing returns the consumer options continuing the trace of the publisher in the consumer:
+// the trace context is extracted from the delivery headers with DeliveryContext(),
+// and every delivery is processed within the span started by ProcessingSpan().
+//
+// The options are shared by both, so e.g. the propagator and the tracer provider are configured once:
+//
+//	consumer := rabbitmq.NewConsumer(client, handler, logger, metric, cfg, rabbitmqotel.Tracing(
+//		rabbitmqotel.WithPropagator(propagation.TraceContext{}),
+//		rabbitmqotel.WithTracerProvider(provider),
+//	)...)
+//
+// Consumers created without these options do not extract the trace context nor start spans.
+func Tracing(opts ...Option) []rabbitmq.ConsumerOption {
+	return []rabbitmq.ConsumerOption{
+		rabbitmq.WithDeliveryContext(DeliveryContext(opts...)),
+		rabbitmq.WithDeliveryInterceptor(ProcessingSpan(opts...)),
+	}
+}
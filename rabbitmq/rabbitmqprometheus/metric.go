@@ -0,0 +1,288 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rabbitmqprometheus provides a rabbitmq.Metric exporting Prometheus metrics.
+package rabbitmqprometheus
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sumup-oss/go-pkgs/rabbitmq"
+)
+
+// Ensure that Metric implements the rabbitmq metric interfaces.
+var (
+	_ rabbitmq.Metric               = (*Metric)(nil)
+	_ rabbitmq.LabeledMetric        = (*Metric)(nil)
+	_ rabbitmq.DeliveryCountMetric  = (*Metric)(nil)
+	_ rabbitmq.PublishLatencyMetric = (*Metric)(nil)
+	_ rabbitmq.ConsumerStateMetric  = (*Metric)(nil)
+	_ rabbitmq.HandlerLatencyMetric = (*Metric)(nil)
+)
+
+const (
+	labelQueue       = "queue"
+	labelConsumerTag = "consumer_tag"
+	labelResult      = "result"
+	labelType        = "type"
+	labelSuccess     = "success"
+)
+
+// Metric is a rabbitmq.Metric that exports the observations as Prometheus metrics,
+// labeled with the queue and the consumer tag of the consumer they come from.
+//
+// It exports:
+//
+//	<namespace>_rabbitmq_connections_total - counter of the connection attempts by result
+//	<namespace>_rabbitmq_channels_total - counter of the channel creation attempts by result
+//	<namespace>_rabbitmq_messages_delivered_total - counter of the consumed messages
+//	<namespace>_rabbitmq_acknowledgements_total - counter of the acks, nacks and rejects by type and success
+//	<namespace>_rabbitmq_messages_published_total - counter of the published messages by success
+//	<namespace>_rabbitmq_publish_duration_seconds - histogram of the publish latency of rabbitmq.RabbitMQPublisher
+//	<namespace>_rabbitmq_delivery_count - histogram of the delivery count reported by quorum queues
+//	<namespace>_rabbitmq_handler_panics_total - counter of the recovered panics of the consumer handlers
+//	<namespace>_rabbitmq_handler_duration_seconds - histogram of the processing latency of the consumer handlers
+//	<namespace>_rabbitmq_consumer_state - gauge of the state of the consumers, see rabbitmq.ConsumerStateMetric
+//
+// The observations which do not come from a consumer, e.g. the ones of a producer,
+// have empty queue and consumer_tag labels.
+type Metric struct {
+	labels prometheus.Labels
+
+	connections    *prometheus.CounterVec
+	channels       *prometheus.CounterVec
+	delivered      *prometheus.CounterVec
+	acks           *prometheus.CounterVec
+	published      *prometheus.CounterVec
+	publishLatency *prometheus.HistogramVec
+	deliveryCounts *prometheus.HistogramVec
+	panics         *prometheus.CounterVec
+	handlerLatency *prometheus.HistogramVec
+	consumerStates *prometheus.GaugeVec
+}
+
+// NewMetric creates a Metric and registers its metrics in reg.
+//
+// The consumers label the metric with their queue and consumer tag, see rabbitmq.WithMetricLabels():
+//
+//	metric, err := rabbitmqprometheus.NewMetric(prometheus.DefaultRegisterer, "myapp")
+//	consumer := rabbitmq.NewConsumer(client, handler, log, metric, cfg)
+func NewMetric(reg prometheus.Registerer, namespace string) (*Metric, error) {
+	consumerLabels := []string{labelQueue, labelConsumerTag}
+
+	metric := &Metric{
+		labels: prometheus.Labels{
+			labelQueue:       "",
+			labelConsumerTag: "",
+		},
+		connections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "connections_total",
+			Help:      "Total number of RabbitMQ connection attempts by result.",
+		}, append(consumerLabels, labelResult)),
+		channels: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "channels_total",
+			Help:      "Total number of RabbitMQ channel creation attempts by result.",
+		}, append(consumerLabels, labelResult)),
+		delivered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "messages_delivered_total",
+			Help:      "Total number of consumed RabbitMQ messages.",
+		}, consumerLabels),
+		acks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "acknowledgements_total",
+			Help:      "Total number of RabbitMQ message acknowledgements by type and success.",
+		}, append(consumerLabels, labelType, labelSuccess)),
+		published: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "messages_published_total",
+			Help:      "Total number of published RabbitMQ messages by success.",
+		}, append(consumerLabels, labelSuccess)),
+		publishLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "publish_duration_seconds",
+			Help:      "Duration of the RabbitMQ publishes, including the wait for their confirmation.",
+			Buckets:   prometheus.DefBuckets,
+		}, consumerLabels),
+		deliveryCounts: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "delivery_count",
+			Help:      "Number of times the consumed RabbitMQ messages were delivered before.",
+			Buckets:   prometheus.LinearBuckets(0, 1, 10),
+		}, consumerLabels),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "handler_panics_total",
+			Help:      "Total number of recovered panics of the RabbitMQ consumer handlers.",
+		}, consumerLabels),
+		handlerLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "handler_duration_seconds",
+			Help:      "Duration of the processing of the RabbitMQ deliveries by the consumer handlers.",
+			Buckets:   prometheus.DefBuckets,
+		}, consumerLabels),
+		consumerStates: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "rabbitmq",
+			Name:      "consumer_state",
+			Help:      "State of the RabbitMQ consumers: 0=down, 1=connected, 2=consuming, 3=paused.",
+		}, consumerLabels),
+	}
+
+	collectors := []prometheus.Collector{
+		metric.connections,
+		metric.channels,
+		metric.delivered,
+		metric.acks,
+		metric.published,
+		metric.publishLatency,
+		metric.deliveryCounts,
+		metric.panics,
+		metric.handlerLatency,
+		metric.consumerStates,
+	}
+	for _, collector := range collectors {
+		err := reg.Register(collector)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "failed to register the RabbitMQ metrics")
+		}
+	}
+
+	return metric, nil
+}
+
+// WithLabels implements rabbitmq.LabeledMetric.
+func (m *Metric) WithLabels(labels rabbitmq.MetricLabels) rabbitmq.Metric {
+	labeled := *m
+	labeled.labels = prometheus.Labels{
+		labelQueue:       labels.Queue,
+		labelConsumerTag: labels.ConsumerTag,
+	}
+
+	return &labeled
+}
+
+// ObserveRabbitMQConnectionFailed implements rabbitmq.Metric.
+func (m *Metric) ObserveRabbitMQConnectionFailed() {
+	m.connections.With(m.with(labelResult, "failed")).Inc()
+}
+
+// ObserveRabbitMQConnectionRetry implements rabbitmq.Metric.
+func (m *Metric) ObserveRabbitMQConnectionRetry() {
+	m.connections.With(m.with(labelResult, "retry")).Inc()
+}
+
+// ObserveRabbitMQConnection implements rabbitmq.Metric.
+func (m *Metric) ObserveRabbitMQConnection() {
+	m.connections.With(m.with(labelResult, "success")).Inc()
+}
+
+// ObserveRabbitMQChanelConnectionFailed implements rabbitmq.Metric.
+func (m *Metric) ObserveRabbitMQChanelConnectionFailed() {
+	m.channels.With(m.with(labelResult, "failed")).Inc()
+}
+
+// ObserveRabbitMQChanelConnectionRetry implements rabbitmq.Metric.
+func (m *Metric) ObserveRabbitMQChanelConnectionRetry() {
+	m.channels.With(m.with(labelResult, "retry")).Inc()
+}
+
+// ObserveRabbitMQChanelConnection implements rabbitmq.Metric.
+func (m *Metric) ObserveRabbitMQChanelConnection() {
+	m.channels.With(m.with(labelResult, "success")).Inc()
+}
+
+// ObserveMsgDelivered implements rabbitmq.Metric.
+func (m *Metric) ObserveMsgDelivered() {
+	m.delivered.With(m.labels).Inc()
+}
+
+// ObserveAck implements rabbitmq.Metric.
+func (m *Metric) ObserveAck(success bool) {
+	m.observeAcknowledgement("ack", success)
+}
+
+// ObserveNack implements rabbitmq.Metric.
+func (m *Metric) ObserveNack(success bool) {
+	m.observeAcknowledgement("nack", success)
+}
+
+// ObserveReject implements rabbitmq.Metric.
+func (m *Metric) ObserveReject(success bool) {
+	m.observeAcknowledgement("reject", success)
+}
+
+// ObserveMsgPublish implements rabbitmq.Metric.
+func (m *Metric) ObserveMsgPublish(success bool) {
+	m.published.With(m.with(labelSuccess, strconv.FormatBool(success))).Inc()
+}
+
+// ObservePublishLatency implements rabbitmq.PublishLatencyMetric.
+func (m *Metric) ObservePublishLatency(latency time.Duration) {
+	m.publishLatency.With(m.labels).Observe(latency.Seconds())
+}
+
+// ObserveDeliveryCount implements rabbitmq.DeliveryCountMetric.
+func (m *Metric) ObserveDeliveryCount(count int) {
+	m.deliveryCounts.With(m.labels).Observe(float64(count))
+}
+
+// ObserveHandlerPanic implements rabbitmq.Metric.
+func (m *Metric) ObserveHandlerPanic() {
+	m.panics.With(m.labels).Inc()
+}
+
+// ObserveHandlerLatency implements rabbitmq.HandlerLatencyMetric.
+func (m *Metric) ObserveHandlerLatency(latency time.Duration) {
+	m.handlerLatency.With(m.labels).Observe(latency.Seconds())
+}
+
+// ObserveConsumerState implements rabbitmq.ConsumerStateMetric.
+func (m *Metric) ObserveConsumerState(state rabbitmq.ConsumerState) {
+	m.consumerStates.With(m.labels).Set(float64(state))
+}
+
+func (m *Metric) observeAcknowledgement(acknowledgementType string, success bool) {
+	labels := m.with(labelType, acknowledgementType)
+	labels[labelSuccess] = strconv.FormatBool(success)
+
+	m.acks.With(labels).Inc()
+}
+
+// with returns the labels of the metric extended with the provided label.
+func (m *Metric) with(name, value string) prometheus.Labels {
+	labels := make(prometheus.Labels, len(m.labels)+2)
+	for k, v := range m.labels {
+		labels[k] = v
+	}
+
+	labels[name] = value
+
+	return labels
+}
@@ -0,0 +1,154 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmqprometheus_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/rabbitmq"
+	"github.com/sumup-oss/go-pkgs/rabbitmq/rabbitmqprometheus"
+)
+
+func TestNewMetric(t *testing.T) {
+	t.Run("when the metrics are already registered, it returns an error", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		_, err := rabbitmqprometheus.NewMetric(reg, "test")
+		require.NoError(t, err)
+
+		_, err = rabbitmqprometheus.NewMetric(reg, "test")
+		assert.Error(t, err)
+	})
+}
+
+func TestMetric_WithLabels(t *testing.T) {
+	t.Run("it labels the observations with the queue and the consumer tag", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		metric, err := rabbitmqprometheus.NewMetric(reg, "test")
+		require.NoError(t, err)
+
+		orders := metric.WithLabels(rabbitmq.MetricLabels{Queue: "orders", ConsumerTag: "orders-consumer"})
+		payments := metric.WithLabels(rabbitmq.MetricLabels{Queue: "payments", ConsumerTag: "payments-consumer"})
+
+		orders.ObserveMsgDelivered()
+		orders.ObserveMsgDelivered()
+		orders.ObserveAck(true)
+		orders.ObserveNack(false)
+		payments.ObserveMsgDelivered()
+		payments.ObserveReject(true)
+		metric.ObserveMsgPublish(true)
+		payments.ObserveHandlerPanic()
+
+		expected := `
+# HELP test_rabbitmq_acknowledgements_total Total number of RabbitMQ message acknowledgements by type and success.
+# TYPE test_rabbitmq_acknowledgements_total counter
+test_rabbitmq_acknowledgements_total{consumer_tag="orders-consumer",queue="orders",success="false",type="nack"} 1
+test_rabbitmq_acknowledgements_total{consumer_tag="orders-consumer",queue="orders",success="true",type="ack"} 1
+test_rabbitmq_acknowledgements_total{consumer_tag="payments-consumer",queue="payments",success="true",type="reject"} 1
+# HELP test_rabbitmq_handler_panics_total Total number of recovered panics of the RabbitMQ consumer handlers.
+# TYPE test_rabbitmq_handler_panics_total counter
+test_rabbitmq_handler_panics_total{consumer_tag="payments-consumer",queue="payments"} 1
+# HELP test_rabbitmq_messages_delivered_total Total number of consumed RabbitMQ messages.
+# TYPE test_rabbitmq_messages_delivered_total counter
+test_rabbitmq_messages_delivered_total{consumer_tag="orders-consumer",queue="orders"} 2
+test_rabbitmq_messages_delivered_total{consumer_tag="payments-consumer",queue="payments"} 1
+# HELP test_rabbitmq_messages_published_total Total number of published RabbitMQ messages by success.
+# TYPE test_rabbitmq_messages_published_total counter
+test_rabbitmq_messages_published_total{consumer_tag="",queue="",success="true"} 1
+`
+		err = testutil.GatherAndCompare(
+			reg,
+			strings.NewReader(expected),
+			"test_rabbitmq_acknowledgements_total",
+			"test_rabbitmq_handler_panics_total",
+			"test_rabbitmq_messages_delivered_total",
+			"test_rabbitmq_messages_published_total",
+		)
+		assert.NoError(t, err)
+	})
+
+	t.Run("it labels the delivery count", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		metric, err := rabbitmqprometheus.NewMetric(reg, "test")
+		require.NoError(t, err)
+
+		labeled := metric.WithLabels(rabbitmq.MetricLabels{Queue: "orders", ConsumerTag: "orders-consumer"})
+		labeled.(rabbitmq.DeliveryCountMetric).ObserveDeliveryCount(2)
+
+		count, err := testutil.GatherAndCount(reg, "test_rabbitmq_delivery_count")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("it observes the publish latency", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		metric, err := rabbitmqprometheus.NewMetric(reg, "test")
+		require.NoError(t, err)
+
+		metric.ObservePublishLatency(20 * time.Millisecond)
+
+		count, err := testutil.GatherAndCount(reg, "test_rabbitmq_publish_duration_seconds")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("it labels the handler latency", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		metric, err := rabbitmqprometheus.NewMetric(reg, "test")
+		require.NoError(t, err)
+
+		labeled := metric.WithLabels(rabbitmq.MetricLabels{Queue: "orders", ConsumerTag: "orders-consumer"})
+		labeled.(rabbitmq.HandlerLatencyMetric).ObserveHandlerLatency(20 * time.Millisecond)
+
+		count, err := testutil.GatherAndCount(reg, "test_rabbitmq_handler_duration_seconds")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+	t.Run("it labels the consumer state", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		metric, err := rabbitmqprometheus.NewMetric(reg, "test")
+		require.NoError(t, err)
+
+		labeled := metric.WithLabels(rabbitmq.MetricLabels{Queue: "orders", ConsumerTag: "orders-consumer"})
+		labeled.(rabbitmq.ConsumerStateMetric).ObserveConsumerState(rabbitmq.ConsumerConsuming)
+		labeled.(rabbitmq.ConsumerStateMetric).ObserveConsumerState(rabbitmq.ConsumerPaused)
+
+		expected := `
+# HELP test_rabbitmq_consumer_state State of the RabbitMQ consumers: 0=down, 1=connected, 2=consuming, 3=paused.
+# TYPE test_rabbitmq_consumer_state gauge
+test_rabbitmq_consumer_state{consumer_tag="orders-consumer",queue="orders"} 3
+`
+		err = testutil.GatherAndCompare(reg, strings.NewReader(expected), "test_rabbitmq_consumer_state")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// RetryAttemptHeader is the message header holding how many times a message was retried
+// through the retry ladder.
+const RetryAttemptHeader = "x-retry-attempt"
+
+// WithRetryLadder enables delayed retries of the messages the handler wants to requeue.
+//
+// Instead of requeueing a message immediately, when the handler returns Nack or Reject with Requeue,
+// the consumer re-publishes the message to a delay queue and acks the original one.
+// The delay queue is chosen by the retry attempt of the message, e.g. with a ladder of
+// (5s, 30s, 2m), the first retry waits 5 seconds, the second 30 seconds and every next one 2 minutes.
+// The retry attempt is tracked by the RetryAttemptHeader header.
+// When all the steps of the ladder are exhausted the message is nacked without requeue,
+// so it gets dead-lettered if the queue has a dead letter exchange.
+//
+// The delay queues must be declared in advance, see RetryLadderSetup().
+func WithRetryLadder(ladder []time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.retryLadder = ladder
+	}
+}
+
+// RetryLadderQueueName returns the name of the delay queue used for the provided queue and delay.
+func RetryLadderQueueName(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queue, delay)
+}
+
+// RetryLadderSetup returns the setup declaring the delay queues used by WithRetryLadder().
+//
+// Every delay queue has a message TTL equal to its delay and dead-letters the expired messages
+// back to the provided queue through the default exchange.
+func RetryLadderSetup(queue string, ladder []time.Duration) *Setup {
+	setup := &Setup{}
+
+	declared := make(map[time.Duration]bool)
+	for _, delay := range ladder {
+		if declared[delay] {
+			continue
+		}
+
+		declared[delay] = true
+		setup.Queues = append(setup.Queues, QueueConfig{
+			Name:    RetryLadderQueueName(queue, delay),
+			Durable: true,
+			Args: amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			},
+		})
+	}
+
+	return setup
+}
+
+// retryDelay returns the delay of the retry attempt, and false when the ladder is exhausted.
+//
+// Attempts start from 1.
+func retryDelay(ladder []time.Duration, attempt int) (time.Duration, bool) {
+	if attempt < 1 || attempt > len(ladder) {
+		return 0, false
+	}
+
+	return ladder[attempt-1], true
+}
+
+// retryAttempt returns how many times the delivery was already retried through the retry ladder.
+func retryAttempt(d *amqp.Delivery) int {
+	return intHeader(d.Headers, RetryAttemptHeader)
+}
+
+// retryLater re-publishes the delivery to the delay queue of its next retry attempt
+// and returns how the original delivery must be acknowledged.
+func (c *Consumer) retryLater(d *amqp.Delivery) HandlerAcknowledgement {
+	attempt := retryAttempt(d) + 1
+
+	delay, ok := retryDelay(c.retryLadder, attempt)
+	if !ok {
+		c.logger.Warn(
+			"RMQ message retry attempts exhausted",
+			logger.Int("attempt", attempt),
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
+
+		return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: false}
+	}
+
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		headers[key] = value
+	}
+	headers[RetryAttemptHeader] = int64(attempt)
+
+	err := c.republish(
+		"",
+		RetryLadderQueueName(c.handler.GetQueueName(), delay),
+		republishing(d, headers),
+	)
+	c.metric.ObserveMsgPublish(err == nil)
+
+	if err != nil {
+		c.logger.Error(
+			"failed to re-publish RMQ message for a delayed retry, requeueing it",
+			logger.ErrorField(err),
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
+
+		return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}
+	}
+
+	return HandlerAcknowledgement{Acknowledgement: Ack}
+}
+
+// republishing returns the publishing re-publishing the delivery with the provided headers.
+func republishing(d *amqp.Delivery, headers amqp.Table) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		UserId:          d.UserId,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryLadderSetup(t *testing.T) {
+	t.Run("it declares a delay queue per ladder step dead-lettering back to the queue", func(t *testing.T) {
+		t.Parallel()
+
+		setup := RetryLadderSetup("orders", []time.Duration{5 * time.Second, 30 * time.Second, 30 * time.Second})
+
+		require.Len(t, setup.Queues, 2)
+		assert.Equal(t, "orders.retry.5s", setup.Queues[0].Name)
+		assert.Equal(t, "orders.retry.30s", setup.Queues[1].Name)
+		assert.Equal(
+			t,
+			amqp.Table{
+				"x-message-ttl":             int64(5000),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": "orders",
+			},
+			setup.Queues[0].Args,
+		)
+	})
+}
+
+func TestWithRetryLadder(t *testing.T) {
+	t.Run("it re-publishes the requeued messages to the delay queue of their retry attempt", func(t *testing.T) {
+		t.Parallel()
+
+		ladder := []time.Duration{5 * time.Second, 30 * time.Second}
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := 0
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			received++
+			if received == 3 {
+				cancel()
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Nack, Requeue: true}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithRetryLadder(ladder))
+
+		channel.deliverWithHeaders(ack, 1, "first", nil)
+		channel.deliverWithHeaders(ack, 2, "second", amqp.Table{RetryAttemptHeader: int64(1)})
+		channel.deliverWithHeaders(ack, 3, "exhausted", amqp.Table{RetryAttemptHeader: int64(2)})
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		published := channel.Published()
+		require.Len(t, published, 2)
+
+		assert.Equal(t, "", published[0].exchange)
+		assert.Equal(t, "test-queue.retry.5s", published[0].key)
+		assert.Equal(t, "first", string(published[0].msg.Body))
+		assert.Equal(t, int64(1), published[0].msg.Headers[RetryAttemptHeader])
+
+		assert.Equal(t, "test-queue.retry.30s", published[1].key)
+		assert.Equal(t, "second", string(published[1].msg.Body))
+		assert.Equal(t, int64(2), published[1].msg.Headers[RetryAttemptHeader])
+
+		assert.Equal(t, []uint64{1, 2}, ack.Acks())
+		assert.Equal(t, []uint64{3}, ack.Nacks())
+	})
+
+	t.Run("when the re-publish fails, it requeues the message", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(1)
+		channel.publishErr = assert.AnError
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Reject, Requeue: true}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{}, WithRetryLadder([]time.Duration{time.Second}))
+
+		channel.deliver(ack, 1, "foo")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.Empty(t, ack.Acks())
+		assert.Equal(t, []uint64{1}, ack.Nacks())
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+)
+
+// IsRetryable reports whether the operation failed with err may succeed when retried, e.g. after reconnecting.
+//
+// The amqp errors are classified by their reply code. The connection and resource errors are retryable,
+// e.g. amqp.ConnectionForced when the broker restarts, or amqp.ErrClosed when the channel is closed,
+// while the errors which persist until the setup or the configuration changes are not,
+// e.g. amqp.NotFound for a missing queue or amqp.AccessRefused for wrong credentials.
+//
+// The context errors are not retryable. Any other error, e.g. a network error while dialing,
+// is considered retryable, since it gives no hint it would fail again.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	err = stacktrace.RootCause(err)
+
+	if isContextError(err) {
+		return false
+	}
+
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) {
+		return isRetryableCode(amqpErr.Code)
+	}
+
+	return true
+}
+
+func isRetryableCode(code int) bool {
+	switch code {
+	case amqp.ConnectionForced,
+		amqp.ChannelError,
+		amqp.ResourceLocked,
+		amqp.ResourceError,
+		amqp.InternalError,
+		amqp.FrameError,
+		amqp.UnexpectedFrame:
+		return true
+	case amqp.ContentTooLarge,
+		amqp.NoRoute,
+		amqp.NoConsumers,
+		amqp.InvalidPath,
+		amqp.AccessRefused,
+		amqp.NotFound,
+		amqp.PreconditionFailed,
+		amqp.SyntaxError,
+		amqp.CommandInvalid,
+		amqp.NotAllowed,
+		amqp.NotImplemented:
+		return false
+	default:
+		return true
+	}
+}
+
+func isContextError(err error) bool {
+	err = stacktrace.RootCause(err)
+
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -16,24 +16,39 @@ package rabbitmq
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/palantir/stacktrace"
-	"go.uber.org/zap"
 
 	"github.com/sumup-oss/go-pkgs/backoff"
 	"github.com/sumup-oss/go-pkgs/logger"
+	"github.com/sumup-oss/go-pkgs/task"
 )
 
+// RetryableConsumer runs a Consumer, and reconnects it once its channel or connection closes,
+// e.g. when the TCP connection to RMQ drops.
+//
+// Every time it reconnects, it creates a new client with the RabbitClientConfig and a new Consumer
+// with the ConsumerConfig, so the consumer declares its queue and consumes again.
+// The reconnect attempts are delayed by the BackoffConfig, and Run() returns the last error
+// once the MaxRetryAttempts are exhausted, or nil once the context is canceled.
 type RetryableConsumer struct {
 	config        RetryableConsumerConfig
 	logger        logger.StructuredLogger
 	metric        Metric
 	handler       Handler
 	clientFactory func(ctx context.Context, config *ClientConfig) (RabbitMQClientInterface, error)
+	opts          []ConsumerOption
+	clock         task.Clock
+
+	// mu protects the config.ConsumerConfig and the consumer properties
+	mu       sync.Mutex
+	consumer *Consumer
 }
 
 type RetryableConsumerConfig struct {
+	// MaxRetryAttempts is the number of the reconnect attempts after which Run() gives up, 0 means unlimited.
 	MaxRetryAttempts int
 	// healthCheckFactor is a number representing how much N multiplied by backoffConfig.Max time is needed
 	// for a block of code to run w/o returning an error, to consider it healthy.
@@ -51,6 +66,7 @@ func NewRetryableConsumer(
 	logger logger.StructuredLogger,
 	metric Metric,
 	handler Handler,
+	opts ...ConsumerOption,
 ) *RetryableConsumer {
 	return &RetryableConsumer{
 		clientFactory: newClientFactory,
@@ -58,6 +74,8 @@ func NewRetryableConsumer(
 		handler:       handler,
 		logger:        logger,
 		metric:        metric,
+		opts:          opts,
+		clock:         clockFromOptions(opts),
 	}
 }
 
@@ -66,30 +84,42 @@ func (c *RetryableConsumer) Run(ctx context.Context) error {
 	currentRetryAttempts := 0
 
 	for {
-		startTime := time.Now()
+		startTime := c.clock.Now()
 		err := c.doRun(ctx)
 		if err != nil {
-			c.logger.Error("consumer run failed with error", zap.Error(err))
+			c.logger.Error("consumer run failed with error", logger.ErrorField(err))
 
-			if c.config.MaxRetryAttempts != 0 && currentRetryAttempts > c.config.MaxRetryAttempts {
-				return stacktrace.NewError("retry attempts exceeded")
+			// NOTE: A context error while ctx is not done comes from the consumer canceling itself,
+			// e.g. once its channel closes, so it is retried as well.
+			if ctx.Err() == nil && !IsRetryable(err) && !isContextError(err) {
+				return stacktrace.Propagate(err, "consumer run failed with non-retryable error")
 			}
 
-			if time.Since(startTime) > time.Duration(c.config.HealthCheckFactor)*c.config.BackoffConfig.Max {
+			if c.clock.Now().Sub(startTime) > time.Duration(c.config.HealthCheckFactor)*c.config.BackoffConfig.Max {
 				consumerBackoff = backoff.NewBackoff(c.config.BackoffConfig)
 				currentRetryAttempts = 0
-			} else {
-				currentRetryAttempts += 1
 			}
 
+			if c.config.MaxRetryAttempts != 0 && currentRetryAttempts >= c.config.MaxRetryAttempts {
+				return stacktrace.Propagate(err, "retry attempts exceeded")
+			}
+
+			currentRetryAttempts += 1
+
 			backoffDuration := consumerBackoff.Next()
 
+			c.logger.Warn(
+				"reconnecting the RMQ consumer",
+				logger.Int("attempt", currentRetryAttempts),
+				logger.Duration("backoff", backoffDuration),
+			)
+
 			select {
 			case <-ctx.Done():
 				c.logger.Info("received context cancel")
 
 				return nil
-			case <-time.After(backoffDuration):
+			case <-c.clock.After(backoffDuration):
 				continue
 			}
 		}
@@ -115,7 +145,9 @@ func (c *RetryableConsumer) doRun(ctx context.Context) error {
 
 	c.logger.Info("starting to run the consumer")
 
-	consumer := NewConsumer(client, c.handler, c.logger, c.metric, c.config.ConsumerConfig)
+	consumer := c.newConsumer(client)
+	defer c.setConsumer(nil)
+
 	err = consumer.Run(ctx)
 	if err != nil {
 		return stacktrace.Propagate(err, "RabbitMQ consumer Run error")
@@ -123,3 +155,40 @@ func (c *RetryableConsumer) doRun(ctx context.Context) error {
 
 	return nil
 }
+
+// SetPrefetch changes the prefetch count of the consumer at runtime, see Consumer.SetPrefetch().
+//
+// The new prefetch count is applied to the consumer currently running, if any,
+// and it is kept for the consumers created once the consumer reconnects.
+func (c *RetryableConsumer) SetPrefetch(count int) error {
+	if count < 0 {
+		return stacktrace.NewError("invalid RMQ prefetch count: %d", count)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.config.ConsumerConfig.PrefetchCount = count
+
+	if c.consumer != nil {
+		return c.consumer.SetPrefetch(count)
+	}
+
+	return nil
+}
+
+func (c *RetryableConsumer) newConsumer(client RabbitMQClientInterface) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.consumer = NewConsumer(client, c.handler, c.logger, c.metric, c.config.ConsumerConfig, c.opts...)
+
+	return c.consumer
+}
+
+func (c *RetryableConsumer) setConsumer(consumer *Consumer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.consumer = consumer
+}
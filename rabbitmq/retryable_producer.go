@@ -20,7 +20,6 @@ import (
 	"time"
 
 	"github.com/palantir/stacktrace"
-	"go.uber.org/zap"
 
 	"github.com/sumup-oss/go-pkgs/backoff"
 	"github.com/sumup-oss/go-pkgs/logger"
@@ -110,11 +109,15 @@ func (p *RetryableProducer) newProducer(ctx context.Context) (*Producer, error)
 	producer, err := NewProducer(client, p.logger, p.metric)
 	if err != nil {
 		connCloseErr := client.Close()
-		p.logger.Error("cannot close RabbitMQ client connection", zap.Error(connCloseErr))
+		p.logger.Error("cannot close RabbitMQ client connection", logger.ErrorField(connCloseErr))
 
 		return nil, stacktrace.Propagate(err, "RabbitMQ Failed to create new producer")
 	}
 
+	// NOTE: The producer holds its own reference to the client, so the connection is closed
+	// once the producer is closed.
+	_ = client.Close()
+
 	return producer, nil
 }
 
@@ -125,7 +128,11 @@ func (p *RetryableProducer) newProducerWithBackoff(ctx context.Context) (*Produc
 	for {
 		producer, err := p.newProducer(ctx)
 		if err != nil {
-			p.logger.Error("producer connection failed with error", zap.Error(err))
+			p.logger.Error("producer connection failed with error", logger.ErrorField(err))
+
+			if ctx.Err() == nil && !IsRetryable(err) {
+				return nil, stacktrace.Propagate(err, "producer connection failed with non-retryable error")
+			}
 
 			if p.config.MaxRetryAttempts != 0 && currentRetryAttempts > p.config.MaxRetryAttempts {
 				return nil, stacktrace.NewError("retry attempts exceeded")
@@ -149,7 +156,7 @@ func (p *RetryableProducer) initProducer(ctx context.Context) {
 	for {
 		producer, err := p.newProducerWithBackoff(ctx)
 		if err != nil {
-			p.logger.Info("failed to create producer with backoff", zap.Error(err))
+			p.logger.Info("failed to create producer with backoff", logger.ErrorField(err))
 
 			return
 		}
@@ -166,7 +173,7 @@ func (p *RetryableProducer) initProducer(ctx context.Context) {
 
 			err := producer.Close()
 			if err != nil {
-				p.logger.Error("error when closing the producer", zap.Error(err))
+				p.logger.Error("error when closing the producer", logger.ErrorField(err))
 			}
 
 			return
@@ -0,0 +1,235 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/backoff"
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{name: "no error", err: nil, retryable: false},
+		{name: "connection forced", err: &amqp.Error{Code: amqp.ConnectionForced}, retryable: true},
+		{name: "closed channel", err: amqp.ErrClosed, retryable: true},
+		{name: "resource locked", err: &amqp.Error{Code: amqp.ResourceLocked}, retryable: true},
+		{name: "internal error", err: &amqp.Error{Code: amqp.InternalError}, retryable: true},
+		{name: "frame error", err: &amqp.Error{Code: amqp.FrameError}, retryable: true},
+		{name: "not found", err: &amqp.Error{Code: amqp.NotFound}, retryable: false},
+		{name: "access refused", err: &amqp.Error{Code: amqp.AccessRefused}, retryable: false},
+		{name: "wrong credentials", err: amqp.ErrCredentials, retryable: false},
+		{name: "precondition failed", err: &amqp.Error{Code: amqp.PreconditionFailed}, retryable: false},
+		{name: "not allowed", err: &amqp.Error{Code: amqp.NotAllowed}, retryable: false},
+		{name: "unknown code", err: &amqp.Error{Code: 999}, retryable: true},
+		{
+			name:      "propagated amqp error",
+			err:       stacktrace.Propagate(&amqp.Error{Code: amqp.NotFound}, "couldn't start consuming"),
+			retryable: false,
+		},
+		{name: "wrapped amqp error", err: fmt.Errorf("consume: %w", amqp.ErrClosed), retryable: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, retryable: true},
+		{name: "EOF", err: io.EOF, retryable: true},
+		{name: "context canceled", err: stacktrace.Propagate(context.Canceled, "stopped"), retryable: false},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, retryable: false},
+		{name: "other error", err: errors.New("handler failed"), retryable: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.retryable, IsRetryable(test.err))
+		})
+	}
+}
+
+func TestRetryableConsumer_Run(t *testing.T) {
+	t.Run("it stops retrying on a non-retryable error", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		consumer := NewRetryableConsumer(
+			func(ctx context.Context, config *ClientConfig) (RabbitMQClientInterface, error) {
+				attempts++
+
+				return nil, amqp.ErrCredentials
+			},
+			RetryableConsumerConfig{
+				BackoffConfig: &backoff.Config{Base: time.Millisecond, Max: time.Millisecond},
+			},
+			logger.NewStructuredNopLogger("info"),
+			&NullMetric{},
+			newFakeHandler(ackAll),
+		)
+
+		err := consumer.Run(context.Background())
+		assert.Equal(t, amqp.ErrCredentials, stacktrace.RootCause(err))
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("it returns the last error once the retry attempts are exhausted", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		log := newCapturingLogger()
+		consumer := NewRetryableConsumer(
+			func(ctx context.Context, config *ClientConfig) (RabbitMQClientInterface, error) {
+				attempts++
+
+				return nil, amqp.ErrClosed
+			},
+			RetryableConsumerConfig{
+				MaxRetryAttempts:  2,
+				HealthCheckFactor: 1000,
+				BackoffConfig:     &backoff.Config{Base: time.Millisecond, Max: time.Millisecond},
+			},
+			log,
+			&NullMetric{},
+			newFakeHandler(ackAll),
+		)
+
+		err := consumer.Run(context.Background())
+		assert.Equal(t, amqp.ErrClosed, stacktrace.RootCause(err))
+		assert.Equal(t, 3, attempts)
+
+		reconnects := log.logs.FilterMessage("reconnecting the RMQ consumer").All()
+		require.Len(t, reconnects, 2)
+		assert.Equal(t, int64(1), reconnects[0].ContextMap()["attempt"])
+		assert.Equal(t, int64(2), reconnects[1].ContextMap()["attempt"])
+	})
+
+	t.Run("it reconnects once the channel is closed", func(t *testing.T) {
+		t.Parallel()
+
+		channels := []*fakeChannel{newFakeChannel(0), newFakeChannel(0)}
+		channelIdx := make(chan int, len(channels))
+		for i := range channels {
+			channelIdx <- i
+		}
+
+		var clients int
+		consumer := NewRetryableConsumer(
+			func(ctx context.Context, config *ClientConfig) (RabbitMQClientInterface, error) {
+				clients++
+
+				return &fakeClient{}, nil
+			},
+			RetryableConsumerConfig{
+				BackoffConfig: &backoff.Config{Base: time.Millisecond, Max: time.Millisecond},
+			},
+			logger.NewStructuredNopLogger("info"),
+			&NullMetric{},
+			newFakeHandler(ackAll),
+			func(c *Consumer) {
+				c.createChannel = func(ctx context.Context) (amqpChannel, error) {
+					return channels[<-channelIdx], nil
+				}
+			},
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		assert.Eventually(t, func() bool {
+			return channels[0].ConsumeCalls() == 1
+		}, time.Second, time.Millisecond)
+
+		channels[0].closeWithError(&amqp.Error{Code: amqp.ConnectionForced, Reason: "shutdown", Server: true})
+
+		assert.Eventually(t, func() bool {
+			return channels[1].ConsumeCalls() == 1
+		}, time.Second, time.Millisecond)
+
+		cancel()
+		assert.NoError(t, <-runErr)
+		assert.Equal(t, 2, clients)
+	})
+
+	t.Run("it keeps the prefetch count set at runtime once it reconnects", func(t *testing.T) {
+		t.Parallel()
+
+		channels := []*fakeChannel{newFakeChannel(0), newFakeChannel(0)}
+		channelIdx := make(chan int, len(channels))
+		for i := range channels {
+			channelIdx <- i
+		}
+
+		consumer := NewRetryableConsumer(
+			func(ctx context.Context, config *ClientConfig) (RabbitMQClientInterface, error) {
+				return &fakeClient{}, nil
+			},
+			RetryableConsumerConfig{
+				BackoffConfig:  &backoff.Config{Base: time.Millisecond, Max: time.Millisecond},
+				ConsumerConfig: ConsumerConfig{PrefetchCount: 10},
+			},
+			logger.NewStructuredNopLogger("info"),
+			&NullMetric{},
+			newFakeHandler(ackAll),
+			func(c *Consumer) {
+				c.createChannel = func(ctx context.Context) (amqpChannel, error) {
+					return channels[<-channelIdx], nil
+				}
+			},
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		assert.Eventually(t, func() bool {
+			return channels[0].ConsumeCalls() == 1
+		}, time.Second, time.Millisecond)
+
+		assert.NoError(t, consumer.SetPrefetch(25))
+		assert.Equal(t, []int{10, 25}, channels[0].QosCalls())
+
+		channels[0].closeWithError(&amqp.Error{Code: amqp.ConnectionForced, Reason: "shutdown", Server: true})
+
+		assert.Eventually(t, func() bool {
+			return channels[1].ConsumeCalls() == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, []int{25}, channels[1].QosCalls())
+
+		cancel()
+		assert.NoError(t, <-runErr)
+	})
+}
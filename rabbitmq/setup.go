@@ -1,6 +1,9 @@
 package rabbitmq
 
-import "github.com/streadway/amqp"
+import (
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+)
 
 type QueueConfig struct {
 	Name       string
@@ -34,3 +37,37 @@ type Setup struct {
 	Queues        []QueueConfig
 	QueueBindings []QueueBindConfig
 }
+
+// Topology is the set of the exchanges, queues and bindings of a service,
+// declared together by RabbitMQClient.DeclareTopology().
+type Topology = Setup
+
+// declareTopology declares the exchanges, then the queues, then the bindings of the topology,
+// and stops at the first one failing.
+func declareTopology(channel topologyChannel, topology *Topology) error {
+	for _, e := range topology.Exchanges {
+		err := channel.ExchangeDeclare(e.Name, e.Kind, e.Durable, e.AutoDelete, e.Internal, e.NoWait, e.Args)
+		if err != nil {
+			return stacktrace.Propagate(err, "could not declare exchange %s", e.Name)
+		}
+	}
+
+	for _, q := range topology.Queues {
+		_, err := channel.QueueDeclare(q.Name, q.Durable, q.AutoDelete, q.Exclusive, q.NoWait, q.Args)
+		if err != nil {
+			return stacktrace.Propagate(err, "could not declare queue %s", q.Name)
+		}
+	}
+
+	for _, b := range topology.QueueBindings {
+		err := channel.QueueBind(b.Name, b.Key, b.Exchange, b.NoWait, b.Args)
+		if err != nil {
+			return stacktrace.Propagate(
+				err,
+				"could not bind queue %s to exchange %s", b.Name, b.Exchange,
+			)
+		}
+	}
+
+	return nil
+}
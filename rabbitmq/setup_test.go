@@ -0,0 +1,108 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTopologyChannel struct {
+	declared []string
+	failing  string
+}
+
+func (ch *fakeTopologyChannel) declare(entity string) error {
+	if entity == ch.failing {
+		return &amqp.Error{Code: amqp.PreconditionFailed, Reason: "inequivalent arg 'durable'"}
+	}
+
+	ch.declared = append(ch.declared, entity)
+
+	return nil
+}
+
+func (ch *fakeTopologyChannel) ExchangeDeclare(
+	name,
+	kind string,
+	durable,
+	autoDelete,
+	internal,
+	noWait bool,
+	args amqp.Table,
+) error {
+	return ch.declare("exchange " + name)
+}
+
+func (ch *fakeTopologyChannel) QueueDeclare(
+	name string,
+	durable,
+	autoDelete,
+	exclusive,
+	noWait bool,
+	args amqp.Table,
+) (amqp.Queue, error) {
+	return amqp.Queue{Name: name}, ch.declare("queue " + name)
+}
+
+func (ch *fakeTopologyChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	return ch.declare("binding " + name + " " + exchange)
+}
+
+func TestDeclareTopology(t *testing.T) {
+	topology := &Topology{
+		Exchanges:     []ExchangeConfig{{Name: "orders", Kind: amqp.ExchangeTopic, Durable: true}},
+		Queues:        []QueueConfig{{Name: "orders.created", Durable: true}, {Name: "orders.paid", Durable: true}},
+		QueueBindings: []QueueBindConfig{{Name: "orders.created", Key: "created", Exchange: "orders"}},
+	}
+
+	t.Run("it declares the exchanges, then the queues, then the bindings", func(t *testing.T) {
+		t.Parallel()
+
+		channel := &fakeTopologyChannel{}
+
+		err := declareTopology(channel, topology)
+		assert.NoError(t, err)
+		assert.Equal(
+			t,
+			[]string{
+				"exchange orders",
+				"queue orders.created",
+				"queue orders.paid",
+				"binding orders.created orders",
+			},
+			channel.declared,
+		)
+	})
+
+	t.Run("it stops at the first entity failing, telling which one it is", func(t *testing.T) {
+		t.Parallel()
+
+		channel := &fakeTopologyChannel{failing: "queue orders.created"}
+
+		err := declareTopology(channel, topology)
+		assert.Contains(t, err.Error(), "could not declare queue orders.created")
+
+		var amqpErr *amqp.Error
+		assert.True(t, errors.As(stacktrace.RootCause(err), &amqpErr))
+		assert.Equal(t, amqp.PreconditionFailed, amqpErr.Code)
+
+		assert.Equal(t, []string{"exchange orders"}, channel.declared)
+	})
+}
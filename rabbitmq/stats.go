@@ -0,0 +1,87 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ConsumerStats is a point-in-time snapshot of the activity of a consumer.
+type ConsumerStats struct {
+	// Consumed is the number of the deliveries received from the broker.
+	Consumed uint64
+	// Acked, Nacked and Rejected are the numbers of the deliveries successfully acknowledged in each way.
+	// The deliveries consumed with auto-ack are counted as acked.
+	Acked    uint64
+	Nacked   uint64
+	Rejected uint64
+	// Inflight is the number of the deliveries currently being processed by the handler.
+	Inflight int
+	// OldestUnackedAge is how long the oldest delivery which is not acknowledged yet was received ago,
+	// 0 if there are none. See WithMaxUnackedAge().
+	OldestUnackedAge time.Duration
+	// Consuming is true while the consumer receives deliveries from the broker.
+	Consuming bool
+	// Paused is true while the consumer is paused, see WithPauseOnErrorRate().
+	Paused bool
+}
+
+// consumerStats holds the counters of a consumer.
+// NOTE: Allocate it on its own, so the int64 fields are 64-bit aligned for the atomic operations.
+type consumerStats struct {
+	consumed  int64
+	acked     int64
+	nacked    int64
+	rejected  int64
+	inflight  int64
+	consuming int32
+	paused    int32
+}
+
+// Stats returns a snapshot of the activity of the consumer, e.g. for debug endpoints and tests.
+// It is safe to call Stats while the consumer runs.
+func (c *Consumer) Stats() ConsumerStats {
+	return ConsumerStats{
+		Consumed:         uint64(atomic.LoadInt64(&c.stats.consumed)),
+		Acked:            uint64(atomic.LoadInt64(&c.stats.acked)),
+		Nacked:           uint64(atomic.LoadInt64(&c.stats.nacked)),
+		Rejected:         uint64(atomic.LoadInt64(&c.stats.rejected)),
+		Inflight:         int(atomic.LoadInt64(&c.stats.inflight)),
+		OldestUnackedAge: c.unacked.oldestAge(c.clock.Now()),
+		Consuming:        atomic.LoadInt32(&c.stats.consuming) == 1,
+		Paused:           atomic.LoadInt32(&c.stats.paused) == 1,
+	}
+}
+
+func (s *consumerStats) addInflight(delta int) {
+	atomic.AddInt64(&s.inflight, int64(delta))
+}
+
+func (s *consumerStats) setConsuming(consuming bool) {
+	atomic.StoreInt32(&s.consuming, boolToInt32(consuming))
+}
+
+func (s *consumerStats) setPaused(paused bool) {
+	atomic.StoreInt32(&s.paused, boolToInt32(paused))
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
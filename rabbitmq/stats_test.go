@@ -0,0 +1,79 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestConsumer_Stats(t *testing.T) {
+	t.Run("it reflects the processed deliveries", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var consumer *Consumer
+		var statsWhileProcessing []ConsumerStats
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			statsWhileProcessing = append(statsWhileProcessing, consumer.Stats())
+
+			switch string(msg.Body) {
+			case "nack":
+				return HandlerAcknowledgement{Acknowledgement: Nack}, nil
+			case "reject":
+				cancel()
+
+				return HandlerAcknowledgement{Acknowledgement: Reject}, nil
+			default:
+				return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+			}
+		})
+		// NOTE: The fake clock does not move, so the unacked deliveries have no age.
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		consumer, _ = newTestConsumer(handler, channel, ConsumerConfig{}, WithClock(clock))
+
+		assert.Equal(t, ConsumerStats{}, consumer.Stats())
+
+		channel.deliver(ack, 1, "ack")
+		channel.deliver(ack, 2, "nack")
+		channel.deliver(ack, 3, "reject")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+
+		assert.Equal(t, ConsumerStats{Consumed: 1, Inflight: 1, Consuming: true}, statsWhileProcessing[0])
+		assert.Equal(
+			t,
+			ConsumerStats{Consumed: 2, Acked: 1, Inflight: 1, Consuming: true},
+			statsWhileProcessing[1],
+		)
+		assert.Equal(
+			t,
+			ConsumerStats{Consumed: 3, Acked: 1, Nacked: 1, Rejected: 1},
+			consumer.Stats(),
+		)
+	})
+}
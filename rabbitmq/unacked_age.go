@@ -0,0 +1,184 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/streadway/amqp"
+
+	"github.com/sumup-oss/go-pkgs/logger"
+)
+
+// WithMaxUnackedAge nacks with requeue the deliveries which stay unacknowledged for maxAge,
+// e.g. because the handler is stuck, before the broker closes the channel once its
+// consumer_timeout passes.
+//
+// The consumer checks the age of the oldest unacknowledged delivery every quarter of maxAge,
+// logs a warning for every delivery reaching maxAge, and nacks it with requeue right away,
+// so the broker can redeliver it. The acknowledgement returned by the handler for such
+// a delivery afterwards is ignored.
+// The age of the oldest unacknowledged delivery is reported by Consumer.Stats() regardless of the option.
+//
+// NOTE: The option has no effect on the auto-ack queues, see Handler.QueueAutoAck().
+func WithMaxUnackedAge(maxAge time.Duration) ConsumerOption {
+	return func(c *Consumer) {
+		c.maxUnackedAge = maxAge
+	}
+}
+
+// unackedTracker tracks the deliveries which are received but not acknowledged yet.
+type unackedTracker struct {
+	mu         sync.Mutex
+	deliveries map[uint64]unackedDelivery
+}
+
+type unackedDelivery struct {
+	delivery   *amqp.Delivery
+	receivedAt time.Time
+}
+
+func newUnackedTracker() *unackedTracker {
+	return &unackedTracker{deliveries: map[uint64]unackedDelivery{}}
+}
+
+// reset forgets all the deliveries, since the delivery tags are valid only on the channel they come from.
+func (t *unackedTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.deliveries = map[uint64]unackedDelivery{}
+}
+
+func (t *unackedTracker) add(d *amqp.Delivery, receivedAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.deliveries[d.DeliveryTag] = unackedDelivery{delivery: d, receivedAt: receivedAt}
+}
+
+// settle forgets the delivery once it is acknowledged.
+// Returns false if the delivery is already acknowledged, i.e. it was nacked for reaching the max unacked age.
+func (t *unackedTracker) settle(d *amqp.Delivery) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.deliveries[d.DeliveryTag]
+	delete(t.deliveries, d.DeliveryTag)
+
+	return ok
+}
+
+// oldestAge returns the age of the oldest delivery, or 0 if there are none.
+func (t *unackedTracker) oldestAge(now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var oldest time.Duration
+	for _, unacked := range t.deliveries {
+		if age := now.Sub(unacked.receivedAt); age > oldest {
+			oldest = age
+		}
+	}
+
+	return oldest
+}
+
+// expire forgets the deliveries which are at least maxAge old, returning them.
+func (t *unackedTracker) expire(now time.Time, maxAge time.Duration) []unackedDelivery {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var expired []unackedDelivery
+	for tag, unacked := range t.deliveries {
+		if now.Sub(unacked.receivedAt) >= maxAge {
+			expired = append(expired, unacked)
+			delete(t.deliveries, tag)
+		}
+	}
+
+	return expired
+}
+
+// trackUnacked starts tracking the delivery until it is acknowledged, unless the queue is auto-ack.
+func (c *Consumer) trackUnacked(d *amqp.Delivery) {
+	if c.handler.QueueAutoAck() {
+		return
+	}
+
+	c.unacked.add(d, c.clock.Now())
+}
+
+// watchUnackedAge nacks the deliveries reaching the max unacked age in the background, until ctx is done.
+func (c *Consumer) watchUnackedAge(ctx context.Context) {
+	if c.maxUnackedAge <= 0 || c.handler.QueueAutoAck() {
+		return
+	}
+
+	interval := c.maxUnackedAge / 4
+	if interval <= 0 {
+		interval = c.maxUnackedAge
+	}
+	ticker := c.clock.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+
+			now := c.clock.Now()
+			for _, unacked := range c.unacked.expire(now, c.maxUnackedAge) {
+				c.nackExpired(unacked, now)
+			}
+		}
+	}()
+}
+
+func (c *Consumer) nackExpired(unacked unackedDelivery, now time.Time) {
+	d := unacked.delivery
+
+	c.logger.Warn(
+		"RMQ delivery unacked for too long, nacking it with requeue",
+		logger.String("queue", c.handler.GetQueueName()),
+		logger.Duration("unacked_age", now.Sub(unacked.receivedAt)),
+		logger.Duration("max_unacked_age", c.maxUnackedAge),
+		tracingField(d.CorrelationId),
+		c.processingIDField(d),
+	)
+
+	err := d.Nack(false, true)
+	if err != nil {
+		c.metric.ObserveNack(false)
+		c.logger.Error(
+			"failed to nack message",
+			logger.ErrorField(err),
+			tracingField(d.CorrelationId),
+			c.processingIDField(d),
+		)
+
+		return
+	}
+
+	c.metric.ObserveNack(true)
+	atomic.AddInt64(&c.stats.nacked, 1)
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithMaxUnackedAge(t *testing.T) {
+	t.Run("it nacks with requeue the delivery unacked beyond the max age and logs a warning", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+		capturingLog := newCapturingLogger()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		received := make(chan struct{})
+		release := make(chan struct{})
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			close(received)
+			<-release
+			cancel()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(
+			handler,
+			channel,
+			ConsumerConfig{},
+			WithClock(clock),
+			WithMaxUnackedAge(4*time.Second),
+		)
+		consumer.logger = capturingLog
+
+		channel.deliver(ack, 1, "stuck")
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- consumer.Run(ctx)
+		}()
+
+		<-received
+		clock.BlockUntil(1)
+
+		clock.Advance(2 * time.Second)
+		assert.Equal(t, 2*time.Second, consumer.Stats().OldestUnackedAge)
+		assert.Empty(t, ack.Nacks())
+
+		clock.Advance(2 * time.Second)
+		assert.Eventually(t, func() bool {
+			return len(ack.Nacks()) == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, []uint64{1}, ack.Requeued())
+		assert.Equal(t, time.Duration(0), consumer.Stats().OldestUnackedAge)
+
+		close(release)
+		assert.Error(t, <-runErr)
+
+		assert.Empty(t, ack.Acks())
+		assert.Equal(t, uint64(1), consumer.Stats().Nacked)
+		assert.Equal(t, uint64(0), consumer.Stats().Acked)
+
+		warnings := capturingLog.logs.FilterMessage("RMQ delivery unacked for too long, nacking it with requeue").All()
+		if assert.Len(t, warnings, 1) {
+			assert.Equal(t, 4*time.Second, warnings[0].ContextMap()["unacked_age"])
+		}
+	})
+
+	t.Run("it does not nack the deliveries acked in time", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		channel := newFakeChannel(1)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		consumer, _ := newTestConsumer(
+			newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+				cancel()
+
+				return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+			}),
+			channel,
+			ConsumerConfig{},
+			WithClock(clock),
+			WithMaxUnackedAge(4*time.Second),
+		)
+
+		channel.deliver(ack, 1, "fast")
+
+		assert.Error(t, consumer.Run(ctx))
+		clock.Advance(4 * time.Second)
+
+		assert.Equal(t, []uint64{1}, ack.Acks())
+		assert.Empty(t, ack.Nacks())
+	})
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+
+	"github.com/palantir/stacktrace"
+	"github.com/streadway/amqp"
+)
+
+// WithWorkers makes the consumer process up to n deliveries concurrently, e.g. for I/O-bound handlers.
+//
+// Every delivery is processed and acknowledged by its own worker, so the handler must be safe for
+// concurrent use. The number of workers is bounded by the PrefetchCount of the consumer, if any,
+// since the consumer never has more deliveries than that to process.
+// When the handler returns an error, the consumer stops receiving deliveries, and Run() returns
+// the error once the other workers are done with their deliveries.
+// The workers are awaited on shutdown the same way the single delivery in-flight is,
+// see Handler.WaitToConsumeInflight().
+//
+// NOTE: The deliveries consumed with WithBatch() are not processed concurrently.
+func WithWorkers(n int) ConsumerOption {
+	return func(c *Consumer) {
+		c.workers = n
+	}
+}
+
+// workerCount returns the number of the deliveries the consumer processes concurrently.
+func (c *Consumer) workerCount() int {
+	workers := c.workers
+
+	prefetchCount := c.prefetchCount()
+	if prefetchCount > 0 && prefetchCount < workers {
+		workers = prefetchCount
+	}
+
+	return workers
+}
+
+func (c *Consumer) handleConcurrentDeliveries(
+	ctx context.Context,
+	deliveries <-chan amqp.Delivery,
+	workers int,
+) error {
+	idle := c.newIdleWatch()
+	defer idle.stop()
+
+	slots := make(chan struct{}, workers)
+	// failed receives the error of the first failed worker
+	failed := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	// NOTE: Return only once the workers are done, so none of them outlives the consumer.
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Warn("RMQ handler stopping")
+			c.drainAutoAcked(deliveries)
+
+			return ctx.Err()
+		case err := <-failed:
+			return err
+		case <-idle.C():
+			idle.fired()
+			c.onIdle()
+		case d, hasMore := <-deliveries:
+			if !hasMore {
+				if ctx.Err() != nil {
+					// NOTE: The consumer closed the channel while stopping.
+					return ctx.Err()
+				}
+
+				c.logger.Warn("RMQ handler deliveries channel closed.")
+
+				return c.deliveriesClosedError()
+			}
+
+			// NOTE: Do not give up on the received delivery once the context is done,
+			// so an auto-acked one is processed as it would be by a single worker.
+			select {
+			case slots <- struct{}{}:
+			case err := <-failed:
+				return err
+			}
+
+			inflight := c.startInflight()
+			if inflight == nil {
+				<-slots
+
+				return ctx.Err()
+			}
+
+			wg.Add(1)
+
+			go func(d amqp.Delivery) {
+				defer func() {
+					<-slots
+					wg.Done()
+					inflight.done()
+				}()
+
+				err := c.handleSingleDelivery(ctx, &d)
+				if err != nil {
+					select {
+					case failed <- stacktrace.Propagate(err, "failed to process RMQ delivery"):
+					default:
+					}
+				}
+			}(d)
+
+			err := c.pauseOnErrorRate(ctx)
+			if err != nil {
+				return err
+			}
+
+			idle.reset()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithWorkers(t *testing.T) {
+	t.Run("it processes the deliveries concurrently", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(3)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var started sync.WaitGroup
+		started.Add(3)
+
+		var mu sync.Mutex
+		processed := 0
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			// NOTE: Every delivery waits for the others to be in the handler as well.
+			started.Done()
+			started.Wait()
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			processed++
+			if processed == 3 {
+				cancel()
+			}
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{PrefetchCount: 3}, WithWorkers(3))
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+		channel.deliver(ack, 3, "baz")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.ElementsMatch(t, []uint64{1, 2, 3}, ack.Acks())
+	})
+
+	t.Run("it bounds the workers by the prefetch count", func(t *testing.T) {
+		t.Parallel()
+
+		consumer, _ := newTestConsumer(
+			newFakeHandler(ackAll),
+			newFakeChannel(0),
+			ConsumerConfig{PrefetchCount: 2},
+			WithWorkers(4),
+		)
+		assert.Equal(t, 2, consumer.workerCount())
+
+		consumer, _ = newTestConsumer(newFakeHandler(ackAll), newFakeChannel(0), ConsumerConfig{}, WithWorkers(4))
+		assert.Equal(t, 4, consumer.workerCount())
+	})
+
+	t.Run("when the handler fails, it returns the error once the other workers are done", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		fooStarted := make(chan struct{})
+		barFailed := make(chan struct{})
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			if string(msg.Body) == "bar" {
+				defer close(barFailed)
+				<-fooStarted
+
+				return HandlerAcknowledgement{}, assert.AnError
+			}
+
+			close(fooStarted)
+			<-barFailed
+			time.Sleep(10 * time.Millisecond)
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{PrefetchCount: 2}, WithWorkers(2))
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+
+		err := consumer.Run(context.Background())
+		assert.Equal(t, assert.AnError, stacktrace.RootCause(err))
+		assert.Equal(t, []uint64{1}, ack.Acks())
+		assert.True(t, channel.IsClosed())
+	})
+
+	t.Run("on shutdown, it waits for the in-flight workers before closing the channel", func(t *testing.T) {
+		t.Parallel()
+
+		channel := newFakeChannel(2)
+		ack := &fakeAcknowledger{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var started sync.WaitGroup
+		started.Add(2)
+
+		var mu sync.Mutex
+		closedWhileProcessing := false
+
+		handler := newFakeHandler(func(ctx context.Context, msg *Message) (HandlerAcknowledgement, error) {
+			started.Done()
+			started.Wait()
+			cancel()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			closedWhileProcessing = closedWhileProcessing || channel.IsClosed()
+			mu.Unlock()
+
+			return HandlerAcknowledgement{Acknowledgement: Ack}, nil
+		})
+		consumer, _ := newTestConsumer(handler, channel, ConsumerConfig{PrefetchCount: 2}, WithWorkers(2))
+
+		channel.deliver(ack, 1, "foo")
+		channel.deliver(ack, 2, "bar")
+
+		err := consumer.Run(ctx)
+		assert.Equal(t, context.Canceled, stacktrace.RootCause(err))
+		assert.ElementsMatch(t, []uint64{1, 2}, ack.Acks())
+		assert.False(t, closedWhileProcessing)
+		assert.True(t, channel.IsClosed())
+	})
+}
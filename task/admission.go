@@ -0,0 +1,83 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultAdmissionRetryInterval is how long a task waits before its admission is asked for again,
+// unless configured otherwise with WithAdmissionRetryInterval().
+const DefaultAdmissionRetryInterval = 100 * time.Millisecond
+
+// ErrAdmissionRejected is returned by an admission controller to reject a task, see WithAdmissionController().
+var ErrAdmissionRejected = errors.New("task admission rejected")
+
+// WithAdmissionController gates the start of every task on controller, e.g. to bound the memory
+// used by the running tasks.
+//
+// The controller is called with the context of the task right before it starts, and can block
+// until the task may be started. When it returns:
+//   - nil, the task is started.
+//   - an error wrapping ErrAdmissionRejected, the task is not started and fails with the error.
+//   - any other error, the task waits for the admission retry interval and the controller is called again,
+//     see WithAdmissionRetryInterval().
+//
+// Tasks waiting for their admission are not started at all if the group is canceled in the meantime.
+// NOTE: A task waiting for its admission takes a slot of the concurrency limit, see NewGroupWithLimit().
+func WithAdmissionController(controller func(ctx context.Context) error) GroupOption {
+	return func(g *Group) {
+		g.admissionController = controller
+	}
+}
+
+// WithAdmissionRetryInterval sets how long a task waits before its admission is asked for again,
+// after the admission controller returns an error, see WithAdmissionController().
+func WithAdmissionRetryInterval(interval time.Duration) GroupOption {
+	return func(g *Group) {
+		g.admissionRetryInterval = interval
+	}
+}
+
+// awaitAdmission blocks until the admission controller admits the task.
+// Returns false if the task must not be started, with the error of the controller if it rejected the task.
+func (g *Group) awaitAdmission(t *taskEntry) (bool, error) {
+	if g.admissionController == nil {
+		return true, nil
+	}
+
+	interval := g.admissionRetryInterval
+	if interval <= 0 {
+		interval = DefaultAdmissionRetryInterval
+	}
+
+	for {
+		err := g.admissionController(t.ctx)
+		if err == nil {
+			return t.ctx.Err() == nil, nil
+		}
+
+		if errors.Is(err, ErrAdmissionRejected) {
+			return false, fmt.Errorf("task not admitted: %w", err)
+		}
+
+		if !g.awaitLaunch(t, g.clock.Now().Add(interval)) {
+			return false, nil
+		}
+	}
+}
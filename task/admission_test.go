@@ -0,0 +1,138 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithAdmissionController(t *testing.T) {
+	t.Run("it starts the task only once the controller admits it", func(t *testing.T) {
+		t.Parallel()
+
+		admit := make(chan struct{})
+		group := task.NewGroup(task.WithAdmissionController(func(ctx context.Context) error {
+			select {
+			case <-admit:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}))
+
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+
+			return nil
+		})
+
+		select {
+		case <-started:
+			t.Fatal("the task must not be started before it is admitted")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(admit)
+		<-started
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("when the controller returns an error, it asks for the admission again after the retry interval", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		var calls int32
+		group := task.NewGroup(
+			task.WithClock(clock),
+			task.WithAdmissionRetryInterval(time.Second),
+			task.WithAdmissionController(func(ctx context.Context) error {
+				if atomic.AddInt32(&calls, 1) < 3 {
+					return errors.New("memory above the limit")
+				}
+
+				return nil
+			}),
+		)
+
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+
+			return nil
+		})
+
+		for i := 0; i < 2; i++ {
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+		}
+		<-started
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("when the controller rejects the task, it fails without being started", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithAdmissionController(func(ctx context.Context) error {
+			return fmt.Errorf("too many tasks: %w", task.ErrAdmissionRejected)
+		}))
+
+		group.Go(func(ctx context.Context) error {
+			t.Error("the rejected task must not be started")
+
+			return nil
+		})
+
+		err := group.Wait(context.Background())
+		assert.True(t, errors.Is(err, task.ErrAdmissionRejected))
+		assert.EqualError(t, err, "task not admitted: too many tasks: task admission rejected")
+	})
+
+	t.Run("when the group is canceled, it does not start the tasks waiting for their admission", func(t *testing.T) {
+		t.Parallel()
+
+		asked := make(chan struct{})
+		group := task.NewGroup(task.WithAdmissionController(func(ctx context.Context) error {
+			close(asked)
+			<-ctx.Done()
+
+			return ctx.Err()
+		}))
+
+		group.Go(func(ctx context.Context) error {
+			t.Error("the task must not be started")
+
+			return nil
+		})
+
+		<-asked
+		group.Cancel()
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
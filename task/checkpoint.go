@@ -0,0 +1,76 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"fmt"
+)
+
+// CheckpointStore persists which tasks of a group completed, see WithCheckpointStore().
+//
+// The implementations must be safe for concurrent use, since the tasks of the group may run concurrently.
+type CheckpointStore interface {
+	// Completed reports whether the task with the name already completed in a previous run.
+	Completed(ctx context.Context, name string) (bool, error)
+	// MarkCompleted records that the task with the name completed.
+	MarkCompleted(ctx context.Context, name string) error
+}
+
+// WithCheckpointStore records the tasks which complete in the store, so a restarted group can skip them,
+// e.g. for the migration-style groups running their tasks one by one, see NewGroupWithLimit().
+//
+// Only the tasks run with Group.GoNamed() are checkpointed, keyed by their name, since the names
+// generated for the tasks run with Group.Go() are not stable across restarts.
+// A task is recorded once it returns nil, and a task already recorded returns nil right away
+// without being invoked. A failure of the store fails the task with the store error.
+func WithCheckpointStore(store CheckpointStore) GroupOption {
+	return func(g *Group) {
+		g.checkpoints = store
+	}
+}
+
+// checkpointed returns fn skipped once the task with the name completed, and recorded once it completes.
+func (g *Group) checkpointed(name string, fn TaskFunc) TaskFunc {
+	if g.checkpoints == nil {
+		return fn
+	}
+
+	return func(ctx context.Context) error {
+		completed, err := g.checkpoints.Completed(ctx, name)
+		if err != nil {
+			return fmt.Errorf("could not get the checkpoint: %w", err)
+		}
+
+		if completed {
+			// NOTE: The task completed in a previous run is ready, see Group.GoNamedReady().
+			Ready(ctx)
+
+			return nil
+		}
+
+		err = fn(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = g.checkpoints.MarkCompleted(ctx, name)
+		if err != nil {
+			return fmt.Errorf("could not record the checkpoint: %w", err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+type fakeCheckpointStore struct {
+	mu        sync.Mutex
+	completed map[string]bool
+	err       error
+}
+
+func newFakeCheckpointStore(completed ...string) *fakeCheckpointStore {
+	store := &fakeCheckpointStore{completed: make(map[string]bool)}
+	for _, name := range completed {
+		store.completed[name] = true
+	}
+
+	return store
+}
+
+func (s *fakeCheckpointStore) Completed(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.completed[name], s.err
+}
+
+func (s *fakeCheckpointStore) MarkCompleted(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completed[name] = true
+
+	return nil
+}
+
+func (s *fakeCheckpointStore) Completions() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	completions := make(map[string]bool, len(s.completed))
+	for name, completed := range s.completed {
+		completions[name] = completed
+	}
+
+	return completions
+}
+
+func TestWithCheckpointStore(t *testing.T) {
+	t.Run("it skips the tasks completed in a previous run and records the completed ones", func(t *testing.T) {
+		t.Parallel()
+
+		store := newFakeCheckpointStore("migration-1")
+		group := task.NewGroupWithLimit(1, task.WithCheckpointStore(store))
+
+		var invoked []string
+		for _, name := range []string{"migration-1", "migration-2"} {
+			name := name
+			group.GoNamed(name, func(ctx context.Context) error {
+				invoked = append(invoked, name)
+
+				return nil
+			})
+		}
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"migration-2"}, invoked)
+		assert.Equal(t, map[string]bool{"migration-1": true, "migration-2": true}, store.Completions())
+	})
+
+	t.Run("it does not record the failed tasks", func(t *testing.T) {
+		t.Parallel()
+
+		store := newFakeCheckpointStore()
+		group := task.NewGroup(task.WithCheckpointStore(store))
+
+		errMigration := errors.New("migration failed")
+		group.GoNamed("migration-1", func(ctx context.Context) error {
+			return errMigration
+		})
+
+		err := group.Wait(context.Background())
+		assert.True(t, errors.Is(err, errMigration))
+		assert.Empty(t, store.Completions())
+	})
+
+	t.Run("a failure of the store fails the task without invoking it", func(t *testing.T) {
+		t.Parallel()
+
+		errStore := errors.New("store unavailable")
+		store := newFakeCheckpointStore()
+		store.err = errStore
+		group := task.NewGroup(task.WithCheckpointStore(store))
+
+		var invoked bool
+		group.GoNamed("migration-1", func(ctx context.Context) error {
+			invoked = true
+
+			return nil
+		})
+
+		err := group.Wait(context.Background())
+		assert.True(t, errors.Is(err, errStore))
+		assert.EqualError(t, err, `task "migration-1" failed: could not get the checkpoint: store unavailable`)
+		assert.False(t, invoked)
+	})
+
+	t.Run("the ready tasks completed in a previous run are skipped and ready", func(t *testing.T) {
+		t.Parallel()
+
+		store := newFakeCheckpointStore("migrations")
+		group := task.NewGroup(task.WithCheckpointStore(store))
+
+		invoked := false
+		group.GoNamedReady("migrations", func(ctx context.Context) error {
+			invoked = true
+			task.Ready(ctx)
+
+			return nil
+		})
+
+		assert.NoError(t, group.WaitReady(context.Background()))
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.False(t, invoked)
+	})
+}
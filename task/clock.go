@@ -0,0 +1,171 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock provides the time to the time-based features of a Group, e.g. the launch rate,
+// the start stagger and the shutdown hook timeout.
+//
+// The real clock is used by default. A fake clock, such as tasktest.FakeClock, can be set with WithClock()
+// to test these features deterministically.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is the Clock counterpart of time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Ticker is the Clock counterpart of time.Ticker.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// WithClock sets the clock used by the time-based features of the group.
+func WithClock(clock Clock) GroupOption {
+	return func(g *Group) {
+		g.clock = clock
+	}
+}
+
+// RealClock returns the Clock backed by the time package.
+func RealClock() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// timeoutContext is a context canceled once the timeout measured by a Clock passes.
+//
+// NOTE: It does not embed a context created by context.WithCancel(), so the contexts derived from it
+// are canceled with its own error, e.g. context.DeadlineExceeded, instead of context.Canceled.
+type timeoutContext struct {
+	parent   context.Context
+	deadline time.Time
+	done     chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// withClockTimeout is the Clock counterpart of context.WithTimeout.
+func withClockTimeout(parent context.Context, clock Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(realClock); ok {
+		return context.WithTimeout(parent, timeout)
+	}
+
+	timeoutCtx := &timeoutContext{
+		parent:   parent,
+		deadline: clock.Now().Add(timeout),
+		done:     make(chan struct{}),
+	}
+
+	timer := clock.NewTimer(timeout)
+	go func() {
+		select {
+		case <-timeoutCtx.done:
+		case <-parent.Done():
+			timeoutCtx.cancel(parent.Err())
+		case <-timer.C():
+			timeoutCtx.cancel(context.DeadlineExceeded)
+		}
+	}()
+
+	return timeoutCtx, func() {
+		timer.Stop()
+		timeoutCtx.cancel(context.Canceled)
+	}
+}
+
+// cancel closes the context with the error, unless it is already closed.
+func (c *timeoutContext) cancel(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	c.err = err
+	close(c.done)
+}
+
+// Deadline returns the earlier of the deadline of the parent, and the one of the timeout.
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	parentDeadline, ok := c.parent.Deadline()
+	if ok && parentDeadline.Before(c.deadline) {
+		return parentDeadline, true
+	}
+
+	return c.deadline, true
+}
+
+func (c *timeoutContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *timeoutContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *timeoutContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
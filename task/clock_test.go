@@ -0,0 +1,147 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+var epoch = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestWithClock(t *testing.T) {
+	t.Run("it paces the launches by the clock", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		group := task.NewGroup(task.WithClock(clock), task.WithLaunchRate(1, 1))
+
+		started := make(chan time.Time, 2)
+		for i := 0; i < 2; i++ {
+			group.Go(func(ctx context.Context) error {
+				started <- clock.Now()
+
+				return nil
+			})
+		}
+
+		assert.Equal(t, epoch, <-started)
+
+		clock.BlockUntil(1)
+		select {
+		case <-started:
+			t.Fatal("the second task must wait for its turn")
+		default:
+		}
+
+		clock.Advance(time.Second)
+		assert.Equal(t, epoch.Add(time.Second), <-started)
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it staggers the starts by the clock", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		group := task.NewGroup(task.WithClock(clock), task.WithStartStagger(time.Minute))
+
+		started := make(chan time.Time, 2)
+		for i := 0; i < 2; i++ {
+			group.Go(func(ctx context.Context) error {
+				started <- clock.Now()
+
+				return nil
+			})
+		}
+
+		assert.Equal(t, epoch, <-started)
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		assert.Equal(t, epoch.Add(time.Minute), <-started)
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it times out the shutdown hooks by the clock", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		group := task.NewGroup(task.WithClock(clock), task.WithShutdownHookTimeout(time.Minute))
+
+		hookErr := make(chan error, 1)
+		group.OnShutdown(func(ctx context.Context) error {
+			<-ctx.Done()
+			hookErr <- ctx.Err()
+
+			return nil
+		})
+
+		waitErr := make(chan error)
+		go func() {
+			waitErr <- group.Wait(context.Background())
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+
+		assert.True(t, errors.Is(<-waitErr, task.ErrShutdownHookTimeout))
+		assert.Equal(t, context.DeadlineExceeded, <-hookErr)
+	})
+
+	t.Run("it measures the task durations by the clock", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		observer := &recordingObserver{}
+		group := task.NewGroup(task.WithClock(clock), task.WithObserver(observer))
+
+		running := make(chan struct{})
+		canceled := make(chan struct{})
+		release := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(running)
+			clock.Advance(time.Second)
+
+			<-ctx.Done()
+			close(canceled)
+			<-release
+
+			return nil
+		})
+
+		<-running
+		group.Cancel()
+		<-canceled
+
+		clock.Advance(2 * time.Second)
+		close(release)
+
+		assert.NoError(t, group.Wait(context.Background()))
+
+		finished := observer.Finished()
+		if assert.Len(t, finished, 1) {
+			assert.Equal(t, epoch, finished[0].info.StartedAt)
+			assert.Equal(t, 3*time.Second, finished[0].info.Duration)
+			assert.Equal(t, 2*time.Second, finished[0].info.DrainDuration)
+		}
+	})
+}
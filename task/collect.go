@@ -0,0 +1,134 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// MultiError holds the errors of multiple tasks.
+type MultiError struct {
+	Errors []error
+}
+
+// Error joins the messages of all the errors.
+func (e *MultiError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the errors, so errors.Is() and errors.As() match any of them.
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is reports whether any of the errors matches target.
+//
+// NOTE: errors.Is() walks the errors returned by Unwrap() only since Go 1.20.
+func (e *MultiError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first of the errors which matches target, and if so, sets target to it.
+//
+// NOTE: errors.As() walks the errors returned by Unwrap() only since Go 1.20.
+func (e *MultiError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithCollectErrors makes the group drain all its tasks instead of canceling them on the first task failure.
+//
+// The errors of the failed tasks are collected and Group.Wait() returns them as a *MultiError,
+// in the order the tasks failed.
+func WithCollectErrors() GroupOption {
+	return func(g *Group) {
+		g.collectErrors = true
+	}
+}
+
+// WithCriticalError collects the task errors as WithCollectErrors() does, except the ones classified
+// as critical by isCritical.
+//
+// A critical error cancels all the tasks immediately, and it is the error returned by Group.Wait().
+func WithCriticalError(isCritical func(err error) bool) GroupOption {
+	return func(g *Group) {
+		g.collectErrors = true
+		g.isCritical = isCritical
+	}
+}
+
+// WithMaxFailures collects the task errors as WithCollectErrors() does, until k tasks failed.
+//
+// Once k tasks failed, all the remaining tasks are canceled, and the k errors collected so far fail the group
+// as a *MultiError, i.e. Group.Wait() returns it and Group.ErrorChan() receives it.
+// The errors returned by the canceled tasks are not part of it.
+func WithMaxFailures(k int) GroupOption {
+	return func(g *Group) {
+		g.collectErrors = true
+		g.maxFailures = k
+	}
+}
+
+// fail handles the error returned by a task run with ctx.
+func (g *Group) fail(ctx context.Context, err error) {
+	if !g.collectErrors || (g.isCritical != nil && g.isCritical(err)) {
+		g.cancelWithError(ctx, err)
+
+		return
+	}
+
+	g.mu.Lock()
+	g.collectedErrs = append(g.collectedErrs, err)
+
+	var maxFailuresErr *MultiError
+	if g.maxFailures > 0 && !g.maxFailuresReached && len(g.collectedErrs) >= g.maxFailures {
+		g.maxFailuresReached = true
+		maxFailuresErr = &MultiError{Errors: append([]error(nil), g.collectedErrs...)}
+	}
+	g.mu.Unlock()
+
+	if maxFailuresErr != nil {
+		g.cancelWithError(ctx, maxFailuresErr)
+	}
+}
+
+func (g *Group) collectedError() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.collectedErrs) == 0 {
+		return nil
+	}
+
+	return &MultiError{Errors: append([]error(nil), g.collectedErrs...)}
+}
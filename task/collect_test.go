@@ -0,0 +1,298 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+var errCritical = errors.New("critical")
+
+func TestWithCollectErrors(t *testing.T) {
+	t.Run("it lets the other tasks finish and returns all the errors", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithCollectErrors())
+
+		release := make(chan struct{})
+		var finished bool
+
+		group.Go(func(ctx context.Context) error {
+			defer close(release)
+
+			return errors.New("foo")
+		})
+		group.Go(func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-release:
+			}
+
+			finished = true
+
+			return errors.New("bar")
+		})
+
+		err := group.Wait(context.Background())
+		assert.True(t, finished)
+
+		var multiErr *task.MultiError
+		require.True(t, errors.As(err, &multiErr))
+		require.Len(t, multiErr.Errors, 2)
+
+		// NOTE: foo releases bar before it returns, so their errors can be collected in any order.
+		messages := []string{multiErr.Errors[0].Error(), multiErr.Errors[1].Error()}
+		assert.ElementsMatch(t, []string{"foo", "bar"}, messages)
+		assert.EqualError(t, err, messages[0]+"; "+messages[1])
+	})
+
+	t.Run("errors.Is and errors.As match the collected errors", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithCollectErrors())
+
+		errSentinel := errors.New("sentinel")
+		group.Go(func(ctx context.Context) error {
+			return errors.New("foo")
+		})
+		group.Go(func(ctx context.Context) error {
+			return fmt.Errorf("bar: %w", errSentinel)
+		})
+		group.Go(func(ctx context.Context) error {
+			return task.NewRetryableError(errors.New("baz"))
+		})
+
+		err := group.Wait(context.Background())
+
+		multiErr, ok := err.(*task.MultiError)
+		require.True(t, ok)
+		assert.True(t, multiErr.Is(errSentinel))
+		assert.False(t, multiErr.Is(errCritical))
+		assert.True(t, errors.Is(err, errSentinel))
+
+		var retryableErr task.RetryableError
+		require.True(t, multiErr.As(&retryableErr))
+		assert.EqualError(t, retryableErr, "baz")
+	})
+
+	t.Run("it returns nil when no task fails", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithCollectErrors())
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+	})
+}
+
+func TestWithCriticalError(t *testing.T) {
+	isCritical := func(err error) bool {
+		return errors.Is(err, errCritical)
+	}
+
+	t.Run("a non-critical error lets the other tasks finish", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithCriticalError(isCritical))
+
+		failed := make(chan struct{})
+		var finished bool
+
+		group.Go(func(ctx context.Context) error {
+			defer close(failed)
+
+			return errors.New("not critical")
+		})
+		group.Go(func(ctx context.Context) error {
+			<-failed
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			finished = true
+
+			return nil
+		})
+
+		err := group.Wait(context.Background())
+		assert.EqualError(t, err, "not critical")
+		assert.True(t, finished)
+	})
+
+	t.Run("a critical error cancels the other tasks immediately", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithCriticalError(isCritical))
+
+		started := make(chan struct{})
+		var otherErr error
+		group.Go(func(ctx context.Context) error {
+			close(started)
+
+			select {
+			case <-ctx.Done():
+				otherErr = ctx.Err()
+			case <-time.After(time.Minute):
+			}
+
+			return otherErr
+		})
+		group.Go(func(ctx context.Context) error {
+			<-started
+
+			return errors.New("not critical")
+		})
+		group.Go(func(ctx context.Context) error {
+			<-started
+
+			return errCritical
+		})
+
+		err := group.Wait(context.Background())
+		assert.Equal(t, errCritical, err)
+		assert.Equal(t, context.Canceled, otherErr)
+	})
+}
+
+func TestWithMaxFailures(t *testing.T) {
+	t.Run("it cancels the remaining tasks once k tasks failed", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithMaxFailures(2))
+
+		started := make(chan struct{})
+		var otherErr error
+		group.Go(func(ctx context.Context) error {
+			close(started)
+
+			select {
+			case <-ctx.Done():
+				otherErr = ctx.Err()
+			case <-time.After(time.Minute):
+			}
+
+			return otherErr
+		})
+		for _, name := range []string{"foo", "bar"} {
+			name := name
+			group.Go(func(ctx context.Context) error {
+				<-started
+
+				return errors.New(name)
+			})
+		}
+
+		err := group.Wait(context.Background())
+		assert.Equal(t, context.Canceled, otherErr)
+
+		var multiErr *task.MultiError
+		require.True(t, errors.As(err, &multiErr))
+		require.Len(t, multiErr.Errors, 2)
+		assert.ElementsMatch(
+			t,
+			[]string{"foo", "bar"},
+			[]string{multiErr.Errors[0].Error(), multiErr.Errors[1].Error()},
+		)
+	})
+
+	t.Run("it sends the collected errors to the error channel once k tasks failed", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithMaxFailures(2))
+
+		group.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return ctx.Err()
+		})
+		group.Go(func(ctx context.Context) error {
+			return errors.New("foo")
+		})
+		group.Go(func(ctx context.Context) error {
+			return errors.New("bar")
+		})
+
+		select {
+		case err := <-group.ErrorChan():
+			var multiErr *task.MultiError
+			require.True(t, errors.As(err, &multiErr))
+			assert.Len(t, multiErr.Errors, 2)
+		case <-time.After(time.Second):
+			t.Fatal("the collected errors were not received")
+		}
+
+		var multiErr *task.MultiError
+		require.True(t, errors.As(group.Wait(context.Background()), &multiErr))
+		assert.Len(t, multiErr.Errors, 2)
+	})
+
+	t.Run("it lets the other tasks finish while fewer than k tasks failed", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithMaxFailures(3))
+
+		fooFailed := make(chan struct{})
+		barFailed := make(chan struct{})
+		var finished bool
+
+		group.Go(func(ctx context.Context) error {
+			defer close(fooFailed)
+
+			return errors.New("foo")
+		})
+		group.Go(func(ctx context.Context) error {
+			defer close(barFailed)
+
+			return errors.New("bar")
+		})
+		group.Go(func(ctx context.Context) error {
+			<-fooFailed
+			<-barFailed
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			finished = true
+
+			return nil
+		})
+
+		err := group.Wait(context.Background())
+		assert.True(t, finished)
+
+		var multiErr *task.MultiError
+		require.True(t, errors.As(err, &multiErr))
+		assert.Len(t, multiErr.Errors, 2)
+	})
+}
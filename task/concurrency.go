@@ -0,0 +1,63 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import "runtime"
+
+// NewGroupWithLimit creates new task group instance running at most limit tasks concurrently.
+//
+// The tasks exceeding the limit are queued, and started in the order they were passed to Group.Go()
+// once the running tasks complete. The queued tasks are discarded without being started
+// when the group is canceled, e.g. on the first task failure.
+// A non-positive limit means the number of concurrently running tasks is not limited, as with NewGroup().
+func NewGroupWithLimit(limit int, opts ...GroupOption) *Group {
+	return NewGroup(append([]GroupOption{withLimit(limit)}, opts...)...)
+}
+
+func withLimit(limit int) GroupOption {
+	return func(g *Group) {
+		g.limit = limit
+	}
+}
+
+// LimitUsage returns how many tasks occupy the slots of the concurrency limit, and the limit itself,
+// e.g. for an external scheduler to decide whether to submit more work.
+//
+// The used slots are held by the started tasks which have not exited yet, the queued tasks are not counted.
+// The limit is the one set with NewGroupWithLimit() or computed by WithAutoConcurrency(),
+// a non-positive limit means the group does not limit the concurrently running tasks.
+// It is safe to call LimitUsage while the tasks are running.
+func (g *Group) LimitUsage() (used, limit int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.active, g.limit
+}
+
+// WithAutoConcurrency limits the number of concurrently running tasks to multiplier times
+// the number of CPUs, unless the group already has an explicit concurrency limit.
+//
+// It's meant to prevent accidental unbounded goroutine explosions in CPU-bound workloads.
+// A non-positive multiplier disables the option.
+func WithAutoConcurrency(multiplier int) GroupOption {
+	return func(g *Group) {
+		g.autoConcurrency = multiplier
+	}
+}
+
+// autoConcurrencyLimit returns the concurrency limit computed by WithAutoConcurrency().
+func autoConcurrencyLimit(multiplier int) int {
+	return runtime.NumCPU() * multiplier
+}
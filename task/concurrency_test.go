@@ -0,0 +1,239 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestNewGroupWithLimit(t *testing.T) {
+	t.Run("it runs at most limit tasks concurrently, starting the queued ones once a slot frees up", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroupWithLimit(2)
+
+		tasks := []*TestTask{NewTestTask(nil), NewTestTask(nil), NewTestTask(nil)}
+		for _, tt := range tasks {
+			group.Go(tt.Run)
+		}
+
+		<-tasks[0].RunReady
+		<-tasks[1].RunReady
+
+		select {
+		case <-tasks[2].RunReady:
+			t.Fatal("a task was started above the concurrency limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		tasks[0].RunUntil <- nil
+		<-tasks[2].RunReady
+
+		tasks[1].RunUntil <- nil
+		tasks[2].RunUntil <- nil
+
+		assert.NoError(t, group.Wait(context.Background()))
+		for _, tt := range tasks {
+			assert.Equal(t, 1, tt.RunCount)
+		}
+	})
+
+	t.Run("it returns the first error and does not start the queued tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroupWithLimit(1)
+		errFailed := errors.New("failed")
+
+		failing := NewTestTask(nil)
+		queued := NewTestTask(nil)
+		group.Go(failing.Run, queued.Run)
+
+		<-failing.RunReady
+		failing.RunUntil <- errFailed
+
+		assert.Equal(t, errFailed, group.Wait(context.Background()))
+		assert.Equal(t, 0, queued.RunCount)
+	})
+
+	t.Run("when the group is canceled, it does not start new tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroupWithLimit(1)
+		group.Cancel()
+
+		tt := NewTestTask(nil)
+		group.Go(tt.Run)
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, 0, tt.RunCount)
+	})
+
+	t.Run("with a non-positive limit, it does not limit the concurrently running tasks", func(t *testing.T) {
+		t.Parallel()
+
+		for _, limit := range []int{0, -1} {
+			group := task.NewGroupWithLimit(limit)
+
+			tasks := []*TestTask{NewTestTask(nil), NewTestTask(nil), NewTestTask(nil)}
+			for _, tt := range tasks {
+				group.Go(tt.Run)
+			}
+
+			for _, tt := range tasks {
+				<-tt.RunReady
+			}
+
+			group.Cancel()
+			assert.NoError(t, group.Wait(context.Background()))
+		}
+	})
+}
+
+func TestGroup_LimitUsage(t *testing.T) {
+	t.Run("it returns the slots used by the in-flight tasks and the limit", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroupWithLimit(2)
+
+		used, limit := group.LimitUsage()
+		assert.Equal(t, 0, used)
+		assert.Equal(t, 2, limit)
+
+		tasks := []*TestTask{NewTestTask(nil), NewTestTask(nil), NewTestTask(nil)}
+		group.Go(tasks[0].Run)
+		<-tasks[0].RunReady
+
+		used, _ = group.LimitUsage()
+		assert.Equal(t, 1, used)
+
+		group.Go(tasks[1].Run, tasks[2].Run)
+		<-tasks[1].RunReady
+
+		// NOTE: The queued task does not use a slot.
+		used, limit = group.LimitUsage()
+		assert.Equal(t, 2, used)
+		assert.Equal(t, 2, limit)
+
+		tasks[0].RunUntil <- nil
+		<-tasks[2].RunReady
+
+		used, _ = group.LimitUsage()
+		assert.Equal(t, 2, used)
+
+		tasks[1].RunUntil <- nil
+		tasks[2].RunUntil <- nil
+		assert.NoError(t, group.Wait(context.Background()))
+
+		used, _ = group.LimitUsage()
+		assert.Equal(t, 0, used)
+	})
+
+	t.Run("without a limit, it returns a non-positive limit", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		tt := NewTestTask(nil)
+		group.Go(tt.Run)
+		<-tt.RunReady
+
+		used, limit := group.LimitUsage()
+		assert.Equal(t, 1, used)
+		assert.Equal(t, 0, limit)
+
+		tt.RunUntil <- nil
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
+
+func TestWithAutoConcurrency(t *testing.T) {
+	t.Run("it limits the running tasks to NumCPU() times the multiplier", func(t *testing.T) {
+		t.Parallel()
+
+		const multiplier = 2
+		limit := runtime.NumCPU() * multiplier
+
+		group := task.NewGroup(task.WithAutoConcurrency(multiplier))
+
+		var running, maxRunning int32
+		started := make(chan struct{}, limit+1)
+		release := make(chan struct{})
+
+		for i := 0; i < limit+1; i++ {
+			group.Go(func(ctx context.Context) error {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+						break
+					}
+				}
+
+				started <- struct{}{}
+				<-release
+				atomic.AddInt32(&running, -1)
+
+				return nil
+			})
+		}
+
+		for i := 0; i < limit; i++ {
+			<-started
+		}
+
+		select {
+		case <-started:
+			t.Fatal("a task was started above the concurrency limit")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		<-started
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, int32(limit), atomic.LoadInt32(&maxRunning))
+	})
+
+	t.Run("when the group is canceled, it does not start the queued tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithAutoConcurrency(1))
+
+		tasks := make([]*TestTask, runtime.NumCPU()+1)
+		for i := range tasks {
+			tasks[i] = NewTestTask(nil)
+			group.Go(tasks[i].Run)
+		}
+
+		for _, tt := range tasks[:len(tasks)-1] {
+			<-tt.RunReady
+		}
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+
+		queued := tasks[len(tasks)-1]
+		assert.Equal(t, 0, queued.RunCount)
+	})
+}
@@ -0,0 +1,29 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import "time"
+
+// WithTaskDeadline sets deadline as the deadline of the context of every task in the group,
+// so the libraries deriving their own timeouts from ctx.Deadline() take it into account.
+//
+// Once the deadline passes, the contexts of the tasks are done with context.DeadlineExceeded,
+// which the tasks usually return, failing the group with it.
+// NOTE: The deadline is tracked by the standard library, so it does not follow the clock set with WithClock().
+func WithTaskDeadline(deadline time.Time) GroupOption {
+	return func(g *Group) {
+		g.taskDeadline = deadline
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestWithTaskDeadline(t *testing.T) {
+	t.Run("it sets the deadline of the task contexts", func(t *testing.T) {
+		t.Parallel()
+
+		deadline := time.Now().Add(time.Hour)
+		group := task.NewGroup(task.WithTaskDeadline(deadline))
+
+		deadlines := make(chan time.Time, 2)
+		for i := 0; i < 2; i++ {
+			group.Go(func(ctx context.Context) error {
+				taskDeadline, ok := ctx.Deadline()
+				assert.True(t, ok)
+				deadlines <- taskDeadline
+
+				return nil
+			})
+		}
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, deadline, <-deadlines)
+		assert.Equal(t, deadline, <-deadlines)
+	})
+
+	t.Run("it fails the group once the deadline passes", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithTaskDeadline(time.Now().Add(10 * time.Millisecond)))
+
+		group.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return ctx.Err()
+		})
+
+		assert.Equal(t, context.DeadlineExceeded, group.Wait(context.Background()))
+	})
+
+	t.Run("it sets no deadline by default", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.Go(func(ctx context.Context) error {
+			_, ok := ctx.Deadline()
+			assert.False(t, ok)
+
+			return nil
+		})
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_DebugHandler(t *testing.T) {
+	t.Run("it responds with the JSON of the running tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithTaskLocations())
+
+		started := make(chan struct{})
+		group.GoNamed("consumer", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return nil
+		})
+
+		<-started
+
+		recorder := httptest.NewRecorder()
+		group.DebugHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/tasks", nil))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+		var body struct {
+			Canceled bool `json:"canceled"`
+			Tasks    []struct {
+				Name      string `json:"name"`
+				Status    string `json:"status"`
+				StartedAt string `json:"started_at"`
+				Location  string `json:"location"`
+			} `json:"tasks"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+
+		assert.False(t, body.Canceled)
+		require.Len(t, body.Tasks, 1)
+		assert.Equal(t, "consumer", body.Tasks[0].Name)
+		assert.Equal(t, "running", body.Tasks[0].Status)
+		assert.NotEmpty(t, body.Tasks[0].StartedAt)
+		assert.Contains(t, body.Tasks[0].Location, "debug_handler_test.go:")
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it rejects the methods other than GET", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		recorder := httptest.NewRecorder()
+		group.DebugHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/tasks", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	})
+}
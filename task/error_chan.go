@@ -0,0 +1,50 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+// ErrorChan returns a channel receiving the first error which fails the group, i.e. cancels all its tasks.
+//
+// The error is a *TaskError carrying the context of the failed task, or the context passed to Group.Wait()
+// if the group failed because that context is done.
+//
+// The channel is buffered, so the error is kept until it is received. The channel is closed once Group.Wait()
+// returns, so on a clean completion it is closed without receiving any error.
+// It is always the same channel, and it is meant to be used in a select statement together with other events.
+func (g *Group) ErrorChan() <-chan error {
+	return g.errCh
+}
+
+func (g *Group) notifyFirstError(err *TaskError) {
+	g.errChMu.Lock()
+	defer g.errChMu.Unlock()
+
+	if g.errChClosed {
+		return
+	}
+
+	g.errCh <- err
+}
+
+func (g *Group) closeErrorChan() {
+	g.errChMu.Lock()
+	defer g.errChMu.Unlock()
+
+	if g.errChClosed {
+		return
+	}
+
+	g.errChClosed = true
+	close(g.errCh)
+}
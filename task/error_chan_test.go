@@ -0,0 +1,73 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_ErrorChan(t *testing.T) {
+	t.Run("it receives the error of the failed task", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.Go(func(ctx context.Context) error {
+			return errors.New("task failed")
+		})
+		group.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return errors.New("canceled")
+		})
+
+		select {
+		case err := <-group.ErrorChan():
+			assert.EqualError(t, err, "task failed")
+		case <-time.After(time.Second):
+			t.Fatal("the error was not received")
+		}
+
+		err := group.Wait(context.Background())
+		assert.EqualError(t, err, "task failed")
+
+		_, open := <-group.ErrorChan()
+		assert.False(t, open)
+	})
+
+	t.Run("it is closed without an error on clean completion", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+
+		err, open := <-group.ErrorChan()
+		assert.NoError(t, err)
+		assert.False(t, open)
+	})
+}
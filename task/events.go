@@ -0,0 +1,141 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sync"
+	"time"
+)
+
+// EventBufferSize is the capacity of the channel returned by Group.Events().
+const EventBufferSize = 64
+
+// EventType is the type of a group lifecycle Event.
+type EventType int
+
+const (
+	// EventTaskStarted is emitted right before the task function is invoked.
+	EventTaskStarted EventType = iota + 1
+	// EventTaskFinished is emitted once the task function returns no error.
+	EventTaskFinished
+	// EventTaskFailed is emitted once the task function returns an error or panics.
+	EventTaskFailed
+	// EventGroupCanceling is emitted once the group is canceled, by Group.Cancel() or by a failed task.
+	EventGroupCanceling
+	// EventGroupShuttingDown is emitted by Group.Wait() once all the tasks are stopped,
+	// right before the shutdown hooks are run.
+	EventGroupShuttingDown
+	// EventTaskStuck is emitted once the task runs for longer than the max run time of the watchdog,
+	// see WithWatchdog().
+	EventTaskStuck
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventTaskStarted:
+		return "task started"
+	case EventTaskFinished:
+		return "task finished"
+	case EventTaskFailed:
+		return "task failed"
+	case EventGroupCanceling:
+		return "group canceling"
+	case EventGroupShuttingDown:
+		return "group shutting down"
+	case EventTaskStuck:
+		return "task stuck"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a lifecycle event of a Group, see Group.Events().
+type Event struct {
+	Type EventType
+	// Task is the name of the task, or the auto-generated one, e.g. "task-0".
+	// It is empty for the group events.
+	Task string
+	// Err is the error returned by the task for EventTaskFailed.
+	Err error
+	// At is the time when the event happened.
+	At time.Time
+}
+
+type eventStream struct {
+	// mu protects the properties of the stream
+	mu     sync.Mutex
+	ch     chan Event
+	closed bool
+}
+
+// Events returns the channel the lifecycle events of the group are emitted to,
+// so that e.g. an external orchestrator can react to them.
+//
+// The group emits the events only once it is subscribed to, so the events happening before
+// the first call are not emitted. All the calls return the same channel.
+// The channel is buffered, see EventBufferSize, and the events are dropped while it is full,
+// so a slow subscriber does not stall the group.
+// The channel is closed by Group.Wait() once the shutdown hooks are run.
+func (g *Group) Events() <-chan Event {
+	g.events.mu.Lock()
+	defer g.events.mu.Unlock()
+
+	if g.events.ch == nil {
+		g.events.ch = make(chan Event, EventBufferSize)
+		if g.events.closed {
+			close(g.events.ch)
+		}
+	}
+
+	return g.events.ch
+}
+
+func (g *Group) emitEvent(typ EventType, task string, err error) {
+	g.events.mu.Lock()
+	defer g.events.mu.Unlock()
+
+	if g.events.ch == nil || g.events.closed {
+		return
+	}
+
+	select {
+	case g.events.ch <- Event{Type: typ, Task: task, Err: err, At: g.clock.Now()}:
+	default:
+	}
+}
+
+func (g *Group) emitTaskFinished(t *taskEntry, err error) {
+	if err != nil {
+		g.emitEvent(EventTaskFailed, t.label, err)
+
+		return
+	}
+
+	g.emitEvent(EventTaskFinished, t.label, nil)
+}
+
+func (g *Group) closeEvents() {
+	g.events.mu.Lock()
+	defer g.events.mu.Unlock()
+
+	if g.events.closed {
+		return
+	}
+
+	g.events.closed = true
+	if g.events.ch != nil {
+		close(g.events.ch)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+type emittedEvent struct {
+	typ  task.EventType
+	task string
+	err  error
+}
+
+func drainEvents(events <-chan task.Event) []emittedEvent {
+	var emitted []emittedEvent
+	for event := range events {
+		emitted = append(emitted, emittedEvent{typ: event.Type, task: event.Task, err: event.Err})
+	}
+
+	return emitted
+}
+
+func TestGroup_Events(t *testing.T) {
+	t.Run("it emits the lifecycle events of the group", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		events := group.Events()
+
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(
+			t,
+			[]emittedEvent{
+				{typ: task.EventTaskStarted, task: "task-0"},
+				{typ: task.EventTaskFinished, task: "task-0"},
+				{typ: task.EventGroupShuttingDown},
+			},
+			drainEvents(events),
+		)
+	})
+
+	t.Run("it emits the failure of a task canceling the group", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		events := group.Events()
+
+		group.Go(func(ctx context.Context) error {
+			return assert.AnError
+		})
+
+		assert.Equal(t, assert.AnError, group.Wait(context.Background()))
+		assert.Equal(
+			t,
+			[]emittedEvent{
+				{typ: task.EventTaskStarted, task: "task-0"},
+				{typ: task.EventTaskFailed, task: "task-0", err: assert.AnError},
+				{typ: task.EventGroupCanceling},
+				{typ: task.EventGroupShuttingDown},
+			},
+			drainEvents(events),
+		)
+	})
+
+	t.Run("it drops the events while the channel is full", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		events := group.Events()
+
+		for i := 0; i < task.EventBufferSize; i++ {
+			group.Go(func(ctx context.Context) error {
+				return nil
+			})
+		}
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Len(t, drainEvents(events), task.EventBufferSize)
+	})
+
+	t.Run("it returns a closed channel once the group is shut down", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		assert.NoError(t, group.Wait(context.Background()))
+
+		assert.Empty(t, drainEvents(group.Events()))
+	})
+}
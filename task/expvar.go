@@ -0,0 +1,87 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"expvar"
+	"sync"
+)
+
+// WithExpvar publishes the counters of the tasks run by the group with the expvar package,
+// as a map with the provided name and the keys:
+//
+//	running - number of currently running tasks
+//	completed - number of tasks that returned no error, or stopped cleanly
+//	failed - number of tasks that failed
+//
+// It is a lightweight alternative to the Prometheus observer of the taskprometheus package.
+// Since expvar variables cannot be unpublished, the groups using the same name share the counters,
+// and it panics if the name is already used by a variable which is not a map.
+func WithExpvar(name string) GroupOption {
+	return WithObserver(newExpvarObserver(name))
+}
+
+// expvarMu serializes looking up and publishing the expvar maps of the groups.
+var expvarMu sync.Mutex
+
+type expvarObserver struct {
+	running   *expvar.Int
+	completed *expvar.Int
+	failed    *expvar.Int
+}
+
+func newExpvarObserver(name string) *expvarObserver {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+
+	vars, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		vars = expvar.NewMap(name)
+	}
+
+	return &expvarObserver{
+		running:   expvarInt(vars, "running"),
+		completed: expvarInt(vars, "completed"),
+		failed:    expvarInt(vars, "failed"),
+	}
+}
+
+func (o *expvarObserver) TaskStarted(info TaskInfo) {
+	o.running.Add(1)
+}
+
+func (o *expvarObserver) TaskFinished(info TaskInfo, err error) {
+	o.running.Add(-1)
+
+	if err != nil && !info.Stopped {
+		o.failed.Add(1)
+
+		return
+	}
+
+	o.completed.Add(1)
+}
+
+// expvarInt returns the *expvar.Int stored under key in vars, adding it first if needed.
+func expvarInt(vars *expvar.Map, key string) *expvar.Int {
+	if v, ok := vars.Get(key).(*expvar.Int); ok {
+		return v
+	}
+
+	v := new(expvar.Int)
+	vars.Set(key, v)
+
+	return v
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestWithExpvar(t *testing.T) {
+	t.Run("it publishes the task counters", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithExpvar("test_tasks"))
+
+		vars, ok := expvar.Get("test_tasks").(*expvar.Map)
+		require.True(t, ok)
+
+		// NOTE: The counters are shared by the runs of the test, so assert how much they change.
+		counter := func(key string) int64 {
+			value, err := strconv.ParseInt(vars.Get(key).String(), 10, 64)
+			require.NoError(t, err)
+
+			return value
+		}
+		running, completed, failed := counter("running"), counter("completed"), counter("failed")
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-release
+
+			return nil
+		})
+
+		<-started
+		assert.Equal(t, running+1, counter("running"))
+
+		close(release)
+		assert.NoError(t, group.Wait(context.Background()))
+
+		failingGroup := task.NewGroup(task.WithExpvar("test_tasks"))
+		failingGroup.Go(func(ctx context.Context) error {
+			return errors.New("failed")
+		})
+		assert.Error(t, failingGroup.Wait(context.Background()))
+
+		assert.Equal(t, running, counter("running"))
+		assert.Equal(t, completed+1, counter("completed"))
+		assert.Equal(t, failed+1, counter("failed"))
+	})
+
+	t.Run("it counts the tasks stopped cleanly as completed", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithExpvar("test_stopped_tasks"))
+
+		vars, ok := expvar.Get("test_stopped_tasks").(*expvar.Map)
+		require.True(t, ok)
+
+		counter := func(key string) int64 {
+			value, err := strconv.ParseInt(vars.Get(key).String(), 10, 64)
+			require.NoError(t, err)
+
+			return value
+		}
+		completed, failed := counter("completed"), counter("failed")
+
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return ctx.Err()
+		})
+
+		<-started
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+
+		assert.Equal(t, completed+1, counter("completed"))
+		assert.Equal(t, failed, counter("failed"))
+	})
+}
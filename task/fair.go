@@ -0,0 +1,118 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+// WithTagWeights shares the concurrency limit of the group among the tags of the tasks proportionally
+// to their weights, so the tasks of a noisy tag do not starve the tasks of the other tags, see Group.GoTagged().
+//
+// Once a slot frees up, the next task started is the first queued task of the tag running the fewest tasks
+// relative to its weight. E.g. with the weights {"a": 3, "b": 1} and a limit of 4, the tasks tagged "a" get 3 slots
+// and the tasks tagged "b" get 1 while both have queued tasks. A tag uses the slots left by the other tags
+// once they have no queued tasks.
+// A task is accounted for by its first tag. The untagged tasks, and the tags without a weight, have a weight of 1.
+//
+// The option has no effect without a concurrency limit, see NewGroupWithLimit().
+func WithTagWeights(weights map[string]int) GroupOption {
+	return func(g *Group) {
+		g.tagWeights = make(map[string]int, len(weights))
+		for tag, weight := range weights {
+			g.tagWeights[tag] = weight
+		}
+
+		g.tagActive = make(map[string]int)
+	}
+}
+
+// fairTag returns the tag the task is accounted for by the weighted fair scheduling.
+func (t *taskEntry) fairTag() string {
+	if len(t.tags) == 0 {
+		return ""
+	}
+
+	return t.tags[0]
+}
+
+func (g *Group) tagWeightLocked(tag string) int {
+	weight := g.tagWeights[tag]
+	if weight < 1 {
+		return 1
+	}
+
+	return weight
+}
+
+// popQueuedLocked removes the next task to start from the queue, see WithTagWeights().
+func (g *Group) popQueuedLocked() *taskEntry {
+	i := g.nextQueuedLocked()
+	next := g.queue[i]
+
+	if i == 0 {
+		g.queue[0] = nil
+		g.queue = g.queue[1:]
+
+		return next
+	}
+
+	copy(g.queue[i:], g.queue[i+1:])
+	g.queue[len(g.queue)-1] = nil
+	g.queue = g.queue[:len(g.queue)-1]
+
+	return next
+}
+
+// nextQueuedLocked returns the index of the first queued task of the tag running the fewest tasks
+// relative to its weight, or the first queued task without tag weights.
+func (g *Group) nextQueuedLocked() int {
+	if g.tagWeights == nil {
+		return 0
+	}
+
+	next := 0
+	nextTag := g.queue[0].fairTag()
+	for i, t := range g.queue[1:] {
+		tag := t.fairTag()
+		if tag == nextTag {
+			continue
+		}
+
+		// NOTE: Compare active/weight of the tags without dividing them.
+		if g.tagActive[tag]*g.tagWeightLocked(nextTag) < g.tagActive[nextTag]*g.tagWeightLocked(tag) {
+			next = i + 1
+			nextTag = tag
+		}
+	}
+
+	return next
+}
+
+func (g *Group) trackTagStartedLocked(t *taskEntry) {
+	if g.tagWeights == nil {
+		return
+	}
+
+	g.tagActive[t.fairTag()]++
+}
+
+func (g *Group) trackTagReleasedLocked(t *taskEntry) {
+	if g.tagWeights == nil {
+		return
+	}
+
+	tag := t.fairTag()
+	g.tagActive[tag]--
+	if g.tagActive[tag] <= 0 {
+		delete(g.tagActive, tag)
+	}
+}
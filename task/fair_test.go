@@ -0,0 +1,140 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// tagConcurrency counts the running tasks of every tag.
+type tagConcurrency struct {
+	mu      sync.Mutex
+	running map[string]int
+	started int
+}
+
+func (c *tagConcurrency) add(tag string, delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.running[tag] += delta
+	if delta > 0 {
+		c.started++
+	}
+}
+
+func (c *tagConcurrency) Started() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.started
+}
+
+func (c *tagConcurrency) Running() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	running := make(map[string]int, len(c.running))
+	for tag, count := range c.running {
+		running[tag] = count
+	}
+
+	return running
+}
+
+func TestWithTagWeights(t *testing.T) {
+	t.Run("it shares the concurrency limit among the tags proportionally to their weights", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroupWithLimit(4, task.WithTagWeights(map[string]int{"noisy": 1, "important": 3}))
+		group.PauseScheduling()
+
+		concurrency := &tagConcurrency{running: make(map[string]int)}
+		release := make(chan struct{})
+		goTagged := func(tag string) {
+			group.GoTagged(func(ctx context.Context) error {
+				concurrency.add(tag, 1)
+				defer concurrency.add(tag, -1)
+
+				<-release
+
+				return nil
+			}, tag)
+		}
+
+		// NOTE: The tasks of the noisy tag are queued first, so without the weights they would take all the slots.
+		for i := 0; i < 6; i++ {
+			goTagged("noisy")
+		}
+		for i := 0; i < 6; i++ {
+			goTagged("important")
+		}
+
+		group.ResumeScheduling()
+
+		// NOTE: Every released task frees a slot, which is taken by the tag below its share,
+		// as long as both tags have queued tasks.
+		for i := 0; i < 3; i++ {
+			require.Eventually(t, func() bool {
+				return concurrency.Started() == 4+i
+			}, time.Second, time.Millisecond)
+			assert.Equal(t, map[string]int{"noisy": 1, "important": 3}, concurrency.Running())
+
+			if i < 2 {
+				release <- struct{}{}
+			}
+		}
+
+		close(release)
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("a tag uses the slots left by the other tags once they have no queued tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroupWithLimit(4, task.WithTagWeights(map[string]int{"noisy": 1, "important": 3}))
+		group.PauseScheduling()
+
+		concurrency := &tagConcurrency{running: make(map[string]int)}
+		release := make(chan struct{})
+		for i := 0; i < 6; i++ {
+			group.GoTagged(func(ctx context.Context) error {
+				concurrency.add("noisy", 1)
+				defer concurrency.add("noisy", -1)
+
+				<-release
+
+				return nil
+			}, "noisy")
+		}
+
+		group.ResumeScheduling()
+
+		require.Eventually(t, func() bool {
+			return concurrency.Running()["noisy"] == 4
+		}, time.Second, time.Millisecond)
+
+		close(release)
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultFlushInterval is how often the observers implementing the Flusher interface are flushed,
+// unless configured otherwise with WithFlushInterval().
+const DefaultFlushInterval = 10 * time.Second
+
+// Flusher is implemented by the observers which buffer what they are notified about,
+// e.g. to send it to a remote metrics backend in batches.
+//
+// The group registering such an observer with WithObserver() flushes it periodically while
+// it is running, and once more when all the tasks are stopped, before Group.Wait() returns.
+// Flush is never called concurrently by the same group.
+type Flusher interface {
+	Flush()
+}
+
+// WithFlushInterval sets how often the observers implementing the Flusher interface are flushed.
+// A zero or negative interval disables the periodic flushes, leaving only the final one.
+func WithFlushInterval(interval time.Duration) GroupOption {
+	return func(g *Group) {
+		g.flushInterval = interval
+	}
+}
+
+type flushState struct {
+	flushers []Flusher
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+// startFlushing starts flushing the observers implementing the Flusher interface in the background.
+//
+// NOTE: Unlike the liveness check, the flushes go on after the group is canceled, since
+// the tasks which are still draining keep notifying the observers.
+func (g *Group) startFlushing() {
+	var flushers []Flusher
+	for _, observer := range g.observers {
+		if flusher, ok := observer.(Flusher); ok {
+			flushers = append(flushers, flusher)
+		}
+	}
+
+	if len(flushers) == 0 {
+		return
+	}
+
+	g.flush = &flushState{
+		flushers: flushers,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	if g.flushInterval <= 0 {
+		close(g.flush.stopped)
+
+		return
+	}
+
+	ticker := g.clock.NewTicker(g.flushInterval)
+
+	go func() {
+		defer close(g.flush.stopped)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-g.flush.done:
+				return
+			case <-ticker.C():
+				g.flush.flushAll()
+			}
+		}
+	}()
+}
+
+// stopFlushing stops the periodic flushes once all the tasks are stopped, and flushes one last time.
+func (g *Group) stopFlushing() {
+	if g.flush == nil {
+		return
+	}
+
+	g.flush.once.Do(func() {
+		close(g.flush.done)
+		<-g.flush.stopped
+
+		g.flush.flushAll()
+	})
+}
+
+func (f *flushState) flushAll() {
+	for _, flusher := range f.flushers {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+type recordingFlusher struct {
+	recordingObserver
+	flushMu sync.Mutex
+	// flushes records how many tasks were finished at every flush
+	flushes   []int
+	flushedCh chan struct{}
+}
+
+func newRecordingFlusher() *recordingFlusher {
+	return &recordingFlusher{flushedCh: make(chan struct{}, 10)}
+}
+
+func (f *recordingFlusher) Flush() {
+	finished := len(f.Finished())
+
+	f.flushMu.Lock()
+	f.flushes = append(f.flushes, finished)
+	f.flushMu.Unlock()
+
+	f.flushedCh <- struct{}{}
+}
+
+func (f *recordingFlusher) Flushes() []int {
+	f.flushMu.Lock()
+	defer f.flushMu.Unlock()
+
+	return append([]int(nil), f.flushes...)
+}
+
+func TestFlusher(t *testing.T) {
+	t.Run("it flushes the observer every interval and once more when the tasks are stopped", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		flusher := newRecordingFlusher()
+		group := task.NewGroup(
+			task.WithClock(clock),
+			task.WithObserver(flusher),
+			task.WithFlushInterval(time.Second),
+		)
+
+		release := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			<-release
+
+			return nil
+		})
+
+		for i := 0; i < 2; i++ {
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			<-flusher.flushedCh
+		}
+
+		close(release)
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []int{0, 0, 1}, flusher.Flushes())
+	})
+
+	t.Run("without a flush interval, it flushes the observer only when the tasks are stopped", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		flusher := newRecordingFlusher()
+		group := task.NewGroup(
+			task.WithClock(clock),
+			task.WithObserver(flusher),
+			task.WithFlushInterval(0),
+		)
+
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, clock.Waiters())
+		assert.Equal(t, []int{1}, flusher.Flushes())
+	})
+
+	t.Run("it flushes the observer once, even when waited for multiple times", func(t *testing.T) {
+		t.Parallel()
+
+		flusher := newRecordingFlusher()
+		group := task.NewGroup(task.WithObserver(flusher))
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, []int{0}, flusher.Flushes())
+	})
+
+	t.Run("it describes the flush in the shutdown order", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithObserver(newRecordingFlusher()))
+		group.OnShutdown(func(ctx context.Context) error { return nil })
+
+		assert.Equal(t, "1. cancel tasks: none\n2. flush observers\n3. run shutdown hook 0\n", group.DescribeShutdownOrder())
+	})
+}
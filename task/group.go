@@ -18,34 +18,166 @@ import (
 	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
 // Group is used to wait for a group of tasks to finish.
 //
 // It will stop all the tasks on the first task failure, and the Wait() method will return only the
-// first encountered error.
+// first encountered error, unless the group collects the errors, see WithCollectErrors().
 type Group struct {
+	// canceledAt is the unix time in nanoseconds when the group was canceled, 0 if it is not canceled.
+	// NOTE: Keep it first in the struct, so it is 64-bit aligned for the atomic operations.
+	canceledAt int64
+	// unnamedSeq is the number of unnamed tasks so far, used to generate their names, e.g. "task-0".
+	// NOTE: Keep it right after canceledAt, so it is 64-bit aligned as well.
+	unnamedSeq uint64
+
 	wg             sync.WaitGroup
 	ctx            context.Context
 	cancelFunc     context.CancelFunc
 	firstRunErrPtr unsafe.Pointer
+	clock          Clock
+	launchLimiter  *launchLimiter
+	observers      []Observer
+	taskDeadline   time.Time
+
+	admissionController    func(ctx context.Context) error
+	admissionRetryInterval time.Duration
+
+	// mu protects the scheduling state
+	mu              sync.Mutex
+	limit           int
+	autoConcurrency int
+	active          int
+	queue           []*taskEntry
+	tasks           map[*taskEntry]struct{}
+	taskSeq         uint64
+	named           map[string]map[*taskEntry]struct{}
+	tagged          map[string]map[*taskEntry]struct{}
+	awaitingReady   []*taskEntry
+	// exitedNotReady are the names of the awaited tasks which returned without being ready, see Group.WaitReady()
+	exitedNotReady []string
+	paused         bool
+	hasOrdered     bool
+	keepResults    bool
+	results        []error
+	tagWeights     map[string]int
+	// tagActive is the number of the running tasks of every tag, see WithTagWeights()
+	tagActive map[string]int
+	summary   RunSummary
+	// summaryStartedAt and summaryFinishedAt bound the run of the completed tasks, see Group.Summary()
+	summaryStartedAt  time.Time
+	summaryFinishedAt time.Time
+
+	// errChMu protects the errCh channel from being written to after it is closed
+	errChMu     sync.Mutex
+	errCh       chan error
+	errChClosed bool
+
+	// running is the number of the task functions being executed
+	running int32
+	// pending is the number of the scheduled tasks which have not exited yet, protected by mu
+	pending  int
+	doneCh   chan struct{}
+	doneOnce sync.Once
+
+	// startStagger is the minimum delay between the start of consecutive tasks.
+	// The staggerTail is closed once the last started task is done with its turn, and it is protected by mu.
+	// The lastStaggeredStart is protected by the turns, see Group.awaitStagger().
+	startStagger       time.Duration
+	staggerTail        chan struct{}
+	lastStaggeredStart time.Time
+
+	livenessInterval time.Duration
+	livenessCheck    func(ctx context.Context) error
+	livenessDone     chan struct{}
+	livenessOnce     sync.Once
+
+	watchdogMaxRunTime time.Duration
+	watchdogAction     WatchdogAction
+	watchdogDone       chan struct{}
+	watchdogOnce       sync.Once
+
+	stopConditions     []func(ctx context.Context) <-chan struct{}
+	stopConditionsDone chan struct{}
+	stopConditionsOnce sync.Once
+
+	parents     []context.Context
+	parentsDone chan struct{}
+	parentsOnce sync.Once
+
+	flushInterval time.Duration
+	flush         *flushState
+
+	collectErrors bool
+	isCritical    func(err error) bool
+	collectedErrs []error
+	// maxFailures is the number of the collected errors canceling the group, 0 if it is unlimited
+	maxFailures        int
+	maxFailuresReached bool
+
+	checkpoints CheckpointStore
+
+	// taskLocations tells whether the location scheduling a task is captured, see WithTaskLocations()
+	taskLocations bool
+
+	stopInOrderOnce sync.Once
+
+	shutdownOnce        sync.Once
+	shutdownHookTimeout time.Duration
+	shutdownHooks       []ShutdownHook
+	shutdownErrs        []error
+	onRunSummary        func(summary RunSummary)
+
+	events eventStream
 }
 
+// GroupOption configures a Group.
+type GroupOption func(g *Group)
+
 // NewGroup creates new task group instance.
-func NewGroup() *Group {
+func NewGroup(opts ...GroupOption) *Group {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Group{
-		ctx:        ctx,
-		cancelFunc: cancel,
+	g := &Group{
+		ctx:           ctx,
+		cancelFunc:    cancel,
+		errCh:         make(chan error, 1),
+		doneCh:        make(chan struct{}),
+		clock:         realClock{},
+		flushInterval: DefaultFlushInterval,
+	}
+
+	for _, opt := range opts {
+		opt(g)
 	}
+
+	if g.startStagger > 0 {
+		g.staggerTail = make(chan struct{})
+		close(g.staggerTail)
+	}
+
+	if g.limit <= 0 && g.autoConcurrency > 0 {
+		g.limit = autoConcurrencyLimit(g.autoConcurrency)
+	}
+
+	g.startLivenessCheck()
+	g.startWatchdog()
+	g.watchStopConditions()
+	g.watchParents()
+	g.startFlushing()
+
+	return g
 }
 
 // Go runs tasks in the group.
 //
 // Every task is run in new goroutine.
-// When a task returns an error, all the tasks in the group are canceled.
+// When the group has a concurrency limit, the tasks exceeding it are queued and started
+// once the running tasks complete.
+// When a task returns an error or panics, all the tasks in the group are canceled, see PanicError.
 //
 // Typically one should schedule tasks with the Group.Go() method and then wait for all of them to
 // finish by using the Group.Wait() method.
@@ -54,21 +186,57 @@ func (g *Group) Go(tasks ...TaskFunc) {
 		return
 	}
 
-	for _, fn := range tasks {
-		g.wg.Add(1)
-		go func(fn TaskFunc) {
-			defer g.wg.Done()
+	entries := make([]*taskEntry, len(tasks))
+	for i, fn := range tasks {
+		entries[i] = g.newTaskEntry("", fn)
+	}
 
-			err := fn(g.ctx)
-			if err != nil {
-				g.cancelWithError(err)
-			}
-		}(fn)
+	g.schedule(entries)
+}
+
+// run invokes the task function, emitting its lifecycle events, see Group.Events().
+func (g *Group) run(t *taskEntry) error {
+	g.emitEvent(EventTaskStarted, t.label, nil)
+
+	err := g.observe(t)
+	g.emitTaskFinished(t, err)
+
+	return err
+}
+
+// observe invokes the task function and notifies the observers about it.
+// A panic of the task function is returned as a *PanicError.
+func (g *Group) observe(t *taskEntry) error {
+	if len(g.observers) == 0 {
+		return t.stuckError(call(t.ctx, t.label, t.fn))
+	}
+
+	info := TaskInfo{
+		StartedAt: g.clock.Now(),
 	}
+	g.notifyTaskStarted(info)
+
+	err := t.stuckError(call(t.ctx, t.label, t.fn))
+
+	finishedAt := g.clock.Now()
+	info.Duration = finishedAt.Sub(info.StartedAt)
+
+	canceledAt := atomic.LoadInt64(&t.canceledAt)
+	if canceledAt == 0 {
+		canceledAt = atomic.LoadInt64(&g.canceledAt)
+	}
+	if canceledAt != 0 && canceledAt < finishedAt.UnixNano() {
+		info.DrainDuration = time.Duration(finishedAt.UnixNano() - canceledAt)
+	}
+	info.Stopped = err != nil && g.isCleanStop(t, err)
+
+	g.notifyTaskFinished(info, err)
+
+	return err
 }
 
-// Wait until all tasks are stopped.
-// Returns the first encountered error if any.
+// Wait until all tasks are stopped, then run the shutdown hooks registered with Group.OnShutdown().
+// Returns the first encountered error if any, including the errors of the shutdown hooks.
 // If the context is done all tasks are canceled and the context error is returned.
 func (g *Group) Wait(ctx context.Context) error {
 	if ctx != context.TODO() {
@@ -80,30 +248,69 @@ func (g *Group) Wait(ctx context.Context) error {
 			case <-g.ctx.Done():
 			case <-doneCh:
 			case <-ctx.Done():
-				g.cancelWithError(ctx.Err())
+				g.cancelWithError(ctx, ctx.Err())
 			}
 		}()
 	}
 
 	g.wg.Wait()
+	g.stopLivenessCheck()
+	g.stopWatchdog()
+	g.stopWatchingStopConditions()
+	g.stopWatchingParents()
+	g.stopFlushing()
+	g.shutdownOnce.Do(func() {
+		g.emitEvent(EventGroupShuttingDown, "", nil)
+		g.runShutdownHooks()
+		g.emitRunSummary()
+	})
+	g.closeEvents()
+	g.closeErrorChan()
+	g.closeDone()
 
 	err := (*error)(atomic.LoadPointer(&g.firstRunErrPtr))
 	if err != nil {
 		return *err
 	}
 
+	collectedErr := g.collectedError()
+	if collectedErr != nil {
+		return collectedErr
+	}
+
+	shutdownErrs := g.ShutdownErrors()
+	if len(shutdownErrs) > 0 {
+		return shutdownErrs[0]
+	}
+
 	return nil
 }
 
-func (g *Group) cancelWithError(err error) {
+// cancelWithError cancels the group with the error, if it is the first one.
+// The ctx is the context the error originates from.
+func (g *Group) cancelWithError(ctx context.Context, err error) {
 	swapped := atomic.CompareAndSwapPointer(&g.firstRunErrPtr, nil, (unsafe.Pointer)(&err))
 
 	if swapped {
-		g.cancelFunc()
+		g.notifyFirstError(withTaskContext(ctx, err))
+		g.cancel()
 	}
 }
 
 // Cancel cancels all the tasks.
+//
+// The tasks returning the error of their canceled context, e.g. ctx.Err(), are stopped cleanly,
+// so Group.Wait() does not return it.
 func (g *Group) Cancel() {
+	g.cancel()
+}
+
+func (g *Group) cancel() {
+	if atomic.CompareAndSwapInt64(&g.canceledAt, 0, g.clock.Now().UnixNano()) {
+		g.emitEvent(EventGroupCanceling, "", nil)
+	}
 	g.cancelFunc()
+	g.dropQueued()
+	g.stopInOrder()
+	g.closeDoneIfIdle()
 }
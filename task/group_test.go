@@ -16,6 +16,7 @@ package task_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -168,6 +169,36 @@ func TestGroup_Cancel(t *testing.T) {
 		assert.Equal(t, 1, foo.StopCount)
 		assert.Equal(t, 1, bar.StopCount)
 	})
+
+	t.Run("the tasks returning the error of their canceled context stop cleanly", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return fmt.Errorf("stopped: %w", ctx.Err())
+		})
+
+		<-started
+		group.Cancel()
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("a task returning a context error of its own still fails the group", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		group.Go(func(ctx context.Context) error {
+			return context.DeadlineExceeded
+		})
+
+		assert.Equal(t, context.DeadlineExceeded, group.Wait(context.Background()))
+	})
 }
 
 func BenchmarkGroup_Go(b *testing.B) {
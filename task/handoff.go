@@ -0,0 +1,42 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TakeOverFrom hands off from the old group to this one, e.g. when reloading the configuration
+// starts a new generation of the tasks.
+//
+// It waits up to timeout until the tasks of this group started with Group.GoNamedReady() signal
+// they are ready, then cancels the old group and waits until all its tasks are stopped.
+// So a singleton task never has a gap, both generations of it run until the old one stops.
+//
+// When this group is not ready within the timeout, the old group keeps running and the error of
+// Group.WaitReadyTimeout() is returned, so it is up to the caller to cancel this group.
+// Otherwise, it returns the error returned by the Group.Wait() of the old group.
+func (g *Group) TakeOverFrom(old *Group, timeout time.Duration) error {
+	err := g.WaitReadyTimeout(timeout)
+	if err != nil {
+		return fmt.Errorf("new generation not ready, the old one keeps running: %w", err)
+	}
+
+	old.Cancel()
+
+	return old.Wait(context.Background())
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_TakeOverFrom(t *testing.T) {
+	t.Run("it cancels the old group once the new generation is ready", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		var events []string
+		record := func(event string) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			events = append(events, event)
+		}
+
+		old := task.NewGroup()
+		old.GoNamed("singleton", func(ctx context.Context) error {
+			<-ctx.Done()
+			record("old stopped")
+
+			return nil
+		})
+
+		release := make(chan struct{})
+		next := task.NewGroup()
+		next.GoNamedReady("singleton", func(ctx context.Context) error {
+			<-release
+			record("new ready")
+			task.Ready(ctx)
+			<-ctx.Done()
+
+			return nil
+		})
+
+		tookOver := make(chan error)
+		go func() {
+			tookOver <- next.TakeOverFrom(old, time.Minute)
+		}()
+
+		select {
+		case <-tookOver:
+			t.Fatal("TakeOverFrom returned before the new generation is ready")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(release)
+		assert.NoError(t, <-tookOver)
+
+		mu.Lock()
+		assert.Equal(t, []string{"new ready", "old stopped"}, events)
+		mu.Unlock()
+
+		next.Cancel()
+		assert.NoError(t, next.Wait(context.Background()))
+	})
+
+	t.Run("when the new generation is not ready in time, it keeps the old group running", func(t *testing.T) {
+		t.Parallel()
+
+		old := task.NewGroup()
+		oldStopped := make(chan struct{})
+		old.GoNamed("singleton", func(ctx context.Context) error {
+			<-ctx.Done()
+			close(oldStopped)
+
+			return nil
+		})
+
+		next := task.NewGroup()
+		next.GoNamedReady("singleton", func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return nil
+		})
+
+		err := next.TakeOverFrom(old, 10*time.Millisecond)
+		assert.True(t, errors.Is(err, task.ErrNotReady))
+
+		select {
+		case <-oldStopped:
+			t.Fatal("the old group must keep running")
+		default:
+		}
+
+		next.Cancel()
+		assert.NoError(t, next.Wait(context.Background()))
+
+		old.Cancel()
+		assert.NoError(t, old.Wait(context.Background()))
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// WithLaunchRate limits how fast the group starts new tasks.
+//
+// The start of every task is paced by a token bucket refilled with rps tokens per second,
+// which can hold at most burst tokens. This only limits how often tasks are started and not how many
+// of them run at the same time.
+// Tasks waiting for their turn are not started at all if the group is canceled in the meantime.
+//
+// A non-positive rps disables the limiting.
+func WithLaunchRate(rps float64, burst int) GroupOption {
+	return func(g *Group) {
+		if rps <= 0 {
+			g.launchLimiter = nil
+
+			return
+		}
+
+		g.launchLimiter = newLaunchLimiter(rps, burst)
+	}
+}
+
+// launchLimiter is a token bucket used to pace the start of tasks.
+//
+// Instead of blocking, every call to reserve takes a token (possibly going into debt) and returns
+// the time at which the caller is allowed to proceed. This keeps the launch order stable and does not
+// block the Group.Go() callers.
+type launchLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLaunchLimiter(rps float64, burst int) *launchLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &launchLimiter{
+		rate:   rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// reserve takes a token and returns the time at which it is available.
+func (l *launchLimiter) reserve(now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		elapsed := now.Sub(l.last).Seconds()
+		l.tokens = math.Min(l.burst, l.tokens+elapsed*l.rate)
+	}
+
+	l.last = now
+	l.tokens--
+
+	if l.tokens >= 0 {
+		return now
+	}
+
+	wait := -l.tokens / l.rate * float64(time.Second)
+
+	return now.Add(time.Duration(wait))
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithLaunchRate(t *testing.T) {
+	t.Run("it paces the start of the tasks", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		group := task.NewGroup(task.WithClock(clock), task.WithLaunchRate(50, 1))
+
+		started := make(chan struct{}, 4)
+		for i := 0; i < 4; i++ {
+			group.Go(func(ctx context.Context) error {
+				started <- struct{}{}
+
+				return nil
+			})
+		}
+
+		// NOTE: 50 launches per second means one launch every 20ms.
+		<-started
+		for i := 1; i < 4; i++ {
+			clock.BlockUntil(4 - i)
+
+			clock.Advance(19 * time.Millisecond)
+			select {
+			case <-started:
+				t.Fatal("the task started before its turn")
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			clock.Advance(time.Millisecond)
+			<-started
+		}
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it starts up to burst tasks immediately", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithLaunchRate(1, 3))
+
+		started := make(chan struct{}, 3)
+		for i := 0; i < 3; i++ {
+			group.Go(func(ctx context.Context) error {
+				started <- struct{}{}
+
+				return nil
+			})
+		}
+
+		timeout := time.After(500 * time.Millisecond)
+		for i := 0; i < 3; i++ {
+			select {
+			case <-started:
+			case <-timeout:
+				t.Fatal("the burst tasks were not started immediately")
+			}
+		}
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("when the group is canceled, it does not start the tasks waiting for their turn", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithLaunchRate(0.001, 1))
+		foo := NewTestTask(nil)
+		bar := NewTestTask(nil)
+
+		group.Go(foo.Run, bar.Run)
+
+		<-foo.RunReady
+		group.Cancel()
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+
+		assert.Equal(t, 1, foo.RunCount)
+		assert.Equal(t, 1, foo.StopCount)
+		assert.Equal(t, 0, bar.RunCount)
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"time"
+)
+
+// WithLivenessCheck runs check every interval while the group is running, and cancels all the tasks
+// if the check fails, e.g. when a dependency becomes unreachable.
+//
+// The error returned by the check is the error returned by Group.Wait().
+// The check is run with the context of the group, so it is canceled when the group is canceled.
+func WithLivenessCheck(interval time.Duration, check func(ctx context.Context) error) GroupOption {
+	return func(g *Group) {
+		g.livenessInterval = interval
+		g.livenessCheck = check
+	}
+}
+
+// startLivenessCheck starts checking the liveness in the background, if configured.
+func (g *Group) startLivenessCheck() {
+	if g.livenessCheck == nil || g.livenessInterval <= 0 {
+		return
+	}
+
+	g.livenessDone = make(chan struct{})
+	ticker := g.clock.NewTicker(g.livenessInterval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-g.ctx.Done():
+				return
+			case <-g.livenessDone:
+				return
+			case <-ticker.C():
+			}
+
+			err := g.livenessCheck(g.ctx)
+			if err != nil && g.ctx.Err() == nil {
+				g.cancelWithError(g.ctx, err)
+
+				return
+			}
+		}
+	}()
+}
+
+// stopLivenessCheck stops checking the liveness once all the tasks are stopped.
+func (g *Group) stopLivenessCheck() {
+	if g.livenessDone == nil {
+		return
+	}
+
+	g.livenessOnce.Do(func() {
+		close(g.livenessDone)
+	})
+}
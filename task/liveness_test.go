@@ -0,0 +1,99 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithLivenessCheck(t *testing.T) {
+	t.Run("when the check fails, it cancels the tasks and returns the check error", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		errUnreachable := errors.New("dependency unreachable")
+
+		checks := make(chan int)
+		calls := 0
+		group := task.NewGroup(
+			task.WithClock(clock),
+			task.WithLivenessCheck(time.Second, func(ctx context.Context) error {
+				calls++
+				defer func() { checks <- calls }()
+
+				if calls == 3 {
+					return errUnreachable
+				}
+
+				return nil
+			}),
+		)
+
+		var taskErr error
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			taskErr = ctx.Err()
+
+			return nil
+		})
+		// NOTE: The tasks canceled before they start are not run.
+		<-started
+
+		for i := 1; i <= 3; i++ {
+			clock.BlockUntil(1)
+			clock.Advance(time.Second)
+			assert.Equal(t, i, <-checks)
+		}
+
+		err := group.Wait(context.Background())
+		assert.Equal(t, errUnreachable, err)
+		assert.Equal(t, context.Canceled, taskErr)
+	})
+
+	t.Run("it returns no error when the tasks are done before the check fails", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		group := task.NewGroup(
+			task.WithClock(clock),
+			task.WithLivenessCheck(time.Second, func(ctx context.Context) error {
+				return errors.New("must not be checked")
+			}),
+		)
+
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+
+		// NOTE: The ticker of the check is stopped once the tasks are done.
+		for clock.Waiters() > 0 {
+			runtime.Gosched()
+		}
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrTaskNotFound is returned when there is no running or queued task with the requested name.
+var ErrTaskNotFound = errors.New("task not found")
+
+// GoNamed runs a task in the group, the same way Group.Go() does, and associates a name with it.
+//
+// The name can be used to stop the task on its own with Group.StopTask().
+// Multiple tasks can share the same name.
+//
+// The error returned by the task is wrapped with its name, e.g. `task "consumer" failed: connection lost`,
+// so it can still be matched with errors.Is() and errors.As().
+// The tasks run with Group.Go() get an auto-generated name instead, e.g. "task-0", used only by PanicError.
+// The named tasks are checkpointed by the group, see WithCheckpointStore().
+func (g *Group) GoNamed(name string, fn TaskFunc) {
+	if g.ctx.Err() != nil {
+		return
+	}
+
+	g.schedule([]*taskEntry{g.newTaskEntry(name, g.checkpointed(name, fn))})
+}
+
+// StopTask stops the tasks with the provided name without stopping the rest of the group.
+//
+// The queued tasks with this name are discarded, and the running ones have their context canceled.
+// StopTask waits until all of them return, except the task calling it to stop itself, which is
+// identified by ctx, i.e. the task passes its own context, or one derived from it.
+// A stopped task returning context.Canceled is considered stopped cleanly, while any other error
+// returned by it fails the group as usual.
+//
+// Returns ErrTaskNotFound if there is no running or queued task with this name.
+func (g *Group) StopTask(ctx context.Context, name string) error {
+	g.mu.Lock()
+	tasks := g.detachLocked(g.named[name])
+	g.mu.Unlock()
+
+	if len(tasks) == 0 {
+		return fmt.Errorf("task %q: %w", name, ErrTaskNotFound)
+	}
+
+	g.stopTasks(ctx, tasks)
+
+	return nil
+}
+
+// detachLocked discards the queued tasks of the set, and returns all the tasks of the set.
+func (g *Group) detachLocked(set map[*taskEntry]struct{}) []*taskEntry {
+	tasks := make([]*taskEntry, 0, len(set))
+	for t := range set {
+		tasks = append(tasks, t)
+	}
+
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	queue := g.queue[:0]
+	for _, t := range g.queue {
+		if _, ok := set[t]; ok {
+			g.discardLocked(t)
+
+			continue
+		}
+
+		queue = append(queue, t)
+	}
+	for i := len(queue); i < len(g.queue); i++ {
+		g.queue[i] = nil
+	}
+	g.queue = queue
+
+	return tasks
+}
+
+// stopTasks stops the tasks and waits until all of them return.
+// The ctx is the context of the caller.
+func (g *Group) stopTasks(ctx context.Context, tasks []*taskEntry) {
+	for _, t := range tasks {
+		t.stop(g.clock.Now())
+	}
+
+	// NOTE: A task stopping itself would wait for itself to return forever, so it is not waited for.
+	self := taskFromContext(ctx)
+	for _, t := range tasks {
+		if t == self {
+			continue
+		}
+
+		<-t.done
+	}
+}
+
+// registerLocked indexes the scheduled task, so it can be looked up e.g. by Group.StopTask() or Group.Snapshot().
+func (g *Group) registerLocked(t *taskEntry) {
+	g.taskSeq++
+	t.seq = g.taskSeq
+
+	if g.tasks == nil {
+		g.tasks = make(map[*taskEntry]struct{})
+	}
+	g.tasks[t] = struct{}{}
+
+	if t.name != "" {
+		g.named = addToIndex(g.named, t.name, t)
+	}
+
+	for _, tag := range t.tags {
+		g.tagged = addToIndex(g.tagged, tag, t)
+	}
+}
+
+func (g *Group) unregisterLocked(t *taskEntry) {
+	delete(g.tasks, t)
+
+	if t.name != "" {
+		removeFromIndex(g.named, t.name, t)
+	}
+
+	for _, tag := range t.tags {
+		removeFromIndex(g.tagged, tag, t)
+	}
+}
+
+func addToIndex(index map[string]map[*taskEntry]struct{}, key string, t *taskEntry) map[string]map[*taskEntry]struct{} {
+	if index == nil {
+		index = make(map[string]map[*taskEntry]struct{})
+	}
+
+	if index[key] == nil {
+		index[key] = make(map[*taskEntry]struct{})
+	}
+
+	index[key][t] = struct{}{}
+
+	return index
+}
+
+func removeFromIndex(index map[string]map[*taskEntry]struct{}, key string, t *taskEntry) {
+	delete(index[key], t)
+
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
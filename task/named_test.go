@@ -0,0 +1,84 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_GoNamed(t *testing.T) {
+	t.Run("it wraps the error of the task with its name", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		errLost := errors.New("connection lost")
+
+		group.GoNamed("consumer-orders", func(ctx context.Context) error {
+			return errLost
+		})
+
+		err := group.Wait(context.Background())
+		assert.EqualError(t, err, `task "consumer-orders" failed: connection lost`)
+		assert.True(t, errors.Is(err, errLost))
+	})
+
+	t.Run("it attaches the name to the panic error", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.GoNamed("consumer-orders", func(ctx context.Context) error {
+			panic("boom")
+		})
+
+		err := group.Wait(context.Background())
+
+		var panicErr *task.PanicError
+		require.True(t, errors.As(err, &panicErr))
+		assert.Equal(t, "consumer-orders", panicErr.Task)
+		assert.EqualError(t, err, `task "consumer-orders" panicked: boom`)
+	})
+
+	t.Run("it keeps the errors of the unnamed tasks as they are, naming them only in the panic errors", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		errFailed := errors.New("failed")
+
+		group.Go(func(ctx context.Context) error {
+			return errFailed
+		})
+		assert.Equal(t, errFailed, group.Wait(context.Background()))
+
+		group = task.NewGroup()
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+		group.Go(func(ctx context.Context) error {
+			panic("boom")
+		})
+
+		var panicErr *task.PanicError
+		require.True(t, errors.As(group.Wait(context.Background()), &panicErr))
+		assert.Equal(t, "task-1", panicErr.Task)
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import "time"
+
+// Observer is notified about the lifecycle of the tasks run by a Group.
+//
+// The methods are called from the goroutines running the tasks,
+// so the implementations must be safe for concurrent use.
+type Observer interface {
+	// TaskStarted is called right before the task function is invoked.
+	TaskStarted(info TaskInfo)
+	// TaskFinished is called after the task function returns, with the error returned by it.
+	// The task failed only if the err is not nil and TaskInfo.Stopped is false.
+	TaskFinished(info TaskInfo, err error)
+}
+
+// TaskInfo describes a task run by a Group.
+type TaskInfo struct {
+	// StartedAt is the time when the task function was invoked.
+	StartedAt time.Time
+	// Duration is how long the task function ran.
+	// It is zero when passed to Observer.TaskStarted().
+	Duration time.Duration
+	// DrainDuration is how long the task took to return after the group was canceled.
+	// It is zero if the task returned before the group was canceled.
+	DrainDuration time.Duration
+	// Stopped tells whether the task stopped cleanly by returning the error of its context canceled
+	// by the group, or by Group.StopTask(), so the error passed to Observer.TaskFinished() does not fail the group.
+	Stopped bool
+}
+
+// WithObserver registers an observer notified about the lifecycle of every task run by the group.
+//
+// The option can be used multiple times to register more than one observer.
+func WithObserver(observer Observer) GroupOption {
+	return func(g *Group) {
+		g.observers = append(g.observers, observer)
+	}
+}
+
+func (g *Group) notifyTaskStarted(info TaskInfo) {
+	for _, observer := range g.observers {
+		observer.TaskStarted(info)
+	}
+}
+
+func (g *Group) notifyTaskFinished(info TaskInfo, err error) {
+	for _, observer := range g.observers {
+		observer.TaskFinished(info, err)
+	}
+}
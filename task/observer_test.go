@@ -0,0 +1,120 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+type finishedTask struct {
+	info task.TaskInfo
+	err  error
+}
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	started  []task.TaskInfo
+	finished []finishedTask
+}
+
+func (o *recordingObserver) TaskStarted(info task.TaskInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.started = append(o.started, info)
+}
+
+func (o *recordingObserver) TaskFinished(info task.TaskInfo, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.finished = append(o.finished, finishedTask{info: info, err: err})
+}
+
+func (o *recordingObserver) Started() []task.TaskInfo {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]task.TaskInfo(nil), o.started...)
+}
+
+func (o *recordingObserver) Finished() []finishedTask {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]finishedTask(nil), o.finished...)
+}
+
+func TestWithObserver(t *testing.T) {
+	t.Run("it notifies the observers about the task lifecycle", func(t *testing.T) {
+		t.Parallel()
+
+		foo := &recordingObserver{}
+		bar := &recordingObserver{}
+		group := task.NewGroup(task.WithObserver(foo), task.WithObserver(bar))
+
+		group.Go(func(ctx context.Context) error {
+			return assert.AnError
+		})
+
+		assert.Equal(t, assert.AnError, group.Wait(context.Background()))
+
+		for _, observer := range []*recordingObserver{foo, bar} {
+			started := observer.Started()
+			require.Len(t, started, 1)
+			assert.False(t, started[0].StartedAt.IsZero())
+			assert.Zero(t, started[0].Duration)
+
+			finished := observer.Finished()
+			require.Len(t, finished, 1)
+			assert.Equal(t, started[0].StartedAt, finished[0].info.StartedAt)
+			assert.Equal(t, assert.AnError, finished[0].err)
+			assert.Zero(t, finished[0].info.DrainDuration)
+		}
+	})
+
+	t.Run("it reports how long the tasks took to stop after the group was canceled", func(t *testing.T) {
+		t.Parallel()
+
+		observer := &recordingObserver{}
+		group := task.NewGroup(task.WithObserver(observer))
+		running := make(chan struct{})
+
+		group.Go(func(ctx context.Context) error {
+			close(running)
+			<-ctx.Done()
+			time.Sleep(20 * time.Millisecond)
+
+			return nil
+		})
+
+		<-running
+		group.Cancel()
+		require.NoError(t, group.Wait(context.Background()))
+
+		finished := observer.Finished()
+		require.Len(t, finished, 1)
+		assert.GreaterOrEqual(t, int64(finished[0].info.DrainDuration), int64(20*time.Millisecond))
+		assert.GreaterOrEqual(t, int64(finished[0].info.Duration), int64(finished[0].info.DrainDuration))
+	})
+}
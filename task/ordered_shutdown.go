@@ -0,0 +1,115 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+)
+
+// GoWithShutdownOrder runs tasks in the group, the same way Group.Go() does, and assigns them a shutdown order,
+// e.g. to stop an HTTP server before the DB pool it uses is closed.
+//
+// When the group is canceled, the tasks run with Group.Go() are canceled right away as usual, while the tasks
+// with a shutdown order are stopped tier by tier, the lowest order first. A tier is canceled only once
+// all the tasks of the lower tiers have returned, and the tasks of the same tier stop concurrently.
+// An order lower than 1 is the same as running the tasks with Group.Go().
+//
+// See Group.DescribeShutdownOrder().
+func (g *Group) GoWithShutdownOrder(order int, tasks ...TaskFunc) {
+	if order < 1 {
+		g.Go(tasks...)
+
+		return
+	}
+
+	if g.ctx.Err() != nil {
+		return
+	}
+
+	entries := make([]*taskEntry, len(tasks))
+	for i, fn := range tasks {
+		// NOTE: The context of the task is not derived from the group context, so it is not canceled
+		// together with the group, but once the tier of the task is stopped.
+		entries[i] = g.newTaskEntryWithParent(context.Background(), "", fn)
+		entries[i].shutdownOrder = order
+	}
+
+	g.mu.Lock()
+	g.hasOrdered = true
+	g.mu.Unlock()
+
+	g.schedule(entries)
+}
+
+// stopInOrder starts stopping the tasks with a shutdown order, if there are any.
+func (g *Group) stopInOrder() {
+	g.mu.Lock()
+	hasOrdered := g.hasOrdered
+	g.mu.Unlock()
+
+	if !hasOrdered {
+		return
+	}
+
+	g.stopInOrderOnce.Do(func() {
+		go g.stopTiers()
+	})
+}
+
+// stopTiers cancels the tiers of the tasks one by one, once all the tasks of the lower tiers have returned.
+func (g *Group) stopTiers() {
+	for {
+		lower, tier := g.nextTier()
+		if len(tier) == 0 {
+			return
+		}
+
+		for _, t := range lower {
+			<-t.done
+		}
+
+		for _, t := range tier {
+			t.cancel()
+		}
+	}
+}
+
+// nextTier returns the tasks of the lowest tier which is not canceled yet, and the tasks of the lower tiers.
+func (g *Group) nextTier() (lower []*taskEntry, tier []*taskEntry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	order := 0
+	for t := range g.tasks {
+		if t.shutdownOrder > 0 && t.ctx.Err() == nil && (order == 0 || t.shutdownOrder < order) {
+			order = t.shutdownOrder
+		}
+	}
+
+	if order == 0 {
+		return nil, nil
+	}
+
+	for t := range g.tasks {
+		switch {
+		case t.shutdownOrder < order:
+			lower = append(lower, t)
+		case t.shutdownOrder == order:
+			tier = append(tier, t)
+		}
+	}
+
+	return lower, tier
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_GoWithShutdownOrder(t *testing.T) {
+	t.Run("it stops the tiers one by one once the lower tiers have returned", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		var stopped []string
+		var started sync.WaitGroup
+		started.Add(3)
+		stopAfter := func(name string, drain time.Duration) task.TaskFunc {
+			return func(ctx context.Context) error {
+				started.Done()
+				<-ctx.Done()
+				time.Sleep(drain)
+
+				mu.Lock()
+				stopped = append(stopped, name)
+				mu.Unlock()
+
+				return nil
+			}
+		}
+
+		group := task.NewGroup()
+		group.GoWithShutdownOrder(2, stopAfter("cache", 0))
+		group.GoWithShutdownOrder(1, stopAfter("db-pool", 10*time.Millisecond))
+		group.Go(stopAfter("http-server", 20*time.Millisecond))
+
+		// NOTE: The tasks canceled before they are started are never run.
+		started.Wait()
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, []string{"http-server", "db-pool", "cache"}, stopped)
+	})
+
+	t.Run("it stops the tasks of the same tier concurrently", func(t *testing.T) {
+		t.Parallel()
+
+		var started, barrier sync.WaitGroup
+		started.Add(2)
+		barrier.Add(2)
+		awaitSibling := func(ctx context.Context) error {
+			started.Done()
+			<-ctx.Done()
+			barrier.Done()
+			barrier.Wait()
+
+			return nil
+		}
+
+		group := task.NewGroup()
+		group.GoWithShutdownOrder(1, awaitSibling, awaitSibling)
+
+		started.Wait()
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it stops the tiers when a task fails", func(t *testing.T) {
+		t.Parallel()
+
+		foo := NewTestTask(nil)
+
+		group := task.NewGroup()
+		group.GoWithShutdownOrder(1, foo.Run)
+		<-foo.RunReady
+
+		group.Go(func(ctx context.Context) error {
+			return assert.AnError
+		})
+
+		assert.Equal(t, assert.AnError, group.Wait(context.Background()))
+		assert.Equal(t, 1, foo.StopCount)
+	})
+
+	t.Run("it is described one tier per step", func(t *testing.T) {
+		t.Parallel()
+
+		wait := func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return nil
+		}
+
+		group := task.NewGroup()
+		group.GoNamed("http-server", wait)
+		group.GoWithShutdownOrder(2, wait)
+		group.GoWithShutdownOrder(1, wait, wait)
+		group.GoWithShutdownOrder(0, wait)
+		group.OnShutdown(func(ctx context.Context) error { return nil })
+
+		expected := "1. cancel tasks: http-server, +1 unnamed\n" +
+			"2. cancel tasks with shutdown order 1: +2 unnamed\n" +
+			"3. cancel tasks with shutdown order 2: +1 unnamed\n" +
+			"4. run shutdown hook 0\n"
+		assert.Equal(t, expected, group.DescribeShutdownOrder())
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of a task which panicked.
+//
+// The group recovers the panics of the tasks and handles them as the errors returned by the tasks,
+// so the other tasks are canceled and Group.Wait() returns the *PanicError.
+type PanicError struct {
+	// Task is the name of the task, or the auto-generated one for the unnamed tasks, e.g. "task-0".
+	Task string
+	// Value is the value the task panicked with.
+	Value interface{}
+	// Stack is the stack trace of the goroutine of the task when it panicked.
+	Stack []byte
+}
+
+// Error returns the name of the task and the value it panicked with, e.g. `task "consumer" panicked: boom`.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task %q panicked: %v", e.Task, e.Value)
+}
+
+// Unwrap returns the value the task panicked with, if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+
+	return err
+}
+
+// call invokes fn of the task with this name, returning a *PanicError if it panics.
+func call(ctx context.Context, name string, fn TaskFunc) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			err = &PanicError{Task: name, Value: value, Stack: debug.Stack()}
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// Panics returns the panics of the tasks found in err, e.g. in the *MultiError returned by Group.Wait()
+// when the group collects the errors, so they can be reported apart from the ordinary task errors.
+//
+// It walks the errors wrapped by err, but not the errors the tasks panicked with.
+// Returns nil if none of the tasks panicked.
+func Panics(err error) []*PanicError {
+	if err == nil {
+		return nil
+	}
+
+	if panicErr, ok := err.(*PanicError); ok {
+		return []*PanicError{panicErr}
+	}
+
+	switch wrapped := err.(type) {
+	case interface{ Unwrap() []error }:
+		var panics []*PanicError
+		for _, err := range wrapped.Unwrap() {
+			panics = append(panics, Panics(err)...)
+		}
+
+		return panics
+	case interface{ Unwrap() error }:
+		return Panics(wrapped.Unwrap())
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestPanicError(t *testing.T) {
+	t.Run("it recovers the panic of a task and cancels the other tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		sibling := NewTestTask(nil)
+
+		group.Go(sibling.Run)
+		<-sibling.RunReady
+
+		group.Go(func(ctx context.Context) error {
+			panic("boom")
+		})
+
+		err := group.Wait(context.Background())
+
+		var panicErr *task.PanicError
+		require.True(t, errors.As(err, &panicErr))
+		assert.Equal(t, "boom", panicErr.Value)
+		assert.Contains(t, string(panicErr.Stack), "panic_test.go")
+		assert.Equal(t, "task-1", panicErr.Task)
+		assert.EqualError(t, err, `task "task-1" panicked: boom`)
+		assert.Equal(t, 1, sibling.StopCount)
+	})
+
+	t.Run("it unwraps the error the task panicked with", func(t *testing.T) {
+		t.Parallel()
+
+		errBoom := errors.New("boom")
+		group := task.NewGroup(task.WithObserver(&recordingObserver{}))
+
+		group.Go(func(ctx context.Context) error {
+			panic(errBoom)
+		})
+
+		err := group.Wait(context.Background())
+		assert.True(t, errors.Is(err, errBoom))
+	})
+
+	t.Run("it separates the panics from the errors of the tasks", func(t *testing.T) {
+		t.Parallel()
+
+		errFailed := errors.New("failed")
+		group := task.NewGroup(task.WithCollectErrors())
+
+		group.GoNamed("panicking", func(ctx context.Context) error {
+			panic("boom")
+		})
+		group.GoNamed("failing", func(ctx context.Context) error {
+			return errFailed
+		})
+
+		err := group.Wait(context.Background())
+
+		panics := task.Panics(err)
+		require.Len(t, panics, 1)
+		assert.Equal(t, "panicking", panics[0].Task)
+		assert.Equal(t, "boom", panics[0].Value)
+		assert.True(t, errors.Is(err, errFailed))
+	})
+
+	t.Run("it returns no panics when no task panicked", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Nil(t, task.Panics(nil))
+		assert.Nil(t, task.Panics(errors.New("failed")))
+	})
+
+	t.Run("it finds the wrapped panics", func(t *testing.T) {
+		t.Parallel()
+
+		panicErr := &task.PanicError{Task: "task-0", Value: "boom"}
+
+		assert.Equal(t, []*task.PanicError{panicErr}, task.Panics(fmt.Errorf("shutdown failed: %w", panicErr)))
+	})
+}
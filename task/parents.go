@@ -0,0 +1,65 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import "context"
+
+// NewGroupWithContexts creates new task group instance canceled once the first of the contexts is done,
+// e.g. a request context and a global shutdown context, see WithParentContexts().
+func NewGroupWithContexts(ctxs ...context.Context) *Group {
+	return NewGroup(WithParentContexts(ctxs...))
+}
+
+// WithParentContexts cancels the group once the first of the contexts is done.
+//
+// The group is canceled the same way as on a task failure with the error of the context, so Group.Wait()
+// returns it, unless the group already failed or was canceled before.
+// The option can be used multiple times, the group is canceled by the first of all the contexts.
+func WithParentContexts(ctxs ...context.Context) GroupOption {
+	return func(g *Group) {
+		g.parents = append(g.parents, ctxs...)
+	}
+}
+
+// watchParents starts waiting for the parent contexts in the background, if configured.
+func (g *Group) watchParents() {
+	if len(g.parents) == 0 {
+		return
+	}
+
+	g.parentsDone = make(chan struct{})
+
+	for _, parent := range g.parents {
+		go func(parent context.Context) {
+			select {
+			case <-g.ctx.Done():
+			case <-g.parentsDone:
+			case <-parent.Done():
+				g.cancelWithError(parent, parent.Err())
+			}
+		}(parent)
+	}
+}
+
+// stopWatchingParents stops waiting for the parent contexts once all the tasks are stopped.
+func (g *Group) stopWatchingParents() {
+	if g.parentsDone == nil {
+		return
+	}
+
+	g.parentsOnce.Do(func() {
+		close(g.parentsDone)
+	})
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestNewGroupWithContexts(t *testing.T) {
+	tests := []struct {
+		name     string
+		canceled int
+	}{
+		{name: "it cancels the group once the request context is done", canceled: 0},
+		{name: "it cancels the group once the shutdown context is done", canceled: 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			requestCtx, cancelRequest := context.WithCancel(context.Background())
+			defer cancelRequest()
+			shutdownCtx, cancelShutdown := context.WithCancel(context.Background())
+			defer cancelShutdown()
+
+			group := task.NewGroupWithContexts(requestCtx, shutdownCtx)
+
+			tt := NewTestTask(nil)
+			group.Go(tt.Run)
+			<-tt.RunReady
+
+			[]context.CancelFunc{cancelRequest, cancelShutdown}[test.canceled]()
+
+			err := group.Wait(context.Background())
+			assert.Equal(t, context.Canceled, err)
+			assert.Equal(t, 1, tt.StopCount)
+		})
+	}
+
+	t.Run("it returns the error of the context which is done first", func(t *testing.T) {
+		t.Parallel()
+
+		requestCtx, cancelRequest := context.WithTimeout(context.Background(), 0)
+		defer cancelRequest()
+
+		group := task.NewGroupWithContexts(requestCtx, context.Background())
+
+		tt := NewTestTask(nil)
+		group.Go(tt.Run)
+
+		assert.Equal(t, context.DeadlineExceeded, group.Wait(context.Background()))
+	})
+
+	t.Run("it returns nil when the tasks finish before any context is done", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroupWithContexts(context.Background(), context.Background())
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+// PauseScheduling stops starting new tasks, e.g. to shed load, until ResumeScheduling() is called.
+//
+// The tasks scheduled while the scheduling is paused are queued, as well as the tasks which are
+// already queued because of the concurrency limit. The running tasks are not affected.
+// Canceling the group discards the queued tasks as usual.
+func (g *Group) PauseScheduling() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.paused = true
+}
+
+// ResumeScheduling starts the tasks queued while the scheduling was paused,
+// as many as the concurrency limit allows.
+func (g *Group) ResumeScheduling() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.paused = false
+
+	if g.ctx.Err() != nil {
+		return
+	}
+
+	g.startQueuedLocked()
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_PauseScheduling(t *testing.T) {
+	t.Run("it starts no task while paused and starts them once resumed", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithAutoConcurrency(1))
+
+		running := make(chan struct{})
+		release := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(running)
+			<-release
+
+			return nil
+		})
+		<-running
+
+		group.PauseScheduling()
+
+		started := make(chan int, 3)
+		for i := 0; i < 3; i++ {
+			i := i
+			group.Go(func(ctx context.Context) error {
+				started <- i
+
+				return nil
+			})
+		}
+
+		close(release)
+
+		select {
+		case i := <-started:
+			t.Fatalf("task %d was started while the scheduling is paused", i)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		group.ResumeScheduling()
+
+		assert.NoError(t, group.Wait(context.Background()))
+		close(started)
+
+		var startedTasks []int
+		for i := range started {
+			startedTasks = append(startedTasks, i)
+		}
+		assert.ElementsMatch(t, []int{0, 1, 2}, startedTasks)
+	})
+
+	t.Run("it does not affect the running tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		running := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(running)
+			<-ctx.Done()
+
+			return nil
+		})
+		<-running
+
+		group.PauseScheduling()
+
+		snapshot := group.Snapshot()
+		assert.Len(t, snapshot.Tasks, 1)
+		assert.Equal(t, task.TaskRunning, snapshot.Tasks[0].Status)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("when the group is canceled while paused, it discards the queued tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		group.PauseScheduling()
+
+		group.Go(func(ctx context.Context) error {
+			t.Error("the queued task must not be started")
+
+			return nil
+		})
+
+		group.Cancel()
+		group.ResumeScheduling()
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
@@ -0,0 +1,186 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNotReady is matched by the errors of Group.WaitReady() and Group.WaitReadyTimeout().
+var ErrNotReady = errors.New("not ready")
+
+// NotReadyError lists the tasks which did not signal they are ready.
+type NotReadyError struct {
+	// Tasks are the names of the tasks which are not ready, in the order they were started.
+	Tasks []string
+}
+
+// Error lists the tasks which are not ready, e.g. "consumer not ready, db-pool not ready".
+func (e *NotReadyError) Error() string {
+	messages := make([]string, len(e.Tasks))
+	for i, name := range e.Tasks {
+		messages[i] = name + " not ready"
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+// Is reports whether target is ErrNotReady.
+func (e *NotReadyError) Is(target error) bool {
+	return target == ErrNotReady
+}
+
+// GoNamedReady runs a named task in the group, the same way Group.GoNamed() does,
+// and makes Group.WaitReady() wait until the task signals it is ready by calling Ready().
+func (g *Group) GoNamedReady(name string, fn TaskFunc) {
+	if g.ctx.Err() != nil {
+		return
+	}
+
+	t := g.newTaskEntry(name, g.checkpointed(name, fn))
+	t.ready = make(chan struct{})
+
+	g.mu.Lock()
+	g.pruneReadyLocked()
+	g.exitedNotReady = removeName(g.exitedNotReady, name)
+	g.awaitingReady = append(g.awaitingReady, t)
+	g.mu.Unlock()
+
+	g.schedule([]*taskEntry{t})
+}
+
+// Ready signals that the task run with ctx, or a context derived from it, is ready.
+//
+// It does nothing for the tasks which were not started with Group.GoNamedReady().
+func Ready(ctx context.Context) {
+	t := taskFromContext(ctx)
+	if t == nil || t.ready == nil {
+		return
+	}
+
+	t.readyOnce.Do(func() {
+		close(t.ready)
+	})
+}
+
+// WaitReady waits until all tasks started with Group.GoNamedReady() signal they are ready.
+//
+// Returns a *NotReadyError listing the tasks which are not ready if the context is done,
+// the group is canceled or any of the tasks returns without signaling it is ready.
+// Tasks started after WaitReady() is called are not awaited.
+func (g *Group) WaitReady(ctx context.Context) error {
+	g.mu.Lock()
+	g.pruneReadyLocked()
+	tasks := append([]*taskEntry(nil), g.awaitingReady...)
+	notReady := append([]string(nil), g.exitedNotReady...)
+	g.mu.Unlock()
+
+wait:
+	for _, t := range tasks {
+		select {
+		case <-t.ready:
+		case <-t.done:
+		case <-ctx.Done():
+			break wait
+		case <-g.ctx.Done():
+			break wait
+		}
+	}
+
+	for _, t := range tasks {
+		if !t.isReady() {
+			notReady = append(notReady, t.name)
+		}
+	}
+
+	if len(notReady) > 0 {
+		return &NotReadyError{Tasks: notReady}
+	}
+
+	return nil
+}
+
+// WaitReadyTimeout waits until all tasks started with Group.GoNamedReady() signal they are ready,
+// the same way Group.WaitReady() does, for up to timeout.
+//
+// Returns a *NotReadyError listing the tasks which are not ready within the timeout.
+func (g *Group) WaitReadyTimeout(timeout time.Duration) error {
+	ctx, cancel := withClockTimeout(context.Background(), g.clock, timeout)
+	defer cancel()
+
+	return g.WaitReady(ctx)
+}
+
+// pruneReadyLocked forgets the tasks which are already ready.
+// pruneReadyLocked forgets the awaited tasks which are ready or have returned.
+// The names of the tasks which returned without being ready are kept, so Group.WaitReady() still reports them.
+func (g *Group) pruneReadyLocked() {
+	awaiting := g.awaitingReady[:0]
+	for _, t := range g.awaitingReady {
+		switch {
+		case t.isReady():
+		case t.isDone():
+			g.exitedNotReady = addName(g.exitedNotReady, t.name)
+		default:
+			awaiting = append(awaiting, t)
+		}
+	}
+	for i := len(awaiting); i < len(g.awaitingReady); i++ {
+		g.awaitingReady[i] = nil
+	}
+	g.awaitingReady = awaiting
+}
+
+func (t *taskEntry) isReady() bool {
+	select {
+	case <-t.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *taskEntry) isDone() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// addName appends the name to the names, unless it is there already.
+func addName(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+
+	return append(names, name)
+}
+
+func removeName(names []string, name string) []string {
+	for i, n := range names {
+		if n == name {
+			return append(names[:i], names[i+1:]...)
+		}
+	}
+
+	return names
+}
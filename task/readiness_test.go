@@ -0,0 +1,160 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_WaitReady(t *testing.T) {
+	t.Run("it waits until all tasks are ready", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		release := make(chan struct{})
+		for _, name := range []string{"consumer", "db-pool"} {
+			group.GoNamedReady(name, func(ctx context.Context) error {
+				<-release
+				task.Ready(ctx)
+				<-ctx.Done()
+
+				return nil
+			})
+		}
+
+		waited := make(chan error)
+		go func() {
+			waited <- group.WaitReady(context.Background())
+		}()
+
+		select {
+		case <-waited:
+			t.Fatal("WaitReady returned before the tasks are ready")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(release)
+		assert.NoError(t, <-waited)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it reports the tasks returning without being ready", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.GoNamedReady("migrations", func(ctx context.Context) error {
+			return nil
+		})
+
+		err := group.WaitReady(context.Background())
+
+		var notReadyErr *task.NotReadyError
+		require.True(t, errors.As(err, &notReadyErr))
+		assert.Equal(t, []string{"migrations"}, notReadyErr.Tasks)
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it reports the tasks which returned without being ready before other tasks are started", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.GoNamedReady("migrations", func(ctx context.Context) error {
+			return nil
+		})
+		group.GoNamedReady("warmup", func(ctx context.Context) error {
+			task.Ready(ctx)
+
+			return nil
+		})
+		assert.Error(t, group.WaitReady(context.Background()))
+
+		// NOTE: Starting another task forgets the awaited tasks which returned, but not their names.
+		group.GoNamedReady("consumer", func(ctx context.Context) error {
+			task.Ready(ctx)
+			<-ctx.Done()
+
+			return nil
+		})
+
+		err := group.WaitReady(context.Background())
+
+		var notReadyErr *task.NotReadyError
+		require.True(t, errors.As(err, &notReadyErr))
+		assert.Equal(t, []string{"migrations"}, notReadyErr.Tasks)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it ignores Ready of the tasks not awaited", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.Go(func(ctx context.Context) error {
+			task.Ready(ctx)
+
+			return nil
+		})
+
+		assert.NoError(t, group.WaitReady(context.Background()))
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
+
+func TestGroup_WaitReadyTimeout(t *testing.T) {
+	t.Run("it names the tasks not ready within the timeout", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.GoNamedReady("consumer", func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return nil
+		})
+
+		ready := make(chan struct{})
+		group.GoNamedReady("db-pool", func(ctx context.Context) error {
+			task.Ready(ctx)
+			close(ready)
+			<-ctx.Done()
+
+			return nil
+		})
+
+		<-ready
+
+		err := group.WaitReadyTimeout(20 * time.Millisecond)
+		assert.True(t, errors.Is(err, task.ErrNotReady))
+		assert.EqualError(t, err, "consumer not ready")
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
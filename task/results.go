@@ -0,0 +1,64 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import "context"
+
+// WithResults makes the group keep the error returned by every task it runs, see Group.Results().
+//
+// NOTE: The results grow for as long as the group lives, so avoid it for the long-living groups
+// running many short tasks.
+func WithResults() GroupOption {
+	return func(g *Group) {
+		g.keepResults = true
+	}
+}
+
+// WaitAll waits until all tasks are stopped, the same way Group.Wait() does,
+// and returns the errors returned by all the tasks which ran, in the order they completed,
+// with nil for the tasks which returned no error, e.g. for the shutdown diagnostics.
+//
+// The tasks which were not started, e.g. because the group was canceled while they were queued,
+// are not included. The errors of the shutdown hooks are not included either, see Group.ShutdownErrors().
+//
+// The results are kept only by the groups created WithResults(), otherwise WaitAll() returns nil.
+func (g *Group) WaitAll(ctx context.Context) []error {
+	_ = g.Wait(ctx)
+
+	return g.Results()
+}
+
+// Results returns the errors returned by the tasks which completed so far, in the order they completed,
+// with nil for the tasks which returned no error. See Group.WaitAll().
+//
+// The results are kept only by the groups created WithResults(), otherwise Results() returns nil.
+func (g *Group) Results() []error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]error(nil), g.results...)
+}
+
+// recordResult records the error returned by the task, see Group.Results() and Group.Summary().
+// The failed tells whether the error failed the group, or the task was stopped cleanly.
+func (g *Group) recordResult(t *taskEntry, err error, failed bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.keepResults {
+		g.results = append(g.results, err)
+	}
+	g.recordSummaryLocked(t, err, failed)
+}
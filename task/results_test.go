@@ -0,0 +1,118 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_WaitAll(t *testing.T) {
+	t.Run("it returns the errors of all tasks in the completion order", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithResults())
+		errDB := errors.New("db connection lost")
+		errConsumer := errors.New("consumer failed to ack")
+
+		waitResults := func(n int) {
+			for len(group.Results()) < n {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		started := make(chan struct{}, 2)
+		group.Go(
+			func(ctx context.Context) error {
+				<-started
+				<-started
+
+				return errDB
+			},
+			func(ctx context.Context) error {
+				started <- struct{}{}
+				<-ctx.Done()
+				waitResults(1)
+
+				return errConsumer
+			},
+			func(ctx context.Context) error {
+				started <- struct{}{}
+				<-ctx.Done()
+				waitResults(2)
+
+				return nil
+			},
+		)
+
+		assert.Equal(t, []error{errDB, errConsumer, nil}, group.WaitAll(context.Background()))
+	})
+
+	t.Run("it keeps the semantics of Wait", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithResults())
+		errFailed := errors.New("failed")
+		sibling := NewTestTask(nil)
+
+		group.Go(sibling.Run)
+		<-sibling.RunReady
+		group.Go(func(ctx context.Context) error {
+			return errFailed
+		})
+
+		assert.Equal(t, errFailed, group.Wait(context.Background()))
+		assert.Equal(t, 1, sibling.StopCount)
+		assert.ElementsMatch(t, []error{errFailed, nil}, group.Results())
+	})
+
+	t.Run("it does not include the tasks which were not started", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroupWithLimit(1, task.WithResults())
+
+		running := NewTestTask(nil)
+		queued := NewTestTask(nil)
+		group.Go(func(ctx context.Context) error {
+			_ = running.Run(ctx)
+
+			return ctx.Err()
+		}, queued.Run)
+
+		<-running.RunReady
+		group.Cancel()
+
+		assert.Equal(t, []error{context.Canceled}, group.WaitAll(context.Background()))
+		assert.Equal(t, 0, queued.RunCount)
+	})
+
+	t.Run("it does not keep the results without WithResults", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+
+		assert.Nil(t, group.WaitAll(context.Background()))
+		assert.Equal(t, 1, group.Summary().Total)
+	})
+}
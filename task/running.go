@@ -0,0 +1,55 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sync/atomic"
+)
+
+// Running returns the number of the tasks currently executing their function, e.g. for a health endpoint.
+//
+// The tasks queued by the concurrency limit, or waiting for their launch, are not counted.
+func (g *Group) Running() int {
+	return int(atomic.LoadInt32(&g.running))
+}
+
+// Done returns a channel closed once the group has finished, i.e. once it is canceled and the last of its tasks
+// has exited, whether Group.Wait() is called or not. Otherwise it is closed once Group.Wait() returns,
+// since the tasks may still be started until then.
+//
+// NOTE: The shutdown hooks are run by Group.Wait(), so they may still be running when the channel is closed.
+func (g *Group) Done() <-chan struct{} {
+	return g.doneCh
+}
+
+// closeDoneIfIdle closes the channel of Group.Done() if the group is canceled and none of its tasks is pending.
+func (g *Group) closeDoneIfIdle() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closeDoneIfIdleLocked()
+}
+
+func (g *Group) closeDoneIfIdleLocked() {
+	if g.pending == 0 && g.ctx.Err() != nil {
+		g.closeDone()
+	}
+}
+
+func (g *Group) closeDone() {
+	g.doneOnce.Do(func() {
+		close(g.doneCh)
+	})
+}
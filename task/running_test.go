@@ -0,0 +1,203 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_Running(t *testing.T) {
+	t.Run("it counts the tasks executing their function", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroupWithLimit(2)
+		foo := NewTestTask(nil)
+		bar := NewTestTask(nil)
+		baz := NewTestTask(nil)
+
+		assert.Equal(t, 0, group.Running())
+
+		group.Go(foo.Run, bar.Run, baz.Run)
+		<-foo.RunReady
+		<-bar.RunReady
+
+		// NOTE: baz is queued until a slot is free, so it is not running yet.
+		assert.Equal(t, 2, group.Running())
+
+		foo.RunUntil <- nil
+		<-baz.RunReady
+		bar.RunUntil <- nil
+
+		assert.Eventually(t, func() bool {
+			return group.Running() == 1
+		}, time.Second, time.Millisecond)
+
+		baz.RunUntil <- nil
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, 0, group.Running())
+	})
+
+	t.Run("it is safe to call concurrently with Go and Cancel", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+
+			go func() {
+				defer wg.Done()
+
+				group.Go(func(ctx context.Context) error {
+					<-ctx.Done()
+
+					return nil
+				})
+			}()
+
+			go func() {
+				defer wg.Done()
+
+				assert.True(t, group.Running() >= 0)
+			}()
+		}
+
+		group.Cancel()
+		wg.Wait()
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, 0, group.Running())
+	})
+}
+
+func TestGroup_Done(t *testing.T) {
+	t.Run("it is closed once Wait returns", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		foo := NewTestTask(nil)
+
+		group.Go(foo.Run)
+		<-foo.RunReady
+
+		select {
+		case <-group.Done():
+			t.Fatal("the group is done before its tasks exit")
+		default:
+		}
+
+		waitErr := make(chan error)
+		go func() {
+			waitErr <- group.Wait(context.Background())
+		}()
+
+		foo.RunUntil <- nil
+
+		<-group.Done()
+		assert.NoError(t, <-waitErr)
+		assert.Equal(t, 1, foo.RunCount)
+
+		// NOTE: Waiting again leaves the channel closed.
+		assert.NoError(t, group.Wait(context.Background()))
+		<-group.Done()
+	})
+
+	t.Run("it is closed once the last task of the canceled group exits without calling Wait", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-release
+
+			return nil
+		})
+		<-started
+
+		group.Cancel()
+
+		select {
+		case <-group.Done():
+			t.Fatal("the group is done before its last task exits")
+		default:
+		}
+
+		close(release)
+
+		select {
+		case <-group.Done():
+		case <-time.After(time.Second):
+			t.Fatal("the group is not done once its tasks exit")
+		}
+		assert.Equal(t, 0, group.Running())
+	})
+
+	t.Run("it is not closed once the last task exits while the group is running", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		foo := NewTestTask(nil)
+		bar := NewTestTask(nil)
+
+		group.Go(foo.Run)
+		<-foo.RunReady
+		foo.RunUntil <- nil
+
+		assert.Eventually(t, func() bool {
+			return group.Running() == 0
+		}, time.Second, time.Millisecond)
+
+		select {
+		case <-group.Done():
+			t.Fatal("the group is done before it is canceled or waited for")
+		default:
+		}
+
+		// NOTE: The group accepts the tasks scheduled after the first ones exit.
+		group.Go(bar.Run)
+		<-bar.RunReady
+		bar.RunUntil <- nil
+
+		assert.NoError(t, group.Wait(context.Background()))
+		<-group.Done()
+		assert.Equal(t, 1, foo.RunCount)
+		assert.Equal(t, 1, bar.RunCount)
+	})
+
+	t.Run("it is closed by Cancel once the tasks have exited", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		group.Cancel()
+
+		select {
+		case <-group.Done():
+		case <-time.After(time.Second):
+			t.Fatal("the canceled group without tasks is not done")
+		}
+	})
+}
@@ -0,0 +1,319 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// taskEntry is a task scheduled by the group.
+type taskEntry struct {
+	// canceledAt is the unix time in nanoseconds when the task was stopped on its own,
+	// 0 if it was not stopped.
+	// NOTE: Keep it first in the struct, so it is 64-bit aligned for the atomic operations.
+	canceledAt int64
+	// startedAt is the unix time in nanoseconds when the task function was invoked, 0 if it is not started.
+	startedAt int64
+	// stuck tells whether the watchdog flagged the task, see WithWatchdog().
+	stuck int32
+
+	seq      uint64
+	location string
+	name     string
+	// label identifies the task in its errors, it is the name or an auto-generated one, e.g. "task-0"
+	label string
+	tags  []string
+	// shutdownOrder is the tier the task is stopped in when the group is canceled, see Group.GoWithShutdownOrder()
+	shutdownOrder int
+	fn            TaskFunc
+	ctx           context.Context
+	cancel        context.CancelFunc
+	// done is closed once the task returns or is discarded before it is started
+	done chan struct{}
+	// restartMu protects the restart properties, see Group.GoUntilSuccess()
+	restartMu   sync.Mutex
+	restarts    int
+	lastRestart RestartInfo
+	// ready is closed once the task signals it is ready, nil if the task is not awaited for readiness
+	ready     chan struct{}
+	readyOnce sync.Once
+	// staggerPrev is closed once the previous task is done with its turn to start, see WithStartStagger(),
+	// and the task closes staggerNext once it is done with its own turn
+	staggerPrev chan struct{}
+	staggerNext chan struct{}
+}
+
+func (g *Group) newTaskEntry(name string, fn TaskFunc) *taskEntry {
+	return g.newTaskEntryWithParent(g.ctx, name, fn)
+}
+
+// newTaskEntryWithParent creates a task whose context is derived from parent instead of the group context.
+func (g *Group) newTaskEntryWithParent(parent context.Context, name string, fn TaskFunc) *taskEntry {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if g.taskDeadline.IsZero() {
+		ctx, cancel = context.WithCancel(parent)
+	} else {
+		ctx, cancel = context.WithDeadline(parent, g.taskDeadline)
+	}
+
+	label := name
+	if label == "" {
+		label = fmt.Sprintf("task-%d", atomic.AddUint64(&g.unnamedSeq, 1)-1)
+	}
+
+	t := &taskEntry{
+		name:   name,
+		label:  label,
+		fn:     fn,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	if g.taskLocations {
+		t.location = callerLocation()
+	}
+	t.ctx = context.WithValue(ctx, taskKey{}, t)
+
+	return t
+}
+
+// taskKey is the context key of the task run with the context, see taskFromContext().
+type taskKey struct{}
+
+// taskFromContext returns the task run with ctx, or a context derived from it, nil if there is none.
+func taskFromContext(ctx context.Context) *taskEntry {
+	t, _ := ctx.Value(taskKey{}).(*taskEntry)
+
+	return t
+}
+
+// stop cancels the context of the task only.
+func (t *taskEntry) stop(now time.Time) {
+	atomic.CompareAndSwapInt64(&t.canceledAt, 0, now.UnixNano())
+	t.cancel()
+}
+
+func (t *taskEntry) isStopped() bool {
+	return atomic.LoadInt64(&t.canceledAt) != 0
+}
+
+// wrapError attaches the name of the task to the error it failed with, unless the task is unnamed.
+// A *PanicError already carries the name.
+func (t *taskEntry) wrapError(err error) error {
+	if t.name == "" {
+		return err
+	}
+
+	if _, ok := err.(*PanicError); ok {
+		return err
+	}
+
+	return fmt.Errorf("task %q failed: %w", t.name, err)
+}
+
+// schedule starts the tasks if the concurrency limit allows it, otherwise queues them.
+func (g *Group) schedule(tasks []*taskEntry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, t := range tasks {
+		g.wg.Add(1)
+		g.pending++
+		g.registerLocked(t)
+
+		if t.shutdownOrder > 0 && g.ctx.Err() != nil {
+			// NOTE: The group was canceled while the task was being scheduled, after its tier was stopped.
+			t.cancel()
+		}
+
+		if len(g.queue) == 0 && !g.paused && g.hasFreeSlotLocked() {
+			g.startLocked(t)
+
+			continue
+		}
+
+		g.queue = append(g.queue, t)
+	}
+}
+
+// release frees the slot of a completed task and starts the next queued tasks.
+func (g *Group) release(t *taskEntry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.active--
+	g.trackTagReleasedLocked(t)
+	g.unregisterLocked(t)
+
+	if g.ctx.Err() != nil {
+		g.dropQueuedLocked()
+
+		return
+	}
+
+	g.startQueuedLocked()
+}
+
+// startQueuedLocked starts the queued tasks while there are free slots, unless the scheduling is paused.
+func (g *Group) startQueuedLocked() {
+	for len(g.queue) > 0 && !g.paused && g.hasFreeSlotLocked() {
+		g.startLocked(g.popQueuedLocked())
+	}
+}
+
+func (g *Group) hasFreeSlotLocked() bool {
+	return g.limit <= 0 || g.active < g.limit
+}
+
+func (g *Group) startLocked(t *taskEntry) {
+	g.active++
+	g.trackTagStartedLocked(t)
+	g.reserveStaggerLocked(t)
+	go g.start(t, g.reserveLaunchLocked())
+}
+
+// start runs a task and frees its slot once it completes.
+func (g *Group) start(t *taskEntry, startAt time.Time) {
+	defer g.wg.Done()
+	defer g.exit()
+	defer close(t.done)
+	defer t.cancel()
+	defer g.release(t)
+
+	if !g.awaitLaunch(t, startAt) {
+		g.skipStagger(t)
+
+		return
+	}
+
+	if !g.awaitStagger(t) {
+		return
+	}
+
+	admitted, err := g.awaitAdmission(t)
+	if !admitted {
+		if err != nil {
+			g.fail(t.ctx, err)
+		}
+
+		return
+	}
+
+	atomic.StoreInt64(&t.startedAt, g.clock.Now().UnixNano())
+
+	atomic.AddInt32(&g.running, 1)
+	err = g.run(t)
+	atomic.AddInt32(&g.running, -1)
+	if err == nil {
+		g.recordResult(t, nil, false)
+
+		return
+	}
+
+	if g.isCleanStop(t, err) {
+		g.recordResult(t, err, false)
+
+		return
+	}
+
+	err = t.wrapError(err)
+	g.recordResult(t, err, true)
+	g.fail(t.ctx, err)
+}
+
+// isCleanStop tells whether the error returned by the task means it stopped cleanly instead of failing.
+func (g *Group) isCleanStop(t *taskEntry, err error) bool {
+	if t.isStopped() && errors.Is(err, context.Canceled) {
+		// NOTE: The task was stopped on its own with Group.StopTask() and returned
+		// the error of its canceled context. This is a clean stop, not a failure.
+		return true
+	}
+
+	// NOTE: The task returned the error of its context canceled by the group, e.g. by Group.Cancel().
+	// This is a clean stop as well, not the failure of the group.
+	return g.ctx.Err() != nil && t.ctx.Err() != nil && errors.Is(err, t.ctx.Err())
+}
+
+// dropQueued discards the queued tasks which are not started yet.
+func (g *Group) dropQueued() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.dropQueuedLocked()
+}
+
+func (g *Group) dropQueuedLocked() {
+	for _, t := range g.queue {
+		g.discardLocked(t)
+	}
+
+	g.queue = nil
+}
+
+// discardLocked releases a task which is removed from the queue before it is started.
+func (g *Group) discardLocked(t *taskEntry) {
+	g.unregisterLocked(t)
+	t.cancel()
+	close(t.done)
+	g.exitLocked()
+	g.wg.Done()
+}
+
+// exit accounts for a task which has exited, and closes the channel of Group.Done() once it is the last one
+// of a canceled group.
+func (g *Group) exit() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.exitLocked()
+}
+
+func (g *Group) exitLocked() {
+	g.pending--
+	g.closeDoneIfIdleLocked()
+}
+
+// reserveLaunchLocked returns the earliest time at which the next task is allowed to start.
+func (g *Group) reserveLaunchLocked() time.Time {
+	if g.launchLimiter == nil {
+		return time.Time{}
+	}
+
+	return g.launchLimiter.reserve(g.clock.Now())
+}
+
+// awaitLaunch blocks until startAt is reached.
+// Returns false if the task is canceled in the meantime and must not be started.
+func (g *Group) awaitLaunch(t *taskEntry, startAt time.Time) bool {
+	delay := startAt.Sub(g.clock.Now())
+	if delay <= 0 {
+		return t.ctx.Err() == nil
+	}
+
+	timer := g.clock.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-t.ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrShutdownHookTimeout is recorded for a shutdown hook which did not return within the shutdown hook timeout.
+var ErrShutdownHookTimeout = errors.New("shutdown hook timed out")
+
+// ShutdownHook is a cleanup function run once all the tasks of the group are stopped.
+type ShutdownHook func(ctx context.Context) error
+
+// WithShutdownHookTimeout bounds how long every shutdown hook is allowed to run.
+//
+// Each hook gets a context canceled after the timeout. A hook which does not return by then is abandoned
+// and ErrShutdownHookTimeout is recorded for it, so the subsequent hooks still run.
+// A timeout <= 0 means the hooks are not bounded, which is the default.
+func WithShutdownHookTimeout(timeout time.Duration) GroupOption {
+	return func(g *Group) {
+		g.shutdownHookTimeout = timeout
+	}
+}
+
+// OnShutdown registers a hook run by Group.Wait() once all the tasks of the group are stopped.
+//
+// The hooks are run one after another, in the order they are registered, and only once
+// even if Group.Wait() is called multiple times.
+func (g *Group) OnShutdown(hook ShutdownHook) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.shutdownHooks = append(g.shutdownHooks, hook)
+}
+
+// ShutdownErrors returns the errors recorded for the failed shutdown hooks, in the order the hooks were run.
+// It is empty until the hooks are run by Group.Wait().
+func (g *Group) ShutdownErrors() []error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]error(nil), g.shutdownErrs...)
+}
+
+func (g *Group) runShutdownHooks() {
+	g.mu.Lock()
+	hooks := g.shutdownHooks
+	g.mu.Unlock()
+
+	var errs []error
+	for i, hook := range hooks {
+		err := g.runShutdownHook(hook)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook %d: %w", i, err))
+		}
+	}
+
+	g.mu.Lock()
+	g.shutdownErrs = errs
+	g.mu.Unlock()
+}
+
+func (g *Group) runShutdownHook(hook ShutdownHook) error {
+	if g.shutdownHookTimeout <= 0 {
+		return hook(context.Background())
+	}
+
+	ctx, cancel := withClockTimeout(context.Background(), g.clock, g.shutdownHookTimeout)
+	defer cancel()
+
+	// NOTE: Buffered, so the goroutine of an abandoned hook does not leak once the hook returns.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- hook(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ErrShutdownHookTimeout
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DescribeShutdownOrder returns a textual description of the order the group stops in,
+// e.g. to verify the shutdown of a complex setup in tests or to log it.
+//
+// All the running and queued tasks are canceled together in the first step, except the ones run with
+// Group.GoWithShutdownOrder(), which are canceled one tier per step. Then the shutdown hooks registered
+// with Group.OnShutdown() run one per step, in the order they are registered.
+// When some of the observers implement the Flusher interface, they are flushed before the hooks run:
+//
+//  1. cancel tasks: consumer, db-pool, +2 unnamed
+//  2. cancel tasks with shutdown order 1: +1 unnamed
+//  3. run shutdown hook 0
+//  4. run shutdown hook 1
+//
+// The named tasks are listed in alphabetical order. The description is a snapshot,
+// it does not include the tasks and hooks added after it is taken.
+func (g *Group) DescribeShutdownOrder() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	tiers := map[int]*shutdownTier{0: {}}
+	for t := range g.tasks {
+		tier := tiers[t.shutdownOrder]
+		if tier == nil {
+			tier = &shutdownTier{}
+			tiers[t.shutdownOrder] = tier
+		}
+
+		tier.add(t)
+	}
+
+	orders := make([]int, 0, len(tiers))
+	for order := range tiers {
+		orders = append(orders, order)
+	}
+	sort.Ints(orders)
+
+	var description strings.Builder
+	step := 1
+	for _, order := range orders {
+		if order == 0 {
+			fmt.Fprintf(&description, "%d. cancel tasks: %s\n", step, tiers[order])
+		} else {
+			fmt.Fprintf(&description, "%d. cancel tasks with shutdown order %d: %s\n", step, order, tiers[order])
+		}
+		step++
+	}
+	if g.flush != nil {
+		fmt.Fprintf(&description, "%d. flush observers\n", step)
+		step++
+	}
+	for i := range g.shutdownHooks {
+		fmt.Fprintf(&description, "%d. run shutdown hook %d\n", step+i, i)
+	}
+
+	return description.String()
+}
+
+// shutdownTier describes the tasks canceled in the same step of the shutdown.
+type shutdownTier struct {
+	names   map[string]struct{}
+	unnamed int
+}
+
+func (s *shutdownTier) add(t *taskEntry) {
+	if t.name == "" {
+		s.unnamed++
+
+		return
+	}
+
+	if s.names == nil {
+		s.names = make(map[string]struct{})
+	}
+	s.names[t.name] = struct{}{}
+}
+
+// String lists the names of the tasks in alphabetical order, followed by the number of the unnamed tasks.
+func (s *shutdownTier) String() string {
+	tasks := make([]string, 0, len(s.names)+1)
+	for name := range s.names {
+		tasks = append(tasks, name)
+	}
+	sort.Strings(tasks)
+
+	if s.unnamed > 0 {
+		tasks = append(tasks, fmt.Sprintf("+%d unnamed", s.unnamed))
+	}
+	if len(tasks) == 0 {
+		tasks = append(tasks, "none")
+	}
+
+	return strings.Join(tasks, ", ")
+}
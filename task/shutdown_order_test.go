@@ -0,0 +1,61 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_DescribeShutdownOrder(t *testing.T) {
+	t.Run("it describes the tasks canceled before the shutdown hooks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		wait := func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return nil
+		}
+		group.GoNamed("db-pool", wait)
+		group.GoNamed("consumer", wait)
+		group.Go(wait, wait)
+		group.GoNamed("consumer", wait)
+
+		group.OnShutdown(func(ctx context.Context) error { return nil })
+		group.OnShutdown(func(ctx context.Context) error { return nil })
+
+		expected := "1. cancel tasks: consumer, db-pool, +2 unnamed\n" +
+			"2. run shutdown hook 0\n" +
+			"3. run shutdown hook 1\n"
+		assert.Equal(t, expected, group.DescribeShutdownOrder())
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it describes an empty group", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		assert.Equal(t, "1. cancel tasks: none\n", group.DescribeShutdownOrder())
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_OnShutdown(t *testing.T) {
+	t.Run("it runs the hooks in order after the tasks are stopped", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		var mu sync.Mutex
+		var calls []string
+		record := func(call string) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			calls = append(calls, call)
+		}
+
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			record("task")
+
+			return nil
+		})
+		group.OnShutdown(func(ctx context.Context) error {
+			record("first hook")
+
+			return nil
+		})
+		group.OnShutdown(func(ctx context.Context) error {
+			record("second hook")
+
+			return nil
+		})
+
+		<-started
+		group.Cancel()
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"task", "first hook", "second hook"}, calls)
+
+		err = group.Wait(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, calls, 3)
+	})
+
+	t.Run("it returns the hook error", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		group.OnShutdown(func(ctx context.Context) error {
+			return errors.New("flush failed")
+		})
+
+		err := group.Wait(context.Background())
+		assert.EqualError(t, err, "shutdown hook 0: flush failed")
+	})
+}
+
+func TestWithShutdownHookTimeout(t *testing.T) {
+	t.Run("it abandons the slow hook and runs the next ones", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithShutdownHookTimeout(20 * time.Millisecond))
+
+		release := make(chan struct{})
+		defer close(release)
+
+		var slowHookCtxErr error
+		slowHookCanceled := make(chan struct{})
+		group.OnShutdown(func(ctx context.Context) error {
+			<-ctx.Done()
+			slowHookCtxErr = ctx.Err()
+			close(slowHookCanceled)
+			<-release
+
+			return nil
+		})
+
+		var laterHookRun bool
+		group.OnShutdown(func(ctx context.Context) error {
+			laterHookRun = true
+
+			return nil
+		})
+
+		err := group.Wait(context.Background())
+		assert.True(t, errors.Is(err, task.ErrShutdownHookTimeout))
+		assert.True(t, laterHookRun)
+
+		shutdownErrs := group.ShutdownErrors()
+		assert.Len(t, shutdownErrs, 1)
+		assert.EqualError(t, shutdownErrs[0], "shutdown hook 0: shutdown hook timed out")
+
+		<-slowHookCanceled
+		assert.Equal(t, context.DeadlineExceeded, slowHookCtxErr)
+	})
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// TaskStatus is the status of a task in a GroupSnapshot.
+type TaskStatus string
+
+const (
+	// TaskQueued is the status of a task waiting to be started.
+	TaskQueued TaskStatus = "queued"
+	// TaskRunning is the status of a task whose function is running.
+	TaskRunning TaskStatus = "running"
+	// TaskStopping is the status of a task whose context is canceled, but which did not return yet.
+	TaskStopping TaskStatus = "stopping"
+)
+
+// TaskSnapshot describes a task of the group at the time of the snapshot.
+type TaskSnapshot struct {
+	Name   string     `json:"name,omitempty"`
+	Tags   []string   `json:"tags,omitempty"`
+	Status TaskStatus `json:"status"`
+	// StartedAt is the time when the task function was invoked, zero if the task is not started yet.
+	StartedAt time.Time `json:"started_at"`
+	// Location is the file:line of the code which scheduled the task, empty unless the group
+	// is created WithTaskLocations().
+	Location string `json:"location"`
+	// Restarts is how many times the task was restarted, see Group.GoUntilSuccess() and Group.Supervise().
+	Restarts int `json:"restarts,omitempty"`
+	// LastError is the message of the error which triggered the last restart.
+	LastError string `json:"last_error,omitempty"`
+	// Backoff is how long the task waited, or is waiting, after the last restart.
+	Backoff time.Duration `json:"backoff,omitempty"`
+}
+
+// GroupSnapshot describes the state of a group at the time of the snapshot.
+type GroupSnapshot struct {
+	Canceled bool `json:"canceled"`
+	// Error is the message of the error which failed the group, empty if it did not fail.
+	Error string `json:"error,omitempty"`
+	// Tasks are the queued and running tasks, in the order they were scheduled.
+	Tasks []TaskSnapshot `json:"tasks"`
+}
+
+// WithTaskLocations makes the group capture the file:line of the code scheduling every task,
+// reported by Group.Snapshot() and Group.DebugHandler().
+//
+// NOTE: Capturing the location walks the stack of the caller, so it makes Group.Go() several times slower.
+func WithTaskLocations() GroupOption {
+	return func(g *Group) {
+		g.taskLocations = true
+	}
+}
+
+// Snapshot returns the current state of the group, e.g. to inspect a running service, see Group.DebugHandler().
+//
+// The tasks which returned are not part of the snapshot.
+func (g *Group) Snapshot() GroupSnapshot {
+	snapshot := GroupSnapshot{
+		Canceled: g.ctx.Err() != nil,
+	}
+
+	if err := (*error)(atomic.LoadPointer(&g.firstRunErrPtr)); err != nil {
+		snapshot.Error = (*err).Error()
+	}
+
+	g.mu.Lock()
+	tasks := make([]*taskEntry, 0, len(g.tasks))
+	for t := range g.tasks {
+		tasks = append(tasks, t)
+	}
+	g.mu.Unlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].seq < tasks[j].seq
+	})
+
+	snapshot.Tasks = make([]TaskSnapshot, len(tasks))
+	for i, t := range tasks {
+		snapshot.Tasks[i] = t.snapshot()
+	}
+
+	return snapshot
+}
+
+func (t *taskEntry) snapshot() TaskSnapshot {
+	snapshot := TaskSnapshot{
+		Name:     t.name,
+		Tags:     append([]string(nil), t.tags...),
+		Status:   TaskQueued,
+		Location: t.location,
+	}
+
+	if startedAt := atomic.LoadInt64(&t.startedAt); startedAt != 0 {
+		snapshot.Status = TaskRunning
+		snapshot.StartedAt = time.Unix(0, startedAt)
+	}
+
+	if t.ctx.Err() != nil {
+		snapshot.Status = TaskStopping
+	}
+
+	t.restartMu.Lock()
+	snapshot.Restarts = t.restarts
+	if t.restarts > 0 {
+		if t.lastRestart.Err != nil {
+			snapshot.LastError = t.lastRestart.Err.Error()
+		}
+		snapshot.Backoff = t.lastRestart.Backoff
+	}
+	t.restartMu.Unlock()
+
+	return snapshot
+}
+
+func (t *taskEntry) recordRestart(info RestartInfo) {
+	t.restartMu.Lock()
+	defer t.restartMu.Unlock()
+
+	t.restarts = info.Attempt
+	t.lastRestart = info
+}
+
+// taskPackagePrefix is the prefix of the functions of this package, skipped by callerLocation().
+const taskPackagePrefix = "github.com/sumup-oss/go-pkgs/task."
+
+// callerLocation returns the file:line of the first caller outside of this package.
+func callerLocation() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, taskPackagePrefix) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_Snapshot(t *testing.T) {
+	t.Run("it describes the running and queued tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithLaunchRate(1, 1), task.WithTaskLocations())
+
+		started := make(chan struct{})
+		group.GoNamed("consumer", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return nil
+		})
+		group.GoTagged(func(ctx context.Context) error {
+			return nil
+		}, "http")
+
+		<-started
+
+		snapshot := group.Snapshot()
+		assert.False(t, snapshot.Canceled)
+		assert.Empty(t, snapshot.Error)
+		require.Len(t, snapshot.Tasks, 2)
+
+		assert.Equal(t, "consumer", snapshot.Tasks[0].Name)
+		assert.Equal(t, task.TaskRunning, snapshot.Tasks[0].Status)
+		assert.False(t, snapshot.Tasks[0].StartedAt.IsZero())
+		assert.True(t, strings.Contains(snapshot.Tasks[0].Location, "snapshot_test.go:"), snapshot.Tasks[0].Location)
+
+		assert.Equal(t, []string{"http"}, snapshot.Tasks[1].Tags)
+		assert.Equal(t, task.TaskQueued, snapshot.Tasks[1].Status)
+		assert.True(t, snapshot.Tasks[1].StartedAt.IsZero())
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Empty(t, group.Snapshot().Tasks)
+	})
+
+	t.Run("it reports the error which failed the group", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.Go(func(ctx context.Context) error {
+			return errors.New("failed")
+		})
+
+		assert.Error(t, group.Wait(context.Background()))
+
+		snapshot := group.Snapshot()
+		assert.True(t, snapshot.Canceled)
+		assert.Equal(t, "failed", snapshot.Error)
+	})
+
+	t.Run("it does not capture the locations without WithTaskLocations", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return nil
+		})
+
+		<-started
+
+		snapshot := group.Snapshot()
+		require.Len(t, snapshot.Tasks, 1)
+		assert.Empty(t, snapshot.Tasks[0].Location)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import "time"
+
+// WithStartStagger spaces the start of consecutive tasks by at least d.
+//
+// Unlike WithLaunchRate(), there is no burst, every task is started d after the previous one.
+// The delay applies only to starting the tasks and not to how long they run.
+// Tasks waiting for their turn are not started at all if the group is canceled in the meantime.
+//
+// A non-positive d disables the staggering.
+func WithStartStagger(d time.Duration) GroupOption {
+	return func(g *Group) {
+		g.startStagger = d
+	}
+}
+
+// reserveStaggerLocked takes the turn of the task to start after the tasks started before it,
+// so the staggered tasks start in the order they are started by the scheduler.
+func (g *Group) reserveStaggerLocked(t *taskEntry) {
+	if g.startStagger <= 0 {
+		return
+	}
+
+	t.staggerPrev = g.staggerTail
+	t.staggerNext = make(chan struct{})
+	g.staggerTail = t.staggerNext
+}
+
+// awaitStagger blocks until the startStagger passes since the start of the previous task.
+// Returns false if the task is canceled in the meantime and must not be started.
+//
+// NOTE: The delay is measured from the actual start of the previous task, and not from the time it was
+// scheduled at, so a task starting late does not shorten the delay before the next one.
+func (g *Group) awaitStagger(t *taskEntry) bool {
+	if g.startStagger <= 0 {
+		return true
+	}
+
+	select {
+	case <-t.staggerPrev:
+	case <-t.ctx.Done():
+		g.skipStagger(t)
+
+		return false
+	}
+	defer close(t.staggerNext)
+
+	if !g.lastStaggeredStart.IsZero() && !g.awaitLaunch(t, g.lastStaggeredStart.Add(g.startStagger)) {
+		return false
+	}
+
+	g.lastStaggeredStart = g.clock.Now()
+
+	return true
+}
+
+// skipStagger passes the turn of a task which is not started on to the next task.
+//
+// NOTE: The turn is passed on only once the previous task is done with it, so the next tasks keep their order.
+func (g *Group) skipStagger(t *taskEntry) {
+	if g.startStagger <= 0 {
+		return
+	}
+
+	go func() {
+		<-t.staggerPrev
+		close(t.staggerNext)
+	}()
+}
@@ -0,0 +1,118 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithStartStagger(t *testing.T) {
+	t.Run("it starts the tasks in the order they were scheduled, stagger apart", func(t *testing.T) {
+		t.Parallel()
+
+		const stagger = time.Minute
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		group := task.NewGroup(task.WithClock(clock), task.WithStartStagger(stagger))
+
+		started := make(chan int, 5)
+		for i := 0; i < 5; i++ {
+			i := i
+			group.Go(func(ctx context.Context) error {
+				started <- i
+
+				return nil
+			})
+		}
+
+		assert.Equal(t, 0, <-started)
+		for i := 1; i < 5; i++ {
+			clock.BlockUntil(1)
+
+			clock.Advance(stagger - time.Second)
+			select {
+			case next := <-started:
+				t.Fatalf("task %d started before the stagger passed", next)
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			clock.Advance(time.Second)
+			assert.Equal(t, i, <-started)
+		}
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("the tasks keep their order when a task waiting for its turn is stopped", func(t *testing.T) {
+		t.Parallel()
+
+		const stagger = time.Minute
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		group := task.NewGroup(task.WithClock(clock), task.WithStartStagger(stagger))
+
+		started := make(chan string, 4)
+		for _, name := range []string{"first", "second", "third", "fourth"} {
+			name := name
+			group.GoNamed(name, func(ctx context.Context) error {
+				started <- name
+
+				return nil
+			})
+		}
+
+		assert.Equal(t, "first", <-started)
+		clock.BlockUntil(1)
+		assert.NoError(t, group.StopTask(context.Background(), "third"))
+
+		clock.Advance(stagger)
+		assert.Equal(t, "second", <-started)
+
+		clock.BlockUntil(1)
+		clock.Advance(stagger)
+		assert.Equal(t, "fourth", <-started)
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it starts the first task immediately", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithStartStagger(time.Hour))
+
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+
+			return nil
+		})
+
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("the first task must not be delayed")
+		}
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+	})
+}
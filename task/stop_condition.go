@@ -0,0 +1,85 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStopConditionMet is matched by the error returned by Group.Wait() when the group is stopped
+// by one of its stop conditions, see WithStopCondition().
+var ErrStopConditionMet = errors.New("stop condition met")
+
+// StopConditionError is the error returned by Group.Wait() when the group is stopped by a stop condition.
+type StopConditionError struct {
+	// Condition is the index of the stop condition which was met, in the order the conditions were registered.
+	Condition int
+}
+
+// Error returns which stop condition was met, e.g. "stop condition 1 met".
+func (e *StopConditionError) Error() string {
+	return fmt.Sprintf("stop condition %d met", e.Condition)
+}
+
+// Is reports whether target is ErrStopConditionMet.
+func (e *StopConditionError) Is(target error) bool {
+	return target == ErrStopConditionMet
+}
+
+// WithStopCondition stops the group once any of the conditions is met, i.e. the channel it returns is closed
+// or receives a value, e.g. a custom stop channel or the channel of time.After() for a max duration.
+//
+// The conditions are called once with the context of the group when it is created.
+// The group is canceled the same way as on a task failure, and Group.Wait() returns a *StopConditionError
+// telling which condition was met, unless the group already failed or was canceled before.
+// The option can be used multiple times, the conditions are indexed in the order they are registered.
+func WithStopCondition(conditions ...func(ctx context.Context) <-chan struct{}) GroupOption {
+	return func(g *Group) {
+		g.stopConditions = append(g.stopConditions, conditions...)
+	}
+}
+
+// watchStopConditions starts waiting for the stop conditions in the background, if configured.
+func (g *Group) watchStopConditions() {
+	if len(g.stopConditions) == 0 {
+		return
+	}
+
+	g.stopConditionsDone = make(chan struct{})
+
+	for i, condition := range g.stopConditions {
+		go func(i int, met <-chan struct{}) {
+			select {
+			case <-g.ctx.Done():
+			case <-g.stopConditionsDone:
+			case <-met:
+				g.cancelWithError(g.ctx, &StopConditionError{Condition: i})
+			}
+		}(i, condition(g.ctx))
+	}
+}
+
+// stopWatchingStopConditions stops waiting for the stop conditions once all the tasks are stopped.
+func (g *Group) stopWatchingStopConditions() {
+	if g.stopConditionsDone == nil {
+		return
+	}
+
+	g.stopConditionsOnce.Do(func() {
+		close(g.stopConditionsDone)
+	})
+}
@@ -0,0 +1,83 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestWithStopCondition(t *testing.T) {
+	t.Run("it stops the group once the stop channel fires, returning which condition was met", func(t *testing.T) {
+		t.Parallel()
+
+		never := make(chan struct{})
+		stop := make(chan struct{})
+		group := task.NewGroup(task.WithStopCondition(
+			func(ctx context.Context) <-chan struct{} { return never },
+			func(ctx context.Context) <-chan struct{} { return stop },
+		))
+
+		tt := NewTestTask(nil)
+		group.Go(tt.Run)
+		<-tt.RunReady
+
+		close(stop)
+
+		err := group.Wait(context.Background())
+		assert.True(t, errors.Is(err, task.ErrStopConditionMet))
+
+		var stopErr *task.StopConditionError
+		require.True(t, errors.As(err, &stopErr))
+		assert.Equal(t, 1, stopErr.Condition)
+		assert.EqualError(t, err, "stop condition 1 met")
+		assert.Equal(t, 1, tt.StopCount)
+	})
+
+	t.Run("it returns the error of the task failing before any condition is met", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithStopCondition(func(ctx context.Context) <-chan struct{} {
+			return make(chan struct{})
+		}))
+
+		errFailed := errors.New("failed")
+		group.Go(func(ctx context.Context) error {
+			return errFailed
+		})
+
+		assert.Equal(t, errFailed, group.Wait(context.Background()))
+	})
+
+	t.Run("it returns no error when the tasks complete before any condition is met", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithStopCondition(func(ctx context.Context) <-chan struct{} {
+			return make(chan struct{})
+		}))
+
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_StopTask(t *testing.T) {
+	t.Run("it stops only the named task", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		started := make(chan struct{})
+		stopped := make(chan struct{})
+		group.GoNamed("stoppable", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			close(stopped)
+
+			return ctx.Err()
+		})
+
+		release := make(chan struct{})
+		otherCanceled := make(chan struct{})
+		group.GoNamed("other", func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				close(otherCanceled)
+
+				return ctx.Err()
+			case <-release:
+				return nil
+			}
+		})
+
+		<-started
+
+		err := group.StopTask(context.Background(), "stoppable")
+		assert.NoError(t, err)
+
+		select {
+		case <-stopped:
+		default:
+			t.Fatal("StopTask returned before the task stopped")
+		}
+
+		select {
+		case <-otherCanceled:
+			t.Fatal("the other task must keep running")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(release)
+
+		err = group.Wait(context.Background())
+		assert.NoError(t, err)
+	})
+
+	t.Run("it discards the task waiting to be started", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithLaunchRate(1, 1))
+
+		release := make(chan struct{})
+		group.GoNamed("running", func(ctx context.Context) error {
+			<-release
+
+			return nil
+		})
+
+		var queuedStarted bool
+		group.GoNamed("queued", func(ctx context.Context) error {
+			queuedStarted = true
+
+			return nil
+		})
+
+		err := group.StopTask(context.Background(), "queued")
+		assert.NoError(t, err)
+
+		close(release)
+
+		err = group.Wait(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, queuedStarted)
+	})
+
+	t.Run("it fails the group when the stopped task returns another error", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		started := make(chan struct{})
+		group.GoNamed("failing", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return errors.New("failed to stop")
+		})
+
+		<-started
+
+		err := group.StopTask(context.Background(), "failing")
+		assert.NoError(t, err)
+
+		err = group.Wait(context.Background())
+		assert.EqualError(t, err, `task "failing" failed: failed to stop`)
+	})
+
+	t.Run("the task can stop itself", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		stopErr := make(chan error, 1)
+		group.GoNamed("self-stopping", func(ctx context.Context) error {
+			stopErr <- group.StopTask(ctx, "self-stopping")
+			<-ctx.Done()
+
+			return fmt.Errorf("stopped: %w", ctx.Err())
+		})
+
+		select {
+		case err := <-stopErr:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("StopTask waits for the task calling it")
+		}
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it returns an error for unknown task", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		err := group.StopTask(context.Background(), "unknown")
+		assert.True(t, errors.Is(err, task.ErrTaskNotFound))
+		assert.EqualError(t, err, `task "unknown": task not found`)
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// MaxSummaryFailures is the number of the failed tasks listed by RunSummary.Failed,
+// so the summary of a long-living group does not grow unbounded.
+const MaxSummaryFailures = 100
+
+// RunSummary is the outcome of the tasks run by a group, e.g. for a CLI tool to print a report
+// once a batch of tasks completes, see Group.Summary().
+type RunSummary struct {
+	// Total is the number of the tasks which ran and completed.
+	Total int
+	// Succeeded is the number of the tasks which returned no error.
+	Succeeded int
+	// Stopped is the number of the tasks stopped cleanly by returning the error of their context
+	// canceled by the group, or by Group.StopTask().
+	Stopped int
+	// FailedCount is the number of the tasks which failed.
+	FailedCount int
+	// Failed lists the first MaxSummaryFailures tasks which failed, in the order they completed.
+	Failed []TaskFailure
+	// Duration is the time between the start of the first task and the completion of the last one.
+	Duration time.Duration
+}
+
+// TaskFailure is a failed task of a RunSummary.
+type TaskFailure struct {
+	// Task is the name of the task, or the auto-generated one, e.g. "task-0".
+	Task string
+	Err  error
+}
+
+// WithRunSummary calls fn with the summary of the run once all the tasks are stopped,
+// right after the shutdown hooks are run by Group.Wait().
+func WithRunSummary(fn func(summary RunSummary)) GroupOption {
+	return func(g *Group) {
+		g.onRunSummary = fn
+	}
+}
+
+// Summary returns the summary of the tasks which completed so far.
+//
+// The tasks which were not started, e.g. because the group was canceled while they were queued,
+// are not included, the same way they are not in Group.Results().
+func (g *Group) Summary() RunSummary {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	summary := g.summary
+	summary.Failed = append([]TaskFailure(nil), g.summary.Failed...)
+	if !g.summaryStartedAt.IsZero() {
+		summary.Duration = g.summaryFinishedAt.Sub(g.summaryStartedAt)
+	}
+
+	return summary
+}
+
+// recordSummaryLocked records the completion of the task in the summary.
+// The err is nil if the task succeeded, and failed tells whether it failed the group.
+func (g *Group) recordSummaryLocked(t *taskEntry, err error, failed bool) {
+	g.summary.Total++
+
+	switch {
+	case failed:
+		g.summary.FailedCount++
+		if len(g.summary.Failed) < MaxSummaryFailures {
+			g.summary.Failed = append(g.summary.Failed, TaskFailure{Task: t.label, Err: err})
+		}
+	case err != nil:
+		g.summary.Stopped++
+	default:
+		g.summary.Succeeded++
+	}
+
+	startedAt := time.Unix(0, atomic.LoadInt64(&t.startedAt))
+	if g.summaryStartedAt.IsZero() || startedAt.Before(g.summaryStartedAt) {
+		g.summaryStartedAt = startedAt
+	}
+
+	finishedAt := g.clock.Now()
+	if finishedAt.After(g.summaryFinishedAt) {
+		g.summaryFinishedAt = finishedAt
+	}
+}
+
+func (g *Group) emitRunSummary() {
+	if g.onRunSummary != nil {
+		g.onRunSummary(g.Summary())
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestGroup_Summary(t *testing.T) {
+	t.Run("it counts the succeeded and the failed tasks of a mixed run", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		var emitted []task.RunSummary
+		group := task.NewGroup(
+			task.WithClock(clock),
+			task.WithCollectErrors(),
+			task.WithRunSummary(func(summary task.RunSummary) {
+				emitted = append(emitted, summary)
+			}),
+		)
+
+		errFailed := errors.New("failed")
+		group.GoNamed("slow", func(ctx context.Context) error {
+			clock.Advance(time.Minute)
+
+			return nil
+		})
+		group.GoNamed("fast", func(ctx context.Context) error {
+			return nil
+		})
+		group.GoNamed("failing", func(ctx context.Context) error {
+			return errFailed
+		})
+
+		assert.Error(t, group.Wait(context.Background()))
+
+		summary := group.Summary()
+		assert.Equal(t, 3, summary.Total)
+		assert.Equal(t, 2, summary.Succeeded)
+		assert.Equal(t, 0, summary.Stopped)
+		assert.Equal(t, 1, summary.FailedCount)
+		require.Len(t, summary.Failed, 1)
+		assert.Equal(t, "failing", summary.Failed[0].Task)
+		assert.True(t, errors.Is(summary.Failed[0].Err, errFailed))
+		assert.Equal(t, time.Minute, summary.Duration)
+
+		assert.Equal(t, []task.RunSummary{summary}, emitted)
+	})
+
+	t.Run("it counts the tasks stopped by the group", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		started := make(chan struct{})
+		group.GoNamed("stopped", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return ctx.Err()
+		})
+		group.GoNamed("failing", func(ctx context.Context) error {
+			<-started
+
+			return errCritical
+		})
+
+		assert.Error(t, group.Wait(context.Background()))
+
+		summary := group.Summary()
+		assert.Equal(t, 2, summary.Total)
+		assert.Equal(t, 0, summary.Succeeded)
+		assert.Equal(t, 1, summary.Stopped)
+		require.Len(t, summary.Failed, 1)
+		assert.Equal(t, "failing", summary.Failed[0].Task)
+	})
+
+	t.Run("it lists up to MaxSummaryFailures failed tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup(task.WithCollectErrors())
+
+		errFailed := errors.New("failed")
+		for i := 0; i < task.MaxSummaryFailures+10; i++ {
+			group.Go(func(ctx context.Context) error {
+				return errFailed
+			})
+		}
+
+		assert.Error(t, group.Wait(context.Background()))
+
+		summary := group.Summary()
+		assert.Equal(t, task.MaxSummaryFailures+10, summary.Total)
+		assert.Equal(t, task.MaxSummaryFailures+10, summary.FailedCount)
+		assert.Len(t, summary.Failed, task.MaxSummaryFailures)
+	})
+
+	t.Run("without completed tasks, it returns an empty summary", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+		require.NoError(t, group.Wait(context.Background()))
+
+		assert.Equal(t, task.RunSummary{}, group.Summary())
+	})
+}
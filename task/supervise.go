@@ -0,0 +1,128 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sumup-oss/go-pkgs/backoff"
+)
+
+// RestartPolicy controls how Supervise() restarts a task.
+type RestartPolicy struct {
+	// MaxRestarts is the maximum number of restarts of the task, -1 restarts it infinitely.
+	// The task is not restarted when it is 0, so Supervise() returns what the task returns.
+	// Any other negative value is invalid, and the supervised task returns an error without running.
+	MaxRestarts int
+	// Backoff is the delay between the restarts. If it is nil, the task restarted infinitely waits
+	// the backoff.DefaultConfig delays, so it does not spin, and the task restarted at most MaxRestarts
+	// times is restarted immediately.
+	Backoff Backoff
+	// ShouldRestart reports whether the task is restarted after it returned the err,
+	// otherwise the err is propagated. The task is restarted after any error if it is nil.
+	ShouldRestart func(err error) bool
+}
+
+// Supervise restarts the task every time it returns while the context is not canceled,
+// e.g. to keep a consumer alive across transient failures without canceling the whole group.
+//
+// The task returning nil is always restarted, since it is not expected to exit, and the task
+// returning an error is restarted unless RestartPolicy.ShouldRestart() tells otherwise,
+// in which case the error is returned.
+// If the task exits more than RestartPolicy.MaxRestarts times, Supervise returns MaxRetryExceedError,
+// unless RestartPolicy.MaxRestarts is 0.
+//
+// NOTE: when the context is canceled, Supervise stops restarting and returns nil, even if
+// the task had failed couple of times so far.
+func Supervise(fn TaskFunc, policy RestartPolicy) TaskFunc {
+	return supervise(realClock{}, fn, policy, nil)
+}
+
+// Supervise is the counterpart of the Supervise() function which waits the restart backoff
+// with the clock set with WithClock().
+//
+// The restarts are reported to the observers implementing RestartObserver, and by Group.Snapshot()
+// for the task run with the supervised function, the same way Group.GoUntilSuccess() reports them.
+func (g *Group) Supervise(fn TaskFunc, policy RestartPolicy) TaskFunc {
+	return supervise(g.clock, fn, policy, g.reportRestart)
+}
+
+// reportRestart records the restart of the task run with ctx, and notifies the observers about it.
+func (g *Group) reportRestart(ctx context.Context, info RestartInfo) {
+	if t := taskFromContext(ctx); t != nil {
+		t.recordRestart(info)
+	}
+	g.notifyTaskRestarted(info)
+}
+
+// supervise restarts fn according to the policy, and reports every restart with onRestart, unless it is nil.
+func supervise(
+	clock Clock,
+	fn TaskFunc,
+	policy RestartPolicy,
+	onRestart func(ctx context.Context, info RestartInfo),
+) TaskFunc {
+	return func(ctx context.Context) error {
+		if policy.MaxRestarts < -1 {
+			return fmt.Errorf("invalid max restarts %d, expected -1 or more", policy.MaxRestarts)
+		}
+
+		restartBackoff := policy.Backoff
+		if restartBackoff == nil && policy.MaxRestarts == -1 {
+			restartBackoff = backoff.NewBackoff(backoff.DefaultConfig)
+		}
+
+		for restarts := 0; ; restarts++ {
+			err := fn(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if policy.MaxRestarts == 0 {
+				return err
+			}
+
+			if err != nil && policy.ShouldRestart != nil && !policy.ShouldRestart(err) {
+				return err
+			}
+
+			if policy.MaxRestarts != -1 && restarts >= policy.MaxRestarts {
+				return NewMaxRetryError(policy.MaxRestarts, err)
+			}
+
+			info := RestartInfo{Attempt: restarts + 1, Err: err}
+			if restartBackoff != nil {
+				info.Backoff = restartBackoff.Next()
+			}
+			if onRestart != nil {
+				onRestart(ctx, info)
+			}
+
+			if info.Backoff == 0 {
+				continue
+			}
+
+			restartTimer := clock.NewTimer(info.Backoff)
+			select {
+			case <-ctx.Done():
+				restartTimer.Stop()
+
+				return nil
+			case <-restartTimer.C():
+			}
+		}
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/backoff"
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestSupervise(t *testing.T) {
+	t.Run("it restarts the task exiting with or without an error", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		attempts := 0
+		restarted := make(chan struct{})
+		group.Go(task.Supervise(
+			func(ctx context.Context) error {
+				attempts++
+				switch attempts {
+				case 1:
+					return errors.New("connection lost")
+				case 2:
+					return nil
+				default:
+					close(restarted)
+					<-ctx.Done()
+
+					return ctx.Err()
+				}
+			},
+			task.RestartPolicy{
+				MaxRestarts: -1,
+				Backoff:     backoff.NewBackoff(&backoff.Config{Base: time.Millisecond, Max: 5 * time.Millisecond, Jitter: noJitter}),
+			},
+		))
+
+		<-restarted
+		group.Cancel()
+
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("it returns MaxRetryExceedError once the task exceeds the max restarts", func(t *testing.T) {
+		t.Parallel()
+
+		errLost := errors.New("connection lost")
+
+		attempts := 0
+		err := task.Supervise(
+			func(ctx context.Context) error {
+				attempts++
+
+				return errLost
+			},
+			task.RestartPolicy{MaxRestarts: 2},
+		)(context.Background())
+
+		var maxRetryErr *task.MaxRetryExceedError
+		require.True(t, errors.As(err, &maxRetryErr))
+		assert.Equal(t, errLost, maxRetryErr.Cause())
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("it propagates the errors which must not restart the task", func(t *testing.T) {
+		t.Parallel()
+
+		errFatal := errors.New("fatal")
+
+		attempts := 0
+		err := task.Supervise(
+			func(ctx context.Context) error {
+				attempts++
+				if attempts == 1 {
+					return errors.New("connection lost")
+				}
+
+				return errFatal
+			},
+			task.RestartPolicy{
+				MaxRestarts: -1,
+				Backoff:     backoff.NewBackoff(&backoff.Config{Base: time.Millisecond, Max: time.Millisecond, Jitter: noJitter}),
+				ShouldRestart: func(err error) bool {
+					return err != errFatal
+				},
+			},
+		)(context.Background())
+
+		assert.Equal(t, errFatal, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("it stops restarting once the context is canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		attempts := 0
+		err := task.Supervise(
+			func(ctx context.Context) error {
+				attempts++
+				cancel()
+
+				return nil
+			},
+			task.RestartPolicy{
+				MaxRestarts: -1,
+				Backoff:     backoff.NewBackoff(&backoff.Config{Base: time.Hour, Max: time.Hour, Jitter: noJitter}),
+			},
+		)(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("the task is not restarted when the max restarts is 0", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		err := task.Supervise(
+			func(ctx context.Context) error {
+				attempts++
+
+				return nil
+			},
+			task.RestartPolicy{},
+		)(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempts)
+
+		errLost := errors.New("connection lost")
+		err = task.Supervise(
+			func(ctx context.Context) error {
+				return errLost
+			},
+			task.RestartPolicy{},
+		)(context.Background())
+
+		assert.Equal(t, errLost, err)
+	})
+
+	t.Run("the group waits the backoff with its clock", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		group := task.NewGroup(task.WithClock(clock))
+
+		attempts := make(chan int, 2)
+		attempt := 0
+		group.Go(group.Supervise(
+			func(ctx context.Context) error {
+				attempt++
+				attempts <- attempt
+				if attempt == 2 {
+					<-ctx.Done()
+
+					return ctx.Err()
+				}
+
+				return errors.New("connection lost")
+			},
+			task.RestartPolicy{
+				MaxRestarts: -1,
+				Backoff:     backoff.NewBackoff(&backoff.Config{Base: time.Hour, Max: time.Hour, Jitter: noJitter}),
+			},
+		))
+
+		assert.Equal(t, 1, <-attempts)
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Hour)
+		assert.Equal(t, 2, <-attempts)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("the group reports the restarts to the observers and in the snapshot", func(t *testing.T) {
+		t.Parallel()
+
+		observer := &restartRecordingObserver{}
+		group := task.NewGroup(task.WithObserver(observer))
+
+		restarted := make(chan struct{})
+		attempt := 0
+		group.Go(group.Supervise(
+			func(ctx context.Context) error {
+				attempt++
+				switch attempt {
+				case 1:
+					return errors.New("connection lost")
+				case 2:
+					return nil
+				default:
+					close(restarted)
+					<-ctx.Done()
+
+					return ctx.Err()
+				}
+			},
+			task.RestartPolicy{
+				MaxRestarts: -1,
+				Backoff:     backoff.NewBackoff(&backoff.Config{Base: time.Millisecond, Max: time.Millisecond, Jitter: noJitter}),
+			},
+		))
+
+		<-restarted
+
+		snapshot := group.Snapshot()
+		require.Len(t, snapshot.Tasks, 1)
+		assert.Equal(t, 2, snapshot.Tasks[0].Restarts)
+		assert.Empty(t, snapshot.Tasks[0].LastError)
+		assert.Equal(t, time.Millisecond, snapshot.Tasks[0].Backoff)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+
+		restarts := observer.Restarts()
+		require.Len(t, restarts, 2)
+		assert.Equal(t, 1, restarts[0].Attempt)
+		assert.EqualError(t, restarts[0].Err, "connection lost")
+		assert.Equal(t, 2, restarts[1].Attempt)
+		assert.NoError(t, restarts[1].Err)
+	})
+
+	t.Run("the task restarted infinitely waits the default backoff", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		group := task.NewGroup(task.WithClock(clock))
+
+		attempts := make(chan int, 2)
+		attempt := 0
+		group.Go(group.Supervise(
+			func(ctx context.Context) error {
+				attempt++
+				attempts <- attempt
+				if attempt == 2 {
+					<-ctx.Done()
+
+					return ctx.Err()
+				}
+
+				return nil
+			},
+			task.RestartPolicy{MaxRestarts: -1},
+		))
+
+		assert.Equal(t, 1, <-attempts)
+
+		clock.BlockUntil(1)
+		select {
+		case <-attempts:
+			t.Fatal("the task is restarted without waiting the backoff")
+		default:
+		}
+
+		clock.Advance(backoff.DefaultConfig.Max)
+		assert.Equal(t, 2, <-attempts)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it rejects the max restarts lower than -1", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		err := task.Supervise(
+			func(ctx context.Context) error {
+				attempts++
+
+				return nil
+			},
+			task.RestartPolicy{MaxRestarts: -2},
+		)(context.Background())
+
+		assert.EqualError(t, err, "invalid max restarts -2, expected -1 or more")
+		assert.Equal(t, 0, attempts)
+	})
+}
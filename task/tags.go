@@ -0,0 +1,54 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"fmt"
+)
+
+// GoTagged runs a task in the group, the same way Group.Go() does, and associates the tags with it.
+//
+// The tags can be used to stop all the tasks carrying a tag with Group.StopTag(),
+// e.g. to stop a whole subsystem without stopping the rest of the group.
+// The tags can also share the concurrency limit of the group fairly, see WithTagWeights().
+func (g *Group) GoTagged(fn TaskFunc, tags ...string) {
+	if g.ctx.Err() != nil {
+		return
+	}
+
+	t := g.newTaskEntry("", fn)
+	t.tags = append([]string(nil), tags...)
+
+	g.schedule([]*taskEntry{t})
+}
+
+// StopTag stops the tasks carrying the tag without stopping the rest of the group,
+// the same way Group.StopTask() stops the tasks with a name.
+//
+// Returns ErrTaskNotFound if there is no running or queued task with this tag.
+func (g *Group) StopTag(ctx context.Context, tag string) error {
+	g.mu.Lock()
+	tasks := g.detachLocked(g.tagged[tag])
+	g.mu.Unlock()
+
+	if len(tasks) == 0 {
+		return fmt.Errorf("task tag %q: %w", tag, ErrTaskNotFound)
+	}
+
+	g.stopTasks(ctx, tasks)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestGroup_StopTag(t *testing.T) {
+	t.Run("it stops only the tagged tasks", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		var started, stopped int32
+		startedCh := make(chan struct{}, 3)
+		for _, tags := range [][]string{{"consumer"}, {"consumer", "orders"}, {"http"}} {
+			tags := tags
+			group.GoTagged(func(ctx context.Context) error {
+				atomic.AddInt32(&started, 1)
+				startedCh <- struct{}{}
+				<-ctx.Done()
+
+				if tags[0] == "consumer" {
+					atomic.AddInt32(&stopped, 1)
+				}
+
+				return ctx.Err()
+			}, tags...)
+		}
+
+		untaggedCanceled := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			startedCh <- struct{}{}
+			<-ctx.Done()
+			close(untaggedCanceled)
+
+			return nil
+		})
+
+		for i := 0; i < 4; i++ {
+			<-startedCh
+		}
+
+		err := group.StopTag(context.Background(), "consumer")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&stopped))
+
+		select {
+		case <-untaggedCanceled:
+			t.Fatal("the untagged task must keep running")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		err = group.StopTag(context.Background(), "consumer")
+		assert.True(t, errors.Is(err, task.ErrTaskNotFound))
+
+		err = group.StopTag(context.Background(), "http")
+		assert.NoError(t, err)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+		assert.Equal(t, int32(3), atomic.LoadInt32(&started))
+	})
+
+	t.Run("it doesn't wait for the tagged task stopping its own tag", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		otherStarted := make(chan struct{})
+		otherStopped := make(chan struct{})
+		group.GoTagged(func(ctx context.Context) error {
+			close(otherStarted)
+			<-ctx.Done()
+			close(otherStopped)
+
+			return ctx.Err()
+		}, "consumer")
+		<-otherStarted
+
+		stopErr := make(chan error, 1)
+		group.GoTagged(func(ctx context.Context) error {
+			stopErr <- group.StopTag(ctx, "consumer")
+			<-ctx.Done()
+
+			return ctx.Err()
+		}, "consumer")
+
+		select {
+		case err := <-stopErr:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("StopTag waits for the task calling it")
+		}
+
+		select {
+		case <-otherStopped:
+		default:
+			t.Fatal("StopTag must wait for the other tagged task")
+		}
+
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+
+	t.Run("it returns an error for unknown tag", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		err := group.StopTag(context.Background(), "unknown")
+		assert.True(t, errors.Is(err, task.ErrTaskNotFound))
+		assert.EqualError(t, err, `task tag "unknown": task not found`)
+	})
+}
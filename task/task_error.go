@@ -0,0 +1,73 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+)
+
+// TaskError is an error carrying the context of the task which returned it,
+// e.g. so the error handlers can link the error to the trace of the task.
+//
+// Group.ErrorChan() delivers the first error which fails the group as a *TaskError
+// with the context of the failed task.
+type TaskError struct {
+	Err error
+	ctx context.Context
+}
+
+// NewTaskError attaches ctx to err.
+//
+// The tasks can return it to attach a more specific context than the one they were run with,
+// e.g. the context holding the span of the failed operation.
+func NewTaskError(ctx context.Context, err error) error {
+	return &TaskError{Err: err, ctx: ctx}
+}
+
+// Error returns the message of the wrapped error.
+func (e *TaskError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Context returns the context of the task which returned the error.
+//
+// NOTE: The context is usually canceled by the time the error is handled,
+// so it must be used only to access its values.
+func (e *TaskError) Context() context.Context {
+	return e.ctx
+}
+
+// withTaskContext returns err as a *TaskError, attaching ctx unless err already carries a context.
+//
+// When err wraps a *TaskError, the returned error keeps err as the wrapped error,
+// so it has the same message as err, and takes only the context of the wrapped *TaskError.
+func withTaskContext(ctx context.Context, err error) *TaskError {
+	if taskErr, ok := err.(*TaskError); ok {
+		return taskErr
+	}
+
+	var taskErr *TaskError
+	if errors.As(err, &taskErr) {
+		ctx = taskErr.ctx
+	}
+
+	return &TaskError{Err: err, ctx: ctx}
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+type spanKey struct{}
+
+func TestTaskError(t *testing.T) {
+	t.Run("the error delivered by ErrorChan carries the context of the failed task", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		taskErr := errors.New("task failed")
+		taskCtx := make(chan context.Context, 1)
+		group.Go(func(ctx context.Context) error {
+			taskCtx <- ctx
+
+			return taskErr
+		})
+
+		err := <-group.ErrorChan()
+
+		var errWithContext *task.TaskError
+		require.True(t, errors.As(err, &errWithContext))
+		assert.Equal(t, <-taskCtx, errWithContext.Context())
+		assert.True(t, errors.Is(err, taskErr))
+
+		assert.Equal(t, taskErr, group.Wait(context.Background()))
+	})
+
+	t.Run("it keeps the context attached by the task", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		group.Go(func(ctx context.Context) error {
+			spanCtx := context.WithValue(ctx, spanKey{}, "span-1")
+
+			return task.NewTaskError(spanCtx, errors.New("task failed"))
+		})
+
+		err := <-group.ErrorChan()
+
+		var errWithContext *task.TaskError
+		require.True(t, errors.As(err, &errWithContext))
+		assert.Equal(t, "span-1", errWithContext.Context().Value(spanKey{}))
+		assert.EqualError(t, err, "task failed")
+
+		_ = group.Wait(context.Background())
+	})
+
+	t.Run("it keeps the errors wrapping the error with the context attached by the task", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		taskErr := errors.New("task failed")
+		group.Go(func(ctx context.Context) error {
+			spanCtx := context.WithValue(ctx, spanKey{}, "span-1")
+
+			return fmt.Errorf("sync orders: %w", task.NewTaskError(spanCtx, taskErr))
+		})
+
+		err := <-group.ErrorChan()
+
+		var errWithContext *task.TaskError
+		require.True(t, errors.As(err, &errWithContext))
+		assert.Equal(t, "span-1", errWithContext.Context().Value(spanKey{}))
+		assert.True(t, errors.Is(err, taskErr))
+		assert.EqualError(t, err, "sync orders: task failed")
+
+		waitErr := group.Wait(context.Background())
+		assert.EqualError(t, waitErr, err.Error())
+	})
+
+	t.Run("when the context of Wait is done, the error carries that context", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return nil
+		})
+		<-started
+
+		waitCtx, cancel := context.WithCancel(context.WithValue(context.Background(), spanKey{}, "span-2"))
+		cancel()
+
+		err := group.Wait(waitCtx)
+		assert.Equal(t, context.Canceled, err)
+
+		errWithContext, ok := (<-group.ErrorChan()).(*task.TaskError)
+		require.True(t, ok)
+		assert.Equal(t, "span-2", errWithContext.Context().Value(spanKey{}))
+	})
+}
@@ -0,0 +1,44 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package taskgeneric provides the generic helpers of the task package.
+//
+// NOTE: It is a separate module, since the generics require a newer Go than the rest of the modules.
+package taskgeneric
+
+import (
+	"context"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// GoForEach runs fn once per item in the group, each run as a separate task getting its own item.
+//
+// The tasks are scheduled the same way Group.Go() schedules them, so they honor the concurrency limit
+// of the group, and the first one failing cancels the rest:
+//
+//	taskgeneric.GoForEach(group, tenants, func(ctx context.Context, tenant Tenant) error {
+//		return process(ctx, tenant)
+//	})
+func GoForEach[T any](group *task.Group, items []T, fn func(ctx context.Context, item T) error) {
+	tasks := make([]task.TaskFunc, len(items))
+	for i := range items {
+		item := items[i]
+		tasks[i] = func(ctx context.Context) error {
+			return fn(ctx, item)
+		}
+	}
+
+	group.Go(tasks...)
+}
@@ -0,0 +1,70 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskgeneric_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/taskgeneric"
+)
+
+func TestGoForEach(t *testing.T) {
+	t.Run("it runs one task per item", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		var mu sync.Mutex
+		var processed []string
+		taskgeneric.GoForEach(group, []string{"tenant-b", "tenant-a", "tenant-c"}, func(ctx context.Context, tenant string) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			processed = append(processed, tenant)
+
+			return nil
+		})
+
+		assert.NoError(t, group.Wait(context.Background()))
+
+		sort.Strings(processed)
+		assert.Equal(t, []string{"tenant-a", "tenant-b", "tenant-c"}, processed)
+	})
+
+	t.Run("it fails the group when a task fails", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		taskgeneric.GoForEach(group, []int{1, 2}, func(ctx context.Context, item int) error {
+			if item == 2 {
+				return errors.New("failed item")
+			}
+
+			<-ctx.Done()
+
+			return nil
+		})
+
+		assert.EqualError(t, group.Wait(context.Background()), "failed item")
+	})
+}
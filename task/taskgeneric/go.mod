@@ -0,0 +1,17 @@
+module github.com/sumup-oss/go-pkgs/task/taskgeneric
+
+go 1.18
+
+require (
+	github.com/stretchr/testify v1.7.0
+	github.com/sumup-oss/go-pkgs v0.0.0-20210806131309-dd59fc2fd123
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
+)
+
+// NOTE: The module requires the root module of the same checkout, until both are released and tagged together.
+replace github.com/sumup-oss/go-pkgs => ../..
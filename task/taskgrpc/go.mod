@@ -0,0 +1,24 @@
+module github.com/sumup-oss/go-pkgs/task/taskgrpc
+
+go 1.22
+
+require (
+	github.com/palantir/stacktrace v0.0.0-20161112013806-78658fd2d177
+	github.com/stretchr/testify v1.9.0
+	github.com/sumup-oss/go-pkgs v0.0.0-20210806131309-dd59fc2fd123
+	google.golang.org/grpc v1.65.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.25.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157 // indirect
+	google.golang.org/protobuf v1.34.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// NOTE: The module requires the root module of the same checkout, until both are released and tagged together.
+replace github.com/sumup-oss/go-pkgs => ../..
@@ -0,0 +1,122 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package taskgrpc provides a task running a gRPC server until the task group is canceled.
+package taskgrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"google.golang.org/grpc"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// DefaultGracefulStopTimeout is how long the server waits for the pending RPCs before it is stopped forcefully.
+const DefaultGracefulStopTimeout = 30 * time.Second
+
+// ErrGracefulStopTimeout is returned when the pending RPCs are not finished within the graceful stop timeout,
+// and the server is stopped forcefully.
+var ErrGracefulStopTimeout = errors.New("gRPC server graceful stop timed out")
+
+// Option configures the task returned by Server().
+type Option func(s *server)
+
+// WithGracefulStopTimeout sets how long the server waits for the pending RPCs before it is stopped forcefully,
+// DefaultGracefulStopTimeout by default.
+func WithGracefulStopTimeout(timeout time.Duration) Option {
+	return func(s *server) {
+		s.gracefulStopTimeout = timeout
+	}
+}
+
+type server struct {
+	srv                 *grpc.Server
+	lis                 net.Listener
+	gracefulStopTimeout time.Duration
+}
+
+// Server returns a task serving srv on lis until the context is canceled, e.g. a task.Group is canceled.
+//
+// Once the context is canceled the server is stopped gracefully, see grpc.Server.GracefulStop(),
+// and the task returns nil. If the pending RPCs are not finished within the graceful stop timeout,
+// the server is stopped forcefully and the task returns ErrGracefulStopTimeout.
+// If the server fails to serve, the task returns the error of grpc.Server.Serve().
+func Server(srv *grpc.Server, lis net.Listener, opts ...Option) task.TaskFunc {
+	s := &server{
+		srv:                 srv,
+		lis:                 lis,
+		gracefulStopTimeout: DefaultGracefulStopTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s.run
+}
+
+func (s *server) run(ctx context.Context) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.srv.Serve(s.lis)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			return stacktrace.Propagate(err, "gRPC server failed to serve")
+		}
+
+		// NOTE: Serve returns nil only once the server is stopped, e.g. by another task.
+		return nil
+	case <-ctx.Done():
+	}
+
+	stopErr := s.stop()
+
+	err := <-serveErr
+	if err != nil {
+		return stacktrace.Propagate(err, "gRPC server failed to serve")
+	}
+
+	return stopErr
+}
+
+// stop stops the server gracefully, or forcefully once the graceful stop timeout passes.
+func (s *server) stop() error {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		s.srv.GracefulStop()
+	}()
+
+	timer := time.NewTimer(s.gracefulStopTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-timer.C:
+		s.srv.Stop()
+		<-stopped
+
+		return ErrGracefulStopTimeout
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskgrpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/taskgrpc"
+)
+
+func newHealthServer() *grpc.Server {
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+
+	return srv
+}
+
+func newHealthClient(t *testing.T, lis *bufconn.Listener) healthpb.HealthClient {
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	t.Run("it serves until the group is canceled and stops gracefully", func(t *testing.T) {
+		t.Parallel()
+
+		lis := bufconn.Listen(1024 * 1024)
+		group := task.NewGroup()
+		group.Go(taskgrpc.Server(newHealthServer(), lis))
+
+		client := newHealthClient(t, lis)
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+
+		_, err = lis.Dial()
+		assert.Error(t, err)
+	})
+
+	t.Run("it stops the server forcefully once the graceful stop timeout passes", func(t *testing.T) {
+		t.Parallel()
+
+		lis := bufconn.Listen(1024 * 1024)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		runErr := make(chan error)
+		go func() {
+			runErr <- taskgrpc.Server(newHealthServer(), lis, taskgrpc.WithGracefulStopTimeout(10*time.Millisecond))(ctx)
+		}()
+
+		// NOTE: The watch stream is pending until the server is stopped, so the graceful stop never completes.
+		client := newHealthClient(t, lis)
+		stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+
+		cancel()
+		assert.Equal(t, taskgrpc.ErrGracefulStopTimeout, <-runErr)
+	})
+
+	t.Run("it returns the error of the server failing to serve", func(t *testing.T) {
+		t.Parallel()
+
+		lis := bufconn.Listen(1024 * 1024)
+		require.NoError(t, lis.Close())
+
+		err := taskgrpc.Server(newHealthServer(), lis)(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "gRPC server failed to serve")
+		assert.NotEqual(t, taskgrpc.ErrGracefulStopTimeout, stacktrace.RootCause(err))
+	})
+}
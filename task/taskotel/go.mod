@@ -0,0 +1,27 @@
+module github.com/sumup-oss/go-pkgs/task/taskotel
+
+go 1.22
+
+require (
+	github.com/palantir/stacktrace v0.0.0-20161112013806-78658fd2d177
+	github.com/stretchr/testify v1.9.0
+	github.com/sumup-oss/go-pkgs v0.0.0-20210806131309-dd59fc2fd123
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/metric v1.28.0
+	go.opentelemetry.io/otel/sdk/metric v1.28.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel/sdk v1.28.0 // indirect
+	go.opentelemetry.io/otel/trace v1.28.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// NOTE: The module requires the root module of the same checkout, until both are released and tagged together.
+replace github.com/sumup-oss/go-pkgs => ../..
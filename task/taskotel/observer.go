@@ -0,0 +1,114 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package taskotel provides a task.Observer recording OpenTelemetry metrics.
+package taskotel
+
+import (
+	"context"
+
+	"github.com/palantir/stacktrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// Ensure that Observer implements the task observer interfaces.
+var (
+	_ task.Observer        = (*Observer)(nil)
+	_ task.RestartObserver = (*Observer)(nil)
+)
+
+const (
+	// StatusKey is the attribute with the status of a finished task, either StatusSuccess or StatusFailure.
+	// The tasks stopped cleanly by the group have the StatusSuccess.
+	StatusKey     = attribute.Key("status")
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Observer is a task.Observer that records the task lifecycle with OpenTelemetry instruments.
+//
+// It records:
+//
+//	task.completions - counter of the finished tasks, with the StatusKey attribute
+//	task.duration - histogram of the tasks run duration in seconds
+//	task.restarts - counter of the restarts of the tasks run with task.Group.GoUntilSuccess()
+type Observer struct {
+	completions metric.Int64Counter
+	duration    metric.Float64Histogram
+	restarts    metric.Int64Counter
+}
+
+// NewObserver creates an Observer recording its instruments with meter.
+//
+// Use it with task.WithObserver():
+//
+//	observer, err := taskotel.NewObserver(otel.Meter("myapp"))
+//	group := task.NewGroup(task.WithObserver(observer))
+func NewObserver(meter metric.Meter) (*Observer, error) {
+	completions, err := meter.Int64Counter(
+		"task.completions",
+		metric.WithDescription("Number of the finished tasks by status."),
+		metric.WithUnit("{task}"),
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create the task completions counter")
+	}
+
+	duration, err := meter.Float64Histogram(
+		"task.duration",
+		metric.WithDescription("Duration of the task runs."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create the task duration histogram")
+	}
+
+	restarts, err := meter.Int64Counter(
+		"task.restarts",
+		metric.WithDescription("Number of restarts of the tasks that returned an error."),
+		metric.WithUnit("{restart}"),
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create the task restarts counter")
+	}
+
+	return &Observer{
+		completions: completions,
+		duration:    duration,
+		restarts:    restarts,
+	}, nil
+}
+
+// TaskStarted implements task.Observer.
+func (o *Observer) TaskStarted(info task.TaskInfo) {}
+
+// TaskFinished implements task.Observer.
+func (o *Observer) TaskFinished(info task.TaskInfo, err error) {
+	status := StatusSuccess
+	if err != nil && !info.Stopped {
+		status = StatusFailure
+	}
+
+	ctx := context.Background()
+	o.completions.Add(ctx, 1, metric.WithAttributes(StatusKey.String(status)))
+	o.duration.Record(ctx, info.Duration.Seconds())
+}
+
+// TaskRestarted implements task.RestartObserver.
+func (o *Observer) TaskRestarted(info task.RestartInfo) {
+	o.restarts.Add(context.Background(), 1)
+}
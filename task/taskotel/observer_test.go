@@ -0,0 +1,146 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/taskotel"
+)
+
+type noBackoff struct{}
+
+func (noBackoff) Next() time.Duration { return 0 }
+
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	metrics := map[string]metricdata.Aggregation{}
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	return metrics
+}
+
+func TestObserver(t *testing.T) {
+	t.Run("it records the completions by status and the duration of the tasks", func(t *testing.T) {
+		t.Parallel()
+
+		reader := sdkmetric.NewManualReader()
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+		observer, err := taskotel.NewObserver(provider.Meter("test"))
+		require.NoError(t, err)
+
+		group := task.NewGroup(task.WithObserver(observer), task.WithCollectErrors())
+		group.Go(
+			func(ctx context.Context) error { return nil },
+			func(ctx context.Context) error { return nil },
+			func(ctx context.Context) error { return errors.New("failed") },
+		)
+		assert.Error(t, group.Wait(context.Background()))
+
+		metrics := collect(t, reader)
+
+		completions, ok := metrics["task.completions"].(metricdata.Sum[int64])
+		require.True(t, ok)
+
+		byStatus := map[string]int64{}
+		for _, point := range completions.DataPoints {
+			status, _ := point.Attributes.Value(taskotel.StatusKey)
+			byStatus[status.AsString()] = point.Value
+		}
+		assert.Equal(t, map[string]int64{taskotel.StatusSuccess: 2, taskotel.StatusFailure: 1}, byStatus)
+
+		duration, ok := metrics["task.duration"].(metricdata.Histogram[float64])
+		require.True(t, ok)
+		require.Len(t, duration.DataPoints, 1)
+		assert.Equal(t, uint64(3), duration.DataPoints[0].Count)
+		assert.Equal(t, attribute.EmptySet(), &duration.DataPoints[0].Attributes)
+	})
+
+	t.Run("it records the tasks stopped cleanly as successful", func(t *testing.T) {
+		t.Parallel()
+
+		reader := sdkmetric.NewManualReader()
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+		observer, err := taskotel.NewObserver(provider.Meter("test"))
+		require.NoError(t, err)
+
+		group := task.NewGroup(task.WithObserver(observer))
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return ctx.Err()
+		})
+
+		<-started
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+
+		completions, ok := collect(t, reader)["task.completions"].(metricdata.Sum[int64])
+		require.True(t, ok)
+		require.Len(t, completions.DataPoints, 1)
+
+		status, _ := completions.DataPoints[0].Attributes.Value(taskotel.StatusKey)
+		assert.Equal(t, taskotel.StatusSuccess, status.AsString())
+	})
+
+	t.Run("it records the restarts of the tasks", func(t *testing.T) {
+		t.Parallel()
+
+		reader := sdkmetric.NewManualReader()
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+		observer, err := taskotel.NewObserver(provider.Meter("test"))
+		require.NoError(t, err)
+
+		attempts := 0
+		group := task.NewGroup(task.WithObserver(observer))
+		group.GoUntilSuccess(func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("not yet")
+			}
+
+			return nil
+		}, noBackoff{})
+		assert.NoError(t, group.Wait(context.Background()))
+
+		restarts, ok := collect(t, reader)["task.restarts"].(metricdata.Sum[int64])
+		require.True(t, ok)
+		require.Len(t, restarts.DataPoints, 1)
+		assert.Equal(t, int64(2), restarts.DataPoints[0].Value)
+	})
+}
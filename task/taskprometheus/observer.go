@@ -0,0 +1,143 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package taskprometheus provides a task.Observer exporting Prometheus metrics.
+package taskprometheus
+
+import (
+	"github.com/palantir/stacktrace"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// Ensure that Observer implements the task observer interfaces.
+var (
+	_ task.Observer          = (*Observer)(nil)
+	_ task.RestartObserver   = (*Observer)(nil)
+	_ task.StuckTaskObserver = (*Observer)(nil)
+)
+
+// Observer is a task.Observer that exports the task lifecycle as Prometheus metrics.
+//
+// It exports:
+//
+//	<namespace>_tasks_running - gauge with the number of currently running tasks
+//	<namespace>_tasks_completed_total - counter of the tasks that returned no error, or stopped cleanly
+//	<namespace>_tasks_failed_total - counter of the tasks that failed
+//	<namespace>_tasks_duration_seconds - histogram of the tasks run duration
+//	<namespace>_tasks_restarts_total - counter of the restarts of the tasks run with task.Group.GoUntilSuccess()
+//	<namespace>_tasks_stuck_total - counter of the tasks flagged by the watchdog, see task.WithWatchdog()
+type Observer struct {
+	running   prometheus.Gauge
+	completed prometheus.Counter
+	failed    prometheus.Counter
+	duration  prometheus.Histogram
+	restarts  prometheus.Counter
+	stuck     prometheus.Counter
+}
+
+// NewObserver creates an Observer and registers its metrics in reg.
+//
+// Use it with task.WithObserver():
+//
+//	observer, err := taskprometheus.NewObserver(prometheus.DefaultRegisterer, "myapp")
+//	group := task.NewGroup(task.WithObserver(observer))
+func NewObserver(reg prometheus.Registerer, namespace string) (*Observer, error) {
+	observer := &Observer{
+		running: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "tasks",
+			Name:      "running",
+			Help:      "Number of currently running tasks.",
+		}),
+		completed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "tasks",
+			Name:      "completed_total",
+			Help:      "Total number of tasks that returned no error, or stopped cleanly.",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "tasks",
+			Name:      "failed_total",
+			Help:      "Total number of tasks that failed.",
+		}),
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "tasks",
+			Name:      "duration_seconds",
+			Help:      "Duration of the task runs in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}),
+		restarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "tasks",
+			Name:      "restarts_total",
+			Help:      "Total number of restarts of the tasks that returned an error.",
+		}),
+		stuck: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "tasks",
+			Name:      "stuck_total",
+			Help:      "Total number of tasks that ran for longer than the max run time of the watchdog.",
+		}),
+	}
+
+	collectors := []prometheus.Collector{
+		observer.running,
+		observer.completed,
+		observer.failed,
+		observer.duration,
+		observer.restarts,
+		observer.stuck,
+	}
+	for _, collector := range collectors {
+		err := reg.Register(collector)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "failed to register the task metrics")
+		}
+	}
+
+	return observer, nil
+}
+
+// TaskStarted implements task.Observer.
+func (o *Observer) TaskStarted(info task.TaskInfo) {
+	o.running.Inc()
+}
+
+// TaskFinished implements task.Observer.
+func (o *Observer) TaskFinished(info task.TaskInfo, err error) {
+	o.running.Dec()
+	o.duration.Observe(info.Duration.Seconds())
+
+	if err != nil && !info.Stopped {
+		o.failed.Inc()
+
+		return
+	}
+
+	o.completed.Inc()
+}
+
+// TaskRestarted implements task.RestartObserver.
+func (o *Observer) TaskRestarted(info task.RestartInfo) {
+	o.restarts.Inc()
+}
+
+// TaskStuck implements task.StuckTaskObserver.
+func (o *Observer) TaskStuck(info task.StuckTaskInfo) {
+	o.stuck.Inc()
+}
@@ -0,0 +1,203 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskprometheus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/taskprometheus"
+)
+
+func TestNewObserver(t *testing.T) {
+	t.Run("it registers the task metrics", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		_, err := taskprometheus.NewObserver(reg, "test")
+		require.NoError(t, err)
+
+		count, err := testutil.GatherAndCount(reg)
+		require.NoError(t, err)
+		assert.Equal(t, 6, count)
+	})
+
+	t.Run("when the metrics are already registered, it returns an error", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		_, err := taskprometheus.NewObserver(reg, "test")
+		require.NoError(t, err)
+
+		_, err = taskprometheus.NewObserver(reg, "test")
+		assert.Error(t, err)
+	})
+}
+
+func TestObserver(t *testing.T) {
+	t.Run("it updates the metrics after the tasks complete", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		observer, err := taskprometheus.NewObserver(reg, "test")
+		require.NoError(t, err)
+
+		group := task.NewGroup(task.WithObserver(observer))
+		running := make(chan struct{})
+		stop := make(chan struct{})
+
+		group.Go(func(ctx context.Context) error {
+			close(running)
+			<-stop
+
+			return nil
+		})
+
+		<-running
+		assertMetric(t, reg, "test_tasks_running", 1)
+
+		close(stop)
+		require.NoError(t, group.Wait(context.Background()))
+
+		assertMetric(t, reg, "test_tasks_running", 0)
+		assertMetric(t, reg, "test_tasks_completed_total", 1)
+		assertMetric(t, reg, "test_tasks_failed_total", 0)
+		assertMetric(t, reg, "test_tasks_duration_seconds", 1)
+	})
+
+	t.Run("it counts the failed tasks", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		observer, err := taskprometheus.NewObserver(reg, "test")
+		require.NoError(t, err)
+
+		group := task.NewGroup(task.WithObserver(observer))
+		group.Go(func(ctx context.Context) error {
+			return assert.AnError
+		})
+
+		assert.Equal(t, assert.AnError, group.Wait(context.Background()))
+
+		assertMetric(t, reg, "test_tasks_completed_total", 0)
+		assertMetric(t, reg, "test_tasks_failed_total", 1)
+		assertMetric(t, reg, "test_tasks_duration_seconds", 1)
+	})
+
+	t.Run("it does not count the tasks stopped cleanly as failed", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		observer, err := taskprometheus.NewObserver(reg, "test")
+		require.NoError(t, err)
+
+		group := task.NewGroup(task.WithObserver(observer))
+		started := make(chan struct{})
+		group.Go(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+
+			return ctx.Err()
+		})
+
+		<-started
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+
+		assertMetric(t, reg, "test_tasks_completed_total", 1)
+		assertMetric(t, reg, "test_tasks_failed_total", 0)
+	})
+
+	t.Run("it counts the restarts", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		observer, err := taskprometheus.NewObserver(reg, "test")
+		require.NoError(t, err)
+
+		group := task.NewGroup(task.WithObserver(observer))
+		attempts := 0
+		group.GoUntilSuccess(func(ctx context.Context) error {
+			attempts++
+			if attempts <= 2 {
+				return assert.AnError
+			}
+
+			return nil
+		}, noBackoff{})
+
+		require.NoError(t, group.Wait(context.Background()))
+
+		assertMetric(t, reg, "test_tasks_restarts_total", 2)
+		assertMetric(t, reg, "test_tasks_completed_total", 1)
+	})
+
+	t.Run("it counts the stuck tasks", func(t *testing.T) {
+		t.Parallel()
+
+		reg := prometheus.NewPedanticRegistry()
+		observer, err := taskprometheus.NewObserver(reg, "test")
+		require.NoError(t, err)
+
+		observer.TaskStuck(task.StuckTaskInfo{Task: "foo", RunTime: time.Minute, Action: task.WatchdogWarn})
+
+		assertMetric(t, reg, "test_tasks_stuck_total", 1)
+	})
+}
+
+type noBackoff struct{}
+
+func (noBackoff) Next() time.Duration {
+	return 0
+}
+
+func gather(t *testing.T, reg prometheus.Gatherer, name string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		metric := family.GetMetric()[0]
+		switch {
+		case metric.GetGauge() != nil:
+			return metric.GetGauge().GetValue()
+		case metric.GetCounter() != nil:
+			return metric.GetCounter().GetValue()
+		case metric.GetHistogram() != nil:
+			return float64(metric.GetHistogram().GetSampleCount())
+		}
+	}
+
+	t.Fatalf("metric %s not found", name)
+
+	return 0
+}
+
+func assertMetric(t *testing.T, reg prometheus.Gatherer, name string, expected float64) {
+	t.Helper()
+	assert.Equal(t, expected, gather(t, reg, name), name)
+}
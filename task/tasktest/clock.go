@@ -0,0 +1,208 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tasktest provides utilities for testing code built on the task package.
+package tasktest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// Ensure that FakeClock implements the task.Clock interface.
+var _ task.Clock = (*FakeClock)(nil)
+
+// FakeClock is a task.Clock whose time moves only when it is advanced with Advance(),
+// so the time-based features can be tested deterministically and without sleeps.
+//
+// Since the code under test usually creates its timers from other goroutines, use BlockUntil()
+// to wait until the timers are created before advancing the time.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFakeClock creates a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	clock := &FakeClock{now: now}
+	clock.cond = sync.NewCond(&clock.mu)
+
+	return clock
+}
+
+// Now returns the current fake time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After returns a channel receiving the fake time once it is advanced by d.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer creates a timer firing once the fake time is advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) task.Timer {
+	return c.addWaiter(d, 0)
+}
+
+// NewTicker creates a ticker firing every time the fake time is advanced by d.
+//
+// Like time.Ticker, it drops the ticks if the receiver is not fast enough.
+func (c *FakeClock) NewTicker(d time.Duration) task.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for FakeClock.NewTicker")
+	}
+
+	return fakeTicker{c.addWaiter(d, d)}
+}
+
+// Advance moves the fake time forward by d and fires the timers and tickers which are due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+
+	for {
+		next := c.nextDueLocked(end)
+		if next == nil {
+			break
+		}
+
+		c.now = next.at
+		next.fireLocked()
+	}
+
+	c.now = end
+	c.cond.Broadcast()
+}
+
+// BlockUntil blocks until there are at least n active timers and tickers, i.e. not stopped and not fired.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// Waiters returns the number of active timers and tickers.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+func (c *FakeClock) addWaiter(d, interval time.Duration) *fakeWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &fakeWaiter{
+		clock:    c,
+		at:       c.now.Add(d),
+		interval: interval,
+		ch:       make(chan time.Time, 1),
+	}
+
+	if d <= 0 && interval == 0 {
+		w.ch <- c.now
+
+		return w
+	}
+
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+
+	return w
+}
+
+// nextDueLocked returns the waiter due the earliest, not later than end.
+func (c *FakeClock) nextDueLocked(end time.Time) *fakeWaiter {
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		return c.waiters[i].at.Before(c.waiters[j].at)
+	})
+
+	if len(c.waiters) == 0 || c.waiters[0].at.After(end) {
+		return nil
+	}
+
+	return c.waiters[0]
+}
+
+func (c *FakeClock) removeLocked(w *fakeWaiter) bool {
+	for i, waiter := range c.waiters {
+		if waiter == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			c.cond.Broadcast()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// fakeWaiter is a timer, or a ticker when its interval is positive.
+type fakeWaiter struct {
+	clock    *FakeClock
+	at       time.Time
+	interval time.Duration
+	ch       chan time.Time
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+// Stop stops the timer or the ticker. Returns false if the timer already fired or was stopped.
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	return w.clock.removeLocked(w)
+}
+
+// fakeTicker adapts the fakeWaiter to the task.Ticker interface.
+type fakeTicker struct {
+	*fakeWaiter
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeWaiter.Stop()
+}
+
+func (w *fakeWaiter) fireLocked() {
+	select {
+	case w.ch <- w.at:
+	default:
+	}
+
+	if w.interval > 0 {
+		w.at = w.at.Add(w.interval)
+
+		return
+	}
+
+	w.clock.removeLocked(w)
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasktest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+var epoch = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock(t *testing.T) {
+	t.Run("it moves the time only when advanced", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		assert.Equal(t, epoch, clock.Now())
+
+		clock.Advance(time.Minute)
+		assert.Equal(t, epoch.Add(time.Minute), clock.Now())
+	})
+
+	t.Run("it fires the timer once it is due", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		timer := clock.NewTimer(time.Second)
+		assert.Equal(t, 1, clock.Waiters())
+
+		clock.Advance(999 * time.Millisecond)
+		assertNotFired(t, timer.C())
+
+		clock.Advance(time.Millisecond)
+		assert.Equal(t, epoch.Add(time.Second), <-timer.C())
+		assert.Equal(t, 0, clock.Waiters())
+		assert.False(t, timer.Stop())
+	})
+
+	t.Run("it does not fire a stopped timer", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		ch := clock.After(time.Second)
+		timer := clock.NewTimer(time.Second)
+
+		assert.True(t, timer.Stop())
+
+		clock.Advance(time.Second)
+		assert.Equal(t, epoch.Add(time.Second), <-ch)
+		assertNotFired(t, timer.C())
+	})
+
+	t.Run("it ticks every interval", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		ticker := clock.NewTicker(time.Second)
+
+		clock.Advance(time.Second)
+		assert.Equal(t, epoch.Add(time.Second), <-ticker.C())
+
+		clock.Advance(time.Second)
+		assert.Equal(t, epoch.Add(2*time.Second), <-ticker.C())
+
+		ticker.Stop()
+		clock.Advance(time.Second)
+		assertNotFired(t, ticker.C())
+	})
+
+	t.Run("BlockUntil waits for the timers to be created", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		fired := make(chan time.Time)
+
+		go func() {
+			fired <- <-clock.After(time.Second)
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+		assert.Equal(t, epoch.Add(time.Second), <-fired)
+	})
+}
+
+func assertNotFired(t *testing.T, ch <-chan time.Time) {
+	t.Helper()
+
+	select {
+	case <-ch:
+		t.Fatal("the timer must not fire")
+	default:
+	}
+}
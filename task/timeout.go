@@ -0,0 +1,49 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeout wraps fn so its context is canceled once d passes, e.g. for the tasks run with Group.Go().
+//
+// The wrapped task returns context.DeadlineExceeded if fn did not finish by then, whatever fn returns,
+// and the error returned by fn otherwise.
+// The timer is released as soon as fn returns.
+func WithTimeout(d time.Duration, fn TaskFunc) TaskFunc {
+	return withTimeout(realClock{}, d, fn)
+}
+
+// WithTimeout is the counterpart of the WithTimeout() function which measures the timeout
+// with the clock set with WithClock().
+func (g *Group) WithTimeout(d time.Duration, fn TaskFunc) TaskFunc {
+	return withTimeout(g.clock, d, fn)
+}
+
+func withTimeout(clock Clock, d time.Duration, fn TaskFunc) TaskFunc {
+	return func(ctx context.Context) error {
+		timeoutCtx, cancel := withClockTimeout(ctx, clock, d)
+		defer cancel()
+
+		err := fn(timeoutCtx)
+		if ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded {
+			return context.DeadlineExceeded
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+func TestWithTimeout(t *testing.T) {
+	t.Run("it cancels the task once the timeout passes and returns context.DeadlineExceeded", func(t *testing.T) {
+		t.Parallel()
+
+		var taskErr error
+		group := task.NewGroup()
+		group.Go(task.WithTimeout(10*time.Millisecond, func(ctx context.Context) error {
+			<-ctx.Done()
+			taskErr = ctx.Err()
+
+			return errors.New("canceled")
+		}))
+
+		assert.Equal(t, context.DeadlineExceeded, group.Wait(context.Background()))
+		assert.Equal(t, context.DeadlineExceeded, taskErr)
+	})
+
+	t.Run("it returns the error of the task finishing first", func(t *testing.T) {
+		t.Parallel()
+
+		errFailed := errors.New("failed")
+
+		var deadline time.Time
+		fn := task.WithTimeout(time.Hour, func(ctx context.Context) error {
+			deadline, _ = ctx.Deadline()
+
+			return errFailed
+		})
+
+		assert.Equal(t, errFailed, fn(context.Background()))
+		assert.WithinDuration(t, time.Now().Add(time.Hour), deadline, time.Minute)
+		assert.NoError(t, task.WithTimeout(time.Hour, func(ctx context.Context) error {
+			return nil
+		})(context.Background()))
+	})
+
+	t.Run("it returns the error of the task when the parent context is canceled first", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := task.WithTimeout(time.Hour, func(ctx context.Context) error {
+			return ctx.Err()
+		})(ctx)
+		assert.Equal(t, context.Canceled, err)
+	})
+
+	t.Run("the contexts derived from the task context see context.DeadlineExceeded", func(t *testing.T) {
+		t.Parallel()
+
+		childErr := make(chan error, 1)
+		err := task.WithTimeout(10*time.Millisecond, func(ctx context.Context) error {
+			childCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			<-childCtx.Done()
+			childErr <- childCtx.Err()
+
+			return nil
+		})(context.Background())
+
+		assert.Equal(t, context.DeadlineExceeded, err)
+		assert.Equal(t, context.DeadlineExceeded, <-childErr)
+	})
+
+	t.Run("the contexts derived from the task context see context.DeadlineExceeded with the group clock", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		group := task.NewGroup(task.WithClock(clock))
+
+		childErr := make(chan error, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- group.WithTimeout(time.Minute, func(ctx context.Context) error {
+				childCtx, cancel := context.WithCancel(ctx)
+				defer cancel()
+
+				<-childCtx.Done()
+				childErr <- childCtx.Err()
+
+				return nil
+			})(context.Background())
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+
+		assert.Equal(t, context.DeadlineExceeded, <-done)
+		assert.Equal(t, context.DeadlineExceeded, <-childErr)
+	})
+
+	t.Run("the deadline is the earlier of the parent deadline and the timeout", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Now())
+		group := task.NewGroup(task.WithClock(clock))
+
+		parentDeadline := time.Now().Add(time.Minute)
+		ctx, cancel := context.WithDeadline(context.Background(), parentDeadline)
+		defer cancel()
+
+		for _, fn := range []func(time.Duration, task.TaskFunc) task.TaskFunc{task.WithTimeout, group.WithTimeout} {
+			var deadline time.Time
+			_ = fn(time.Hour, func(ctx context.Context) error {
+				deadline, _ = ctx.Deadline()
+
+				return nil
+			})(ctx)
+
+			assert.Equal(t, parentDeadline, deadline)
+		}
+	})
+
+	t.Run("the group measures the timeout with its clock", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+		group := task.NewGroup(task.WithClock(clock))
+
+		var deadline time.Time
+		group.Go(group.WithTimeout(time.Hour, func(ctx context.Context) error {
+			deadline, _ = ctx.Deadline()
+			<-ctx.Done()
+
+			return ctx.Err()
+		}))
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Hour)
+
+		assert.Equal(t, context.DeadlineExceeded, group.Wait(context.Background()))
+		assert.Equal(t, clock.Now(), deadline)
+	})
+}
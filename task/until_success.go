@@ -0,0 +1,94 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"time"
+)
+
+// RestartObserver is an Observer also notified about the restarts of the tasks run with Group.GoUntilSuccess()
+// and Group.Supervise(), e.g. to alert on a crash-looping task.
+//
+// The observers registered with WithObserver() implementing it are notified automatically.
+type RestartObserver interface {
+	Observer
+	// TaskRestarted is called every time the task is restarted, before waiting for the backoff delay.
+	TaskRestarted(info RestartInfo)
+}
+
+// RestartInfo describes a restart of a task run with Group.GoUntilSuccess() or Group.Supervise().
+type RestartInfo struct {
+	// Attempt is the number of the restart, starting from 1.
+	Attempt int
+	// Err is the error returned by the task, which triggered the restart.
+	// It is nil for the supervised task restarted after it returned nil.
+	Err error
+	// Backoff is how long the task waits before it is restarted.
+	Backoff time.Duration
+}
+
+// GoUntilSuccess runs a task in the group and re-runs it after a backoff delay every time it returns an error,
+// until it returns nil or the group is canceled.
+//
+// Unlike Group.Go(), the errors of the task do not cancel the group. The task is done once it returns nil,
+// and it is not retried anymore.
+// The restarts are reported to the observers implementing RestartObserver, and by Group.Snapshot().
+func (g *Group) GoUntilSuccess(fn TaskFunc, backoff Backoff) {
+	if g.ctx.Err() != nil {
+		return
+	}
+
+	t := g.newTaskEntry("", nil)
+	t.fn = g.untilSuccess(t, fn, backoff)
+
+	g.schedule([]*taskEntry{t})
+}
+
+func (g *Group) untilSuccess(t *taskEntry, fn TaskFunc, backoff Backoff) TaskFunc {
+	return func(ctx context.Context) error {
+		for attempt := 1; ; attempt++ {
+			err := fn(ctx)
+			if err == nil || ctx.Err() != nil {
+				return nil
+			}
+
+			info := RestartInfo{
+				Attempt: attempt,
+				Err:     err,
+				Backoff: backoff.Next(),
+			}
+			t.recordRestart(info)
+			g.notifyTaskRestarted(info)
+
+			retryTimer := g.clock.NewTimer(info.Backoff)
+			select {
+			case <-ctx.Done():
+				retryTimer.Stop()
+
+				return nil
+			case <-retryTimer.C():
+			}
+		}
+	}
+}
+
+func (g *Group) notifyTaskRestarted(info RestartInfo) {
+	for _, observer := range g.observers {
+		if restartObserver, ok := observer.(RestartObserver); ok {
+			restartObserver.TaskRestarted(info)
+		}
+	}
+}
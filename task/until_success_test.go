@@ -0,0 +1,170 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/backoff"
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func noJitter(_ backoff.RandomGenerator, duration int64) time.Duration {
+	return time.Duration(duration)
+}
+
+func TestGroup_GoUntilSuccess(t *testing.T) {
+	t.Run("it retries the task until it succeeds", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		attempts := 0
+		group.GoUntilSuccess(
+			func(ctx context.Context) error {
+				attempts++
+				if attempts <= 2 {
+					return errors.New("not ready")
+				}
+
+				return nil
+			},
+			backoff.NewBackoff(&backoff.Config{Base: time.Millisecond, Max: 5 * time.Millisecond, Jitter: noJitter}),
+		)
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("it stops retrying when the group is canceled", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		failed := make(chan struct{})
+		attempts := 0
+		group.GoUntilSuccess(
+			func(ctx context.Context) error {
+				attempts++
+				if attempts == 1 {
+					close(failed)
+				}
+
+				return errors.New("not ready")
+			},
+			backoff.NewBackoff(&backoff.Config{Base: time.Hour, Max: time.Hour, Jitter: noJitter}),
+		)
+
+		<-failed
+		group.Cancel()
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("it reports the restarts with incrementing attempt numbers", func(t *testing.T) {
+		t.Parallel()
+
+		observer := &restartRecordingObserver{}
+		group := task.NewGroup(task.WithObserver(observer))
+
+		attempts := 0
+		group.GoUntilSuccess(
+			func(ctx context.Context) error {
+				attempts++
+				if attempts <= 3 {
+					return fmt.Errorf("crash %d", attempts)
+				}
+
+				return nil
+			},
+			backoff.NewBackoff(&backoff.Config{Base: time.Millisecond, Max: 5 * time.Millisecond, Jitter: noJitter}),
+		)
+
+		err := group.Wait(context.Background())
+		assert.NoError(t, err)
+
+		restarts := observer.Restarts()
+		require.Len(t, restarts, 3)
+		for i, restart := range restarts {
+			assert.Equal(t, i+1, restart.Attempt)
+			assert.EqualError(t, restart.Err, fmt.Sprintf("crash %d", i+1))
+			assert.True(t, restart.Backoff > 0)
+		}
+	})
+
+	t.Run("it reports the restarts in the snapshot", func(t *testing.T) {
+		t.Parallel()
+
+		group := task.NewGroup()
+
+		failed := make(chan struct{}, 1)
+		group.GoUntilSuccess(
+			func(ctx context.Context) error {
+				select {
+				case failed <- struct{}{}:
+				default:
+				}
+
+				return errors.New("not ready")
+			},
+			backoff.NewBackoff(&backoff.Config{Base: time.Hour, Max: time.Hour, Jitter: noJitter}),
+		)
+
+		<-failed
+
+		assert.Eventually(t, func() bool {
+			snapshot := group.Snapshot()
+
+			return len(snapshot.Tasks) == 1 && snapshot.Tasks[0].Restarts == 1
+		}, time.Second, time.Millisecond)
+
+		snapshot := group.Snapshot()
+		assert.Equal(t, "not ready", snapshot.Tasks[0].LastError)
+		assert.Equal(t, time.Hour, snapshot.Tasks[0].Backoff)
+
+		group.Cancel()
+		assert.NoError(t, group.Wait(context.Background()))
+	})
+}
+
+type restartRecordingObserver struct {
+	recordingObserver
+
+	restarts []task.RestartInfo
+}
+
+func (o *restartRecordingObserver) TaskRestarted(info task.RestartInfo) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.restarts = append(o.restarts, info)
+}
+
+func (o *restartRecordingObserver) Restarts() []task.RestartInfo {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]task.RestartInfo(nil), o.restarts...)
+}
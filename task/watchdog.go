@@ -0,0 +1,190 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTaskStuck is the error of the tasks canceled by the watchdog, see WithWatchdog().
+var ErrTaskStuck = errors.New("task stuck")
+
+// WatchdogAction is what the watchdog does with the tasks running for too long, see WithWatchdog().
+type WatchdogAction int
+
+const (
+	// WatchdogWarn reports the stuck task, and lets it run.
+	WatchdogWarn WatchdogAction = iota
+	// WatchdogCancel reports the stuck task, and cancels its context.
+	WatchdogCancel
+)
+
+func (a WatchdogAction) String() string {
+	switch a {
+	case WatchdogWarn:
+		return "warn"
+	case WatchdogCancel:
+		return "cancel"
+	default:
+		return "unknown"
+	}
+}
+
+// StuckTaskObserver is an Observer also notified about the tasks flagged by the watchdog, see WithWatchdog(),
+// e.g. to log them or to count them in a metric.
+//
+// The observers registered with WithObserver() implementing it are notified automatically.
+type StuckTaskObserver interface {
+	Observer
+	// TaskStuck is called once for every task running for longer than the max run time of the watchdog,
+	// from the goroutine of the watchdog.
+	TaskStuck(info StuckTaskInfo)
+}
+
+// StuckTaskInfo describes a task flagged by the watchdog.
+type StuckTaskInfo struct {
+	// Task is the name of the task, or the auto-generated one, e.g. "task-0".
+	Task string
+	// StartedAt is the time when the task function was invoked.
+	StartedAt time.Time
+	// RunTime is how long the task was running when it was flagged.
+	RunTime time.Duration
+	// Action is what the watchdog did with the task.
+	Action WatchdogAction
+}
+
+// WithWatchdog flags the tasks running for longer than maxRunTime, e.g. to detect a task blocked
+// on a dependency which never responds.
+//
+// The watchdog checks the start times of the running tasks, as reported by Group.Snapshot(), every quarter
+// of maxRunTime while the group is running. It flags every task once, emitting EventTaskStuck, see Group.Events(),
+// and notifying the observers implementing StuckTaskObserver.
+// With WatchdogCancel it also cancels the context of the task, and the task fails with ErrTaskStuck
+// once it returns the error of its canceled context.
+// A non-positive maxRunTime disables the watchdog.
+func WithWatchdog(maxRunTime time.Duration, action WatchdogAction) GroupOption {
+	return func(g *Group) {
+		g.watchdogMaxRunTime = maxRunTime
+		g.watchdogAction = action
+	}
+}
+
+const (
+	taskNotStuck int32 = iota
+	taskStuckWarned
+	taskStuckCanceled
+)
+
+// startWatchdog starts checking the run time of the tasks in the background, if configured.
+func (g *Group) startWatchdog() {
+	if g.watchdogMaxRunTime <= 0 {
+		return
+	}
+
+	g.watchdogDone = make(chan struct{})
+
+	interval := g.watchdogMaxRunTime / 4
+	if interval <= 0 {
+		interval = g.watchdogMaxRunTime
+	}
+	ticker := g.clock.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-g.ctx.Done():
+				return
+			case <-g.watchdogDone:
+				return
+			case <-ticker.C():
+			}
+
+			g.flagStuckTasks(g.clock.Now())
+		}
+	}()
+}
+
+// stopWatchdog stops checking the run time of the tasks once all the tasks are stopped.
+func (g *Group) stopWatchdog() {
+	if g.watchdogDone == nil {
+		return
+	}
+
+	g.watchdogOnce.Do(func() {
+		close(g.watchdogDone)
+	})
+}
+
+// flagStuckTasks applies the watchdog action to the tasks running for longer than the max run time.
+func (g *Group) flagStuckTasks(now time.Time) {
+	g.mu.Lock()
+	tasks := make([]*taskEntry, 0, len(g.tasks))
+	for t := range g.tasks {
+		tasks = append(tasks, t)
+	}
+	g.mu.Unlock()
+
+	stuck := taskStuckWarned
+	if g.watchdogAction == WatchdogCancel {
+		stuck = taskStuckCanceled
+	}
+
+	for _, t := range tasks {
+		startedAt := atomic.LoadInt64(&t.startedAt)
+		if startedAt == 0 {
+			continue
+		}
+
+		runTime := time.Duration(now.UnixNano() - startedAt)
+		if runTime < g.watchdogMaxRunTime || !atomic.CompareAndSwapInt32(&t.stuck, taskNotStuck, stuck) {
+			continue
+		}
+
+		if stuck == taskStuckCanceled {
+			t.cancel()
+		}
+
+		g.emitEvent(EventTaskStuck, t.label, nil)
+		g.notifyTaskStuck(StuckTaskInfo{
+			Task:      t.label,
+			StartedAt: time.Unix(0, startedAt),
+			RunTime:   runTime,
+			Action:    g.watchdogAction,
+		})
+	}
+}
+
+func (g *Group) notifyTaskStuck(info StuckTaskInfo) {
+	for _, observer := range g.observers {
+		if stuckObserver, ok := observer.(StuckTaskObserver); ok {
+			stuckObserver.TaskStuck(info)
+		}
+	}
+}
+
+// stuckError returns ErrTaskStuck if the task was canceled by the watchdog and returned
+// the error of its canceled context, and err otherwise.
+func (t *taskEntry) stuckError(err error) error {
+	if err != nil && atomic.LoadInt32(&t.stuck) == taskStuckCanceled && errors.Is(err, context.Canceled) {
+		return ErrTaskStuck
+	}
+
+	return err
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+	"github.com/sumup-oss/go-pkgs/task/tasktest"
+)
+
+type stuckTaskObserver struct {
+	recordingObserver
+	stuck chan task.StuckTaskInfo
+}
+
+func (o *stuckTaskObserver) TaskStuck(info task.StuckTaskInfo) {
+	o.stuck <- info
+}
+
+func TestWithWatchdog(t *testing.T) {
+	runStuckTask := func(clock *tasktest.FakeClock, group *task.Group, release <-chan struct{}) {
+		started := make(chan struct{})
+		group.GoNamed("stuck", func(ctx context.Context) error {
+			close(started)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-release:
+				return nil
+			}
+		})
+		<-started
+
+		// NOTE: The watchdog checks the tasks every quarter of the max run time.
+		for i := 0; i < 4; i++ {
+			clock.BlockUntil(1)
+			clock.Advance(15 * time.Second)
+		}
+	}
+
+	t.Run("with WatchdogWarn, it reports the task running for longer than the max run time and lets it run", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		observer := &stuckTaskObserver{stuck: make(chan task.StuckTaskInfo, 1)}
+		group := task.NewGroup(
+			task.WithClock(clock),
+			task.WithObserver(observer),
+			task.WithWatchdog(time.Minute, task.WatchdogWarn),
+		)
+		events := group.Events()
+
+		release := make(chan struct{})
+		runStuckTask(clock, group, release)
+
+		info := <-observer.stuck
+		assert.Equal(t, "stuck", info.Task)
+		assert.True(t, epoch.Equal(info.StartedAt), info.StartedAt)
+		assert.Equal(t, time.Minute, info.RunTime)
+		assert.Equal(t, task.WatchdogWarn, info.Action)
+
+		close(release)
+		require.NoError(t, group.Wait(context.Background()))
+
+		assert.Contains(t, drainEvents(events), emittedEvent{typ: task.EventTaskStuck, task: "stuck"})
+	})
+
+	t.Run("with WatchdogCancel, it cancels the task running for longer than the max run time", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		observer := &stuckTaskObserver{stuck: make(chan task.StuckTaskInfo, 1)}
+		group := task.NewGroup(
+			task.WithClock(clock),
+			task.WithObserver(observer),
+			task.WithWatchdog(time.Minute, task.WatchdogCancel),
+		)
+
+		runStuckTask(clock, group, nil)
+
+		info := <-observer.stuck
+		assert.Equal(t, task.WatchdogCancel, info.Action)
+
+		err := group.Wait(context.Background())
+		assert.True(t, errors.Is(err, task.ErrTaskStuck), err)
+
+		finished := observer.Finished()
+		require.Len(t, finished, 1)
+		assert.True(t, errors.Is(finished[0].err, task.ErrTaskStuck))
+	})
+
+	t.Run("it does not flag the tasks finishing within the max run time", func(t *testing.T) {
+		t.Parallel()
+
+		clock := tasktest.NewFakeClock(epoch)
+		observer := &stuckTaskObserver{stuck: make(chan task.StuckTaskInfo, 1)}
+		group := task.NewGroup(
+			task.WithClock(clock),
+			task.WithObserver(observer),
+			task.WithWatchdog(time.Minute, task.WatchdogCancel),
+		)
+
+		group.Go(func(ctx context.Context) error {
+			return nil
+		})
+		require.NoError(t, group.Wait(context.Background()))
+
+		clock.Advance(time.Minute)
+		assert.Empty(t, observer.stuck)
+	})
+}